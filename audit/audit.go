@@ -0,0 +1,27 @@
+// Package audit records security relevant events, such as logins and changes to
+// users, clients and API keys, in an append-only and tamper-evident log.
+package audit
+
+import (
+	"github.com/ninth-realm/heimdall/event"
+	"github.com/ninth-realm/heimdall/store"
+)
+
+// Subscriber appends every event to the audit log. Events are appended in the
+// transaction that marks them as handled, so each is recorded exactly once.
+func Subscriber(repo store.Repository) event.Handler {
+	return func(e store.OutboxEvent, opts store.QueryOptions) error {
+		_, err := repo.AppendAuditEvent(store.NewAuditEvent{
+			Type:       e.Type,
+			OccurredAt: e.OccurredAt,
+			ActorType:  e.ActorType,
+			ActorID:    e.ActorID,
+			IPAddress:  e.IPAddress,
+			RequestID:  e.RequestID,
+			TargetType: e.TargetType,
+			TargetID:   e.TargetID,
+		}, opts)
+
+		return err
+	}
+}
@@ -0,0 +1,125 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ninth-realm/heimdall/store"
+)
+
+// verifyPageSize is how many events are read at a time while verifying the
+// chain.
+const verifyPageSize = 500
+
+type Service struct {
+	Repo store.Repository
+}
+
+// ListEvents returns a single page of audit events. An invalid sort or cursor
+// results in store.ErrInvalidSort or store.ErrInvalidCursor.
+func (s Service) ListEvents(ctx context.Context, list store.AuditEventListOptions) (store.Page[store.AuditEvent], error) {
+	if err := list.Validate(); err != nil {
+		return store.Page[store.AuditEvent]{}, err
+	}
+
+	// Fetch an extra event to find out whether there is another page.
+	limit := list.Pagination.Limit
+	if limit > 0 {
+		list.Pagination.Limit++
+	}
+
+	events, err := s.Repo.ListAuditEvents(list, store.QueryOptions{Ctx: ctx})
+	if err != nil {
+		return store.Page[store.AuditEvent]{}, err
+	}
+
+	return store.NewPage(events, limit, func(event store.AuditEvent) store.Cursor {
+		return store.AuditEventCursor(list.Sort, event)
+	}), nil
+}
+
+// Verification is the outcome of checking the audit log's hash chain.
+type Verification struct {
+	Valid bool `json:"valid"`
+	// Checked is the number of events that were checked.
+	Checked int64 `json:"checked"`
+	// InvalidSequence is the position of the first event that doesn't fit in
+	// the chain, when the log isn't valid.
+	InvalidSequence *int64 `json:"invalidSequence,omitempty"`
+	Reason          string `json:"reason,omitempty"`
+}
+
+// Verify walks the audit log from the start and checks that every event is in
+// sequence, links to the hash of the one before it, and hashes to its recorded
+// hash. Events appended while it runs aren't checked.
+func (s Service) Verify(ctx context.Context) (Verification, error) {
+	opts := store.QueryOptions{Ctx: ctx}
+
+	head, err := s.Repo.GetAuditChainHead(opts)
+	if err != nil {
+		return Verification{}, err
+	}
+
+	list := store.AuditEventListOptions{
+		Sort:       store.Sort{Field: store.SortBySequence},
+		Pagination: store.Pagination{Limit: verifyPageSize},
+	}
+
+	var checked int64
+	prevHash := store.GenesisHash
+	for checked < head.Sequence {
+		events, err := s.Repo.ListAuditEvents(list, opts)
+		if err != nil {
+			return Verification{}, err
+		}
+
+		for _, event := range events {
+			if event.Sequence > head.Sequence {
+				break
+			}
+
+			if reason := checkEvent(event, checked+1, prevHash); reason != "" {
+				return invalid(checked, checked+1, reason), nil
+			}
+
+			checked++
+			prevHash = event.Hash
+		}
+
+		if len(events) < verifyPageSize {
+			break
+		}
+
+		cursor := store.AuditEventCursor(list.Sort, events[len(events)-1])
+		list.Pagination.After = &cursor
+	}
+
+	if checked != head.Sequence {
+		return invalid(checked, checked+1, "event is missing"), nil
+	}
+
+	if prevHash != head.Hash {
+		return invalid(checked, checked, "hash does not match the head of the chain"), nil
+	}
+
+	return Verification{Valid: true, Checked: checked}, nil
+}
+
+// checkEvent returns why the event doesn't belong at the given position after
+// an event with prevHash, or an empty string if it does.
+func checkEvent(event store.AuditEvent, sequence int64, prevHash string) string {
+	switch {
+	case event.Sequence != sequence:
+		return fmt.Sprintf("event is missing, found %d instead", event.Sequence)
+	case event.PrevHash != prevHash:
+		return "previous hash does not match the previous event"
+	case event.ComputeHash() != event.Hash:
+		return "hash does not match the event's contents"
+	}
+
+	return ""
+}
+
+func invalid(checked, sequence int64, reason string) Verification {
+	return Verification{Checked: checked, InvalidSequence: &sequence, Reason: reason}
+}
@@ -0,0 +1,158 @@
+package audit
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/ninth-realm/heimdall/event"
+	"github.com/ninth-realm/heimdall/store"
+)
+
+// chainRepo serves a fixed audit log. Only the methods used by Verify are
+// implemented.
+type chainRepo struct {
+	store.Repository
+	events []store.AuditEvent
+	head   store.AuditChainHead
+}
+
+func (r chainRepo) GetAuditChainHead(opts store.QueryOptions) (store.AuditChainHead, error) {
+	return r.head, nil
+}
+
+func (r chainRepo) ListAuditEvents(list store.AuditEventListOptions, opts store.QueryOptions) ([]store.AuditEvent, error) {
+	var after int64
+	if list.Pagination.After != nil {
+		after, _ = strconv.ParseInt(list.Pagination.After.Value, 10, 64)
+	}
+
+	events := []store.AuditEvent{}
+	for _, e := range r.events {
+		if e.Sequence <= after {
+			continue
+		}
+
+		if list.Pagination.Limit > 0 && len(events) == list.Pagination.Limit {
+			break
+		}
+
+		events = append(events, e)
+	}
+
+	return events, nil
+}
+
+// newChain builds a valid log of n events.
+func newChain(n int) chainRepo {
+	r := chainRepo{head: store.AuditChainHead{Hash: store.GenesisHash}}
+	for i := 1; i <= n; i++ {
+		e := store.AuditEvent{
+			ID:         uuid.Must(uuid.NewV4()),
+			Sequence:   int64(i),
+			Type:       event.UserCreated,
+			OccurredAt: time.Date(2023, 1, 1, 0, 0, i, 0, time.UTC),
+			ActorType:  event.ActorSetup,
+			PrevHash:   r.head.Hash,
+		}
+		e.Hash = e.ComputeHash()
+
+		r.events = append(r.events, e)
+		r.head = store.AuditChainHead{Sequence: e.Sequence, Hash: e.Hash}
+	}
+
+	return r
+}
+
+func TestService_Verify(t *testing.T) {
+	tests := []struct {
+		name        string
+		repo        func() chainRepo
+		wantValid   bool
+		wantChecked int64
+		wantInvalid int64
+	}{
+		{
+			name:      "Empty log",
+			repo:      func() chainRepo { return newChain(0) },
+			wantValid: true,
+		},
+		{
+			name:        "Valid log",
+			repo:        func() chainRepo { return newChain(3) },
+			wantValid:   true,
+			wantChecked: 3,
+		},
+		{
+			name: "Changed event",
+			repo: func() chainRepo {
+				r := newChain(3)
+				r.events[1].Type = event.UserDeleted
+				return r
+			},
+			wantChecked: 1,
+			wantInvalid: 2,
+		},
+		{
+			name: "Changed and rehashed event",
+			repo: func() chainRepo {
+				r := newChain(3)
+				r.events[1].Type = event.UserDeleted
+				r.events[1].Hash = r.events[1].ComputeHash()
+				return r
+			},
+			wantChecked: 2,
+			wantInvalid: 3,
+		},
+		{
+			name: "Removed event",
+			repo: func() chainRepo {
+				r := newChain(3)
+				r.events = append(r.events[:1], r.events[2:]...)
+				return r
+			},
+			wantChecked: 1,
+			wantInvalid: 2,
+		},
+		{
+			name: "Removed last event",
+			repo: func() chainRepo {
+				r := newChain(3)
+				r.events = r.events[:2]
+				return r
+			},
+			wantChecked: 2,
+			wantInvalid: 3,
+		},
+		{
+			name: "Events appended after head",
+			repo: func() chainRepo {
+				r := newChain(3)
+				r.head = store.AuditChainHead{Sequence: 2, Hash: r.events[1].Hash}
+				return r
+			},
+			wantValid:   true,
+			wantChecked: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Service{Repo: tt.repo()}
+
+			got, err := s.Verify(context.Background())
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+
+			if got.Valid != tt.wantValid || got.Checked != tt.wantChecked {
+				t.Errorf("Verify() = %+v, want valid %t after checking %d", got, tt.wantValid, tt.wantChecked)
+			}
+
+			if !tt.wantValid && (got.InvalidSequence == nil || *got.InvalidSequence != tt.wantInvalid) {
+				t.Errorf("Verify() invalid sequence = %v, want %d", got.InvalidSequence, tt.wantInvalid)
+			}
+		})
+	}
+}
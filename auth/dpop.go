@@ -0,0 +1,285 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// dpopProofLifetime is how far a DPoP proof's iat may be from the current time,
+// in either direction, to allow for clock skew. Proofs are remembered for
+// twice as long so that a replay is caught for as long as the proof is valid.
+const dpopProofLifetime = time.Minute
+
+// dpopAlgorithms are the signing algorithms accepted for DPoP proofs. Proofs
+// must be signed with a private key, so HMAC isn't allowed.
+var dpopAlgorithms = []string{"ES256", "ES384", "RS256", "PS256", "EdDSA"}
+
+// ErrInvalidDPoPProof is returned for DPoP proofs that aren't valid for the
+// request they were sent with.
+var ErrInvalidDPoPProof = errors.New("invalid DPoP proof")
+
+// DPoPProof is a DPoP proof JWT sent in a request's DPoP header, along with the
+// request it was sent with. See RFC 9449.
+type DPoPProof struct {
+	// JWT is the proof itself.
+	JWT string
+	// Method and URL are those of the request the proof was sent with. The
+	// URL must not include a query string or fragment.
+	Method string
+	URL    string
+}
+
+type dpopClaims struct {
+	jwt.RegisteredClaims
+	Method string `json:"htm"`
+	URL    string `json:"htu"`
+	// AccessTokenHash is the base64url encoded SHA-256 hash of the access
+	// token the proof is sent with, when it's sent with one.
+	AccessTokenHash string `json:"ath,omitempty"`
+}
+
+// verifyDPoPProof checks a DPoP proof and returns the thumbprint of the key it
+// was signed with. When accessToken isn't empty, the proof must have been
+// made for it. Each proof can only be used once.
+func verifyDPoPProof(proof *DPoPProof, accessToken string, replays *ReplayCache, now time.Time) (string, error) {
+	if replays == nil {
+		return "", fmt.Errorf("%w: DPoP isn't enabled", ErrInvalidDPoPProof)
+	}
+
+	var claims dpopClaims
+	var thumbprint string
+	_, err := jwt.ParseWithClaims(
+		proof.JWT,
+		&claims,
+		func(t *jwt.Token) (interface{}, error) {
+			if t.Header["typ"] != "dpop+jwt" {
+				return nil, fmt.Errorf("%w: typ must be dpop+jwt", ErrInvalidDPoPProof)
+			}
+
+			jwk, ok := t.Header["jwk"].(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%w: missing jwk", ErrInvalidDPoPProof)
+			}
+
+			key, tp, err := parseJWK(jwk)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidDPoPProof, err)
+			}
+			thumbprint = tp
+
+			return key, nil
+		},
+		jwt.WithValidMethods(dpopAlgorithms),
+		// The iat claim is checked below with leeway for clock skew.
+		jwt.WithoutClaimsValidation(),
+	)
+	if errors.Is(err, ErrInvalidDPoPProof) {
+		return "", err
+	} else if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidDPoPProof, err)
+	}
+
+	if claims.ID == "" {
+		return "", fmt.Errorf("%w: missing jti", ErrInvalidDPoPProof)
+	}
+
+	if claims.IssuedAt == nil {
+		return "", fmt.Errorf("%w: missing iat", ErrInvalidDPoPProof)
+	}
+
+	if age := now.Sub(claims.IssuedAt.Time); age > dpopProofLifetime || age < -dpopProofLifetime {
+		return "", fmt.Errorf("%w: iat is too far from the current time", ErrInvalidDPoPProof)
+	}
+
+	if claims.Method != proof.Method {
+		return "", fmt.Errorf("%w: htm doesn't match the request", ErrInvalidDPoPProof)
+	}
+
+	if !sameURL(claims.URL, proof.URL) {
+		return "", fmt.Errorf("%w: htu doesn't match the request", ErrInvalidDPoPProof)
+	}
+
+	if accessToken != "" {
+		sum := sha256.Sum256([]byte(accessToken))
+		if claims.AccessTokenHash != base64.RawURLEncoding.EncodeToString(sum[:]) {
+			return "", fmt.Errorf("%w: ath doesn't match the access token", ErrInvalidDPoPProof)
+		}
+	}
+
+	// jti values only need to be unique per key.
+	if !replays.use(thumbprint+":"+claims.ID, now.Add(2*dpopProofLifetime), now) {
+		return "", fmt.Errorf("%w: proof has already been used", ErrInvalidDPoPProof)
+	}
+
+	return thumbprint, nil
+}
+
+// sameURL compares a proof's htu to the request's URL, ignoring any query
+// string and fragment, and the case of the scheme and host.
+func sameURL(htu, want string) bool {
+	u, err := url.Parse(htu)
+	if err != nil {
+		return false
+	}
+
+	w, err := url.Parse(want)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(u.Scheme, w.Scheme) &&
+		strings.EqualFold(u.Host, w.Host) &&
+		u.EscapedPath() == w.EscapedPath()
+}
+
+// parseJWK reads a public JSON Web Key, and returns it along with its RFC 7638
+// thumbprint.
+func parseJWK(jwk map[string]interface{}) (crypto.PublicKey, string, error) {
+	str := func(name string) string {
+		s, _ := jwk[name].(string)
+		return s
+	}
+
+	if str("d") != "" {
+		return nil, "", errors.New("jwk must not contain a private key")
+	}
+
+	// Only the required members are hashed for the thumbprint, in
+	// lexicographic order, which encoding/json uses for map keys.
+	var key crypto.PublicKey
+	var members map[string]string
+	switch str("kty") {
+	case "EC":
+		var curve elliptic.Curve
+		switch str("crv") {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, "", fmt.Errorf("unsupported curve %q", str("crv"))
+		}
+
+		x, err := decodeBigInt(str("x"))
+		if err != nil {
+			return nil, "", err
+		}
+
+		y, err := decodeBigInt(str("y"))
+		if err != nil {
+			return nil, "", err
+		}
+
+		if !curve.IsOnCurve(x, y) {
+			return nil, "", errors.New("point isn't on the curve")
+		}
+
+		key = &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+		members = map[string]string{"crv": str("crv"), "kty": "EC", "x": str("x"), "y": str("y")}
+	case "RSA":
+		n, err := decodeBigInt(str("n"))
+		if err != nil {
+			return nil, "", err
+		}
+
+		e, err := decodeBigInt(str("e"))
+		if err != nil {
+			return nil, "", err
+		}
+
+		if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, "", errors.New("invalid RSA exponent")
+		}
+
+		if n.BitLen() < 2048 {
+			return nil, "", errors.New("RSA keys must be at least 2048 bits")
+		}
+
+		key = &rsa.PublicKey{N: n, E: int(e.Int64())}
+		members = map[string]string{"e": str("e"), "kty": "RSA", "n": str("n")}
+	case "OKP":
+		if str("crv") != "Ed25519" {
+			return nil, "", fmt.Errorf("unsupported curve %q", str("crv"))
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(str("x"))
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, "", errors.New("invalid Ed25519 key")
+		}
+
+		key = ed25519.PublicKey(x)
+		members = map[string]string{"crv": "Ed25519", "kty": "OKP", "x": str("x")}
+	default:
+		return nil, "", fmt.Errorf("unsupported key type %q", str("kty"))
+	}
+
+	canonical, err := json.Marshal(members)
+	if err != nil {
+		return nil, "", err
+	}
+
+	sum := sha256.Sum256(canonical)
+
+	return key, base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("invalid key parameter")
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
+
+// ReplayCache remembers recently used DPoP proofs so that each can only be
+// used once. It's held in memory, so a proof could be replayed against a
+// different replica within its short lifetime.
+type ReplayCache struct {
+	mu       sync.Mutex
+	seen     map[string]time.Time
+	prunedAt time.Time
+}
+
+func NewReplayCache() *ReplayCache {
+	return &ReplayCache{seen: make(map[string]time.Time)}
+}
+
+// use records the key until expiresAt, and reports whether it hadn't already
+// been used. Expired keys are forgotten every so often along the way.
+func (c *ReplayCache) use(key string, expiresAt, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if now.Sub(c.prunedAt) > dpopProofLifetime {
+		for k, exp := range c.seen {
+			if now.After(exp) {
+				delete(c.seen, k)
+			}
+		}
+		c.prunedAt = now
+	}
+
+	if exp, ok := c.seen[key]; ok && !now.After(exp) {
+		return false
+	}
+
+	c.seen[key] = expiresAt
+
+	return true
+}
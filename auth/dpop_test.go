@@ -0,0 +1,339 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/ninth-realm/heimdall/store"
+)
+
+func Test_parseJWK_thumbprint(t *testing.T) {
+	// The example from RFC 7638, section 3.1.
+	jwk := map[string]interface{}{
+		"kty": "RSA",
+		"n":   "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+		"e":   "AQAB",
+		"alg": "RS256",
+		"kid": "2011-04-29",
+	}
+
+	_, got, err := parseJWK(jwk)
+	if err != nil {
+		t.Fatalf("parseJWK() error = %v", err)
+	}
+
+	if want := "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"; got != want {
+		t.Errorf("thumbprint = %s, want %s", got, want)
+	}
+}
+
+func Test_verifyDPoPProof(t *testing.T) {
+	key := newDPoPKey(t)
+	now := time.Now()
+	url := "https://auth.example.com/api/v1/auth/token"
+
+	tests := []struct {
+		name    string
+		proof   func(t *testing.T) string
+		request DPoPProof
+		token   string
+		wantErr bool
+	}{
+		{
+			name:    "Valid proof",
+			proof:   key.proof("POST", url, now, ""),
+			request: DPoPProof{Method: "POST", URL: url},
+		},
+		{
+			name:    "Query string is ignored",
+			proof:   key.proof("GET", "https://auth.example.com/api/v1/clients", now, ""),
+			request: DPoPProof{Method: "GET", URL: "https://AUTH.example.com/api/v1/clients?limit=5"},
+		},
+		{
+			name:    "Different method",
+			proof:   key.proof("GET", url, now, ""),
+			request: DPoPProof{Method: "POST", URL: url},
+			wantErr: true,
+		},
+		{
+			name:    "Different URL",
+			proof:   key.proof("POST", "https://auth.example.com/api/v1/auth/introspect", now, ""),
+			request: DPoPProof{Method: "POST", URL: url},
+			wantErr: true,
+		},
+		{
+			name:    "Old proof",
+			proof:   key.proof("POST", url, now.Add(-2*dpopProofLifetime), ""),
+			request: DPoPProof{Method: "POST", URL: url},
+			wantErr: true,
+		},
+		{
+			name:    "Future proof",
+			proof:   key.proof("POST", url, now.Add(2*dpopProofLifetime), ""),
+			request: DPoPProof{Method: "POST", URL: url},
+			wantErr: true,
+		},
+		{
+			name:    "Access token hash",
+			proof:   key.proof("GET", url, now, "token"),
+			request: DPoPProof{Method: "GET", URL: url},
+			token:   "token",
+		},
+		{
+			name:    "Missing access token hash",
+			proof:   key.proof("GET", url, now, ""),
+			request: DPoPProof{Method: "GET", URL: url},
+			token:   "token",
+			wantErr: true,
+		},
+		{
+			name:    "Wrong access token hash",
+			proof:   key.proof("GET", url, now, "another token"),
+			request: DPoPProof{Method: "GET", URL: url},
+			token:   "token",
+			wantErr: true,
+		},
+		{
+			name: "Wrong typ",
+			proof: func(t *testing.T) string {
+				return key.sign(t, "JWT", key.jwk(), dpopClaims{
+					RegisteredClaims: jwt.RegisteredClaims{ID: "1", IssuedAt: jwt.NewNumericDate(now)},
+					Method:           "POST",
+					URL:              url,
+				})
+			},
+			request: DPoPProof{Method: "POST", URL: url},
+			wantErr: true,
+		},
+		{
+			name: "Signed by a different key",
+			proof: func(t *testing.T) string {
+				return newDPoPKey(t).sign(t, "dpop+jwt", key.jwk(), dpopClaims{
+					RegisteredClaims: jwt.RegisteredClaims{ID: "1", IssuedAt: jwt.NewNumericDate(now)},
+					Method:           "POST",
+					URL:              url,
+				})
+			},
+			request: DPoPProof{Method: "POST", URL: url},
+			wantErr: true,
+		},
+		{
+			name: "HMAC signed",
+			proof: func(t *testing.T) string {
+				token := jwt.NewWithClaims(jwt.SigningMethodHS256, dpopClaims{
+					RegisteredClaims: jwt.RegisteredClaims{ID: "1", IssuedAt: jwt.NewNumericDate(now)},
+					Method:           "POST",
+					URL:              url,
+				})
+				token.Header["typ"] = "dpop+jwt"
+				token.Header["jwk"] = key.jwk()
+				signed, err := token.SignedString([]byte("secret"))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				return signed
+			},
+			request: DPoPProof{Method: "POST", URL: url},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := tt.request
+			request.JWT = tt.proof(t)
+
+			got, err := verifyDPoPProof(&request, tt.token, NewReplayCache(), now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("verifyDPoPProof() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err != nil && !errors.Is(err, ErrInvalidDPoPProof) {
+				t.Errorf("verifyDPoPProof() error = %v, want ErrInvalidDPoPProof", err)
+			}
+
+			if !tt.wantErr && got != key.thumbprint(t) {
+				t.Errorf("thumbprint = %s, want %s", got, key.thumbprint(t))
+			}
+		})
+	}
+}
+
+func Test_verifyDPoPProof_replay(t *testing.T) {
+	key := newDPoPKey(t)
+	now := time.Now()
+	replays := NewReplayCache()
+
+	proof := DPoPProof{
+		JWT:    key.proof("POST", "https://auth.example.com/api/v1/auth/token", now, "")(t),
+		Method: "POST",
+		URL:    "https://auth.example.com/api/v1/auth/token",
+	}
+
+	if _, err := verifyDPoPProof(&proof, "", replays, now); err != nil {
+		t.Fatalf("verifyDPoPProof() error = %v", err)
+	}
+
+	replayed := DPoPProof{JWT: proof.JWT, Method: proof.Method, URL: proof.URL}
+	if _, err := verifyDPoPProof(&replayed, "", replays, now.Add(time.Second)); err == nil {
+		t.Error("verifyDPoPProof() of a replayed proof error = nil, want an error")
+	}
+}
+
+func TestService_ValidateAccessToken_dpopBinding(t *testing.T) {
+	clientID := uuid.Must(uuid.NewV4())
+	s := Service{
+		Repo: clientRepo{client: store.Client{ID: clientID, Enabled: true}},
+		Tokens: JWTSettings{
+			Issuer:     "Heimdall",
+			Lifespan:   60,
+			SigningKey: "secretkey",
+			Algorithm:  HMAC256Algorithm,
+		},
+		ProofReplays: NewReplayCache(),
+	}
+
+	key := newDPoPKey(t)
+	tokenURL := "https://auth.example.com/api/v1/auth/token"
+	clientsURL := "https://auth.example.com/api/v1/clients"
+
+	token, err := s.IssueClientToken(context.Background(), clientID, TokenProof{
+		DPoP: &DPoPProof{JWT: key.proof("POST", tokenURL, time.Now(), "")(t), Method: "POST", URL: tokenURL},
+	})
+	if err != nil {
+		t.Fatalf("IssueClientToken() error = %v", err)
+	}
+
+	if token.TokenType != "DPoP" {
+		t.Errorf("token type = %s, want DPoP", token.TokenType)
+	}
+
+	tests := []struct {
+		name       string
+		proof      TokenProof
+		wantActive bool
+	}{
+		{
+			name: "Proof from the bound key",
+			proof: TokenProof{DPoP: &DPoPProof{
+				JWT:    key.proof("GET", clientsURL, time.Now(), token.AccessToken)(t),
+				Method: "GET",
+				URL:    clientsURL,
+			}},
+			wantActive: true,
+		},
+		{
+			name:  "No proof",
+			proof: TokenProof{},
+		},
+		{
+			name: "Proof from another key",
+			proof: TokenProof{DPoP: &DPoPProof{
+				JWT:    newDPoPKey(t).proof("GET", clientsURL, time.Now(), token.AccessToken)(t),
+				Method: "GET",
+				URL:    clientsURL,
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.ValidateAccessToken(context.Background(), token.AccessToken, tt.proof)
+			if tt.wantActive != (err == nil) {
+				t.Fatalf("ValidateAccessToken() error = %v, want active %v", err, tt.wantActive)
+			}
+		})
+	}
+
+	// Resource servers can't make proofs for introspection, so the binding is
+	// reported for them to check instead.
+	t.Run("Introspection reports the key", func(t *testing.T) {
+		info, err := s.IntrospectToken(context.Background(), token.AccessToken)
+		if err != nil {
+			t.Fatalf("IntrospectToken() error = %v", err)
+		}
+
+		if info.Confirmation == nil || info.Confirmation.JWKThumbprint != key.thumbprint(t) {
+			t.Errorf("confirmation = %+v, want jkt %s", info.Confirmation, key.thumbprint(t))
+		}
+	})
+}
+
+type dpopKey struct {
+	*ecdsa.PrivateKey
+}
+
+func newDPoPKey(t *testing.T) dpopKey {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return dpopKey{key}
+}
+
+func (k dpopKey) jwk() map[string]interface{} {
+	return map[string]interface{}{
+		"kty": "EC",
+		"crv": "P-256",
+		"x":   base64.RawURLEncoding.EncodeToString(k.X.FillBytes(make([]byte, 32))),
+		"y":   base64.RawURLEncoding.EncodeToString(k.Y.FillBytes(make([]byte, 32))),
+	}
+}
+
+func (k dpopKey) thumbprint(t *testing.T) string {
+	t.Helper()
+
+	_, thumbprint, err := parseJWK(k.jwk())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return thumbprint
+}
+
+// proof makes a proof for a request, for the access token if it isn't empty.
+func (k dpopKey) proof(method, url string, iat time.Time, accessToken string) func(t *testing.T) string {
+	return func(t *testing.T) string {
+		claims := dpopClaims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				ID:       uuid.Must(uuid.NewV4()).String(),
+				IssuedAt: jwt.NewNumericDate(iat),
+			},
+			Method: method,
+			URL:    url,
+		}
+
+		if accessToken != "" {
+			sum := sha256.Sum256([]byte(accessToken))
+			claims.AccessTokenHash = base64.RawURLEncoding.EncodeToString(sum[:])
+		}
+
+		return k.sign(t, "dpop+jwt", k.jwk(), claims)
+	}
+}
+
+func (k dpopKey) sign(t *testing.T, typ string, jwk map[string]interface{}, claims dpopClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["typ"] = typ
+	token.Header["jwk"] = jwk
+
+	signed, err := token.SignedString(k.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signed
+}
@@ -2,39 +2,132 @@ package auth
 
 import (
 	"context"
+	"crypto/x509"
 	"errors"
+	"fmt"
 	"strings"
 	"time"
 
 	"github.com/gofrs/uuid/v5"
 	"github.com/jmoiron/sqlx"
 	"github.com/ninth-realm/heimdall/crypto"
+	"github.com/ninth-realm/heimdall/event"
+	"github.com/ninth-realm/heimdall/metrics"
 	"github.com/ninth-realm/heimdall/store"
+	"github.com/ninth-realm/heimdall/tracing"
 )
 
+// ErrInvalidCredentials is returned when a user cannot be authenticated. It
+// deliberately doesn't say whether the user or the password was wrong so that
+// it can't be used to discover which users exist.
+var ErrInvalidCredentials = errors.New("invalid username or password")
+
+// invalidCredentials is an ErrInvalidCredentials that knows why the login
+// failed, so that the reason can be counted without being shown to the user.
+type invalidCredentials struct {
+	reason string
+}
+
+func (e invalidCredentials) Error() string {
+	return ErrInvalidCredentials.Error()
+}
+
+func (e invalidCredentials) Is(target error) bool {
+	return target == ErrInvalidCredentials
+}
+
 type Service struct {
-	Repo store.Repository
+	Repo     store.Repository
+	Sessions SessionSettings
+	// Tokens signs the access tokens issued to clients. Clients can't be
+	// issued tokens when it's empty.
+	Tokens JWTSettings
+	// ProofReplays remembers DPoP proofs that have been used. DPoP proofs are
+	// rejected when it's nil.
+	ProofReplays *ReplayCache
+}
+
+func (s Service) sessionSettings() SessionSettings {
+	if s.Sessions == (SessionSettings{}) {
+		return DefaultSessionSettings
+	}
+
+	return s.Sessions
 }
 
-func (s Service) Login(ctx context.Context, username, password string) (Token, error) {
+// Credentials are the details provided by a user when logging in. The user
+// agent and IP address are recorded on the resulting session.
+type Credentials struct {
+	Username  string
+	Password  string
+	UserAgent string
+	IPAddress string
+	// RememberMe requests a long lived session. See
+	// SessionSettings.RememberMeLifetime.
+	RememberMe bool
+}
+
+// lastSeenResolution is how stale a session's last seen time may get before it
+// is updated along with its expiration. This avoids writing to the session on
+// every single request.
+const lastSeenResolution = time.Minute
+
+func (s Service) Login(ctx context.Context, creds Credentials) (Token, error) {
+	ctx, span := tracing.Start(ctx, "auth.Service.Login")
+	defer span.End()
+
+	token, err := s.login(ctx, creds)
+
+	var invalid invalidCredentials
+	switch {
+	case err == nil:
+		metrics.LoginSucceeded()
+	case errors.As(err, &invalid):
+		metrics.LoginFailed(invalid.reason)
+	default:
+		metrics.LoginFailed(metrics.ReasonError)
+	}
+
+	if errors.Is(err, ErrInvalidCredentials) {
+		// The login's transaction has been rolled back, so the failure is
+		// recorded on its own.
+		auditErr := event.Emit(s.Repo, event.Event{
+			Type:   event.LoginFailed,
+			Target: event.Target{Type: event.TargetEmail, ID: creds.Username},
+		}, store.QueryOptions{Ctx: ctx})
+		if auditErr != nil {
+			return Token{}, auditErr
+		}
+	}
+
+	return token, err
+}
+
+func (s Service) login(ctx context.Context, creds Credentials) (Token, error) {
 	return store.RunUnitOfWork(ctx, s.Repo, func(tx *sqlx.Tx) (Token, error) {
 		opts := store.QueryOptions{Ctx: ctx, Txn: tx}
 
-		user, err := s.Repo.GetUserByEmail(username, opts)
-		if err != nil {
+		user, err := s.Repo.GetUserByEmail(creds.Username, opts)
+		if errors.Is(err, store.NotFoundError{}) {
+			return Token{}, invalidCredentials{reason: metrics.ReasonUnknownUser}
+		} else if err != nil {
 			return Token{}, err
 		}
 
 		hash, err := s.Repo.GetUserPasswordHash(user.ID, opts)
-		if err != nil {
+		if errors.Is(err, store.NotFoundError{}) {
+			return Token{}, invalidCredentials{reason: metrics.ReasonNoPassword}
+		} else if err != nil {
 			return Token{}, err
 		}
 
-		correctPassword, err := crypto.ValidatePassword(password, hash)
+		_, hashSpan := tracing.Start(ctx, "crypto.ValidatePassword")
+		correctPassword, err := crypto.ValidatePassword(creds.Password, hash)
+		hashSpan.End()
 		if err != nil {
 			return Token{}, err
 		} else if !correctPassword {
-			return Token{}, errors.New("incorrect password")
+			return Token{}, invalidCredentials{reason: metrics.ReasonWrongPassword}
 		}
 
 		token, err := crypto.GenerateRandBase64String(32)
@@ -42,77 +135,429 @@ func (s Service) Login(ctx context.Context, username, password string) (Token, e
 			return Token{}, err
 		}
 
-		now := time.Now()
-		lifespan := 24 * time.Hour
-		err = s.Repo.SaveSession(store.Session{
-			Token:     token,
-			UserId:    user.ID,
-			CreatedAt: now,
-			ExpiresAt: now.Add(lifespan),
+		now := time.Now().UTC()
+		expiresAt := s.sessionSettings().expiresAt(creds.RememberMe, now, now)
+		sessionID, err := s.Repo.InsertSession(store.NewSession{
+			TokenHash:  crypto.HashToken(token),
+			UserID:     user.ID,
+			UserAgent:  optionalString(creds.UserAgent),
+			IPAddress:  optionalString(creds.IPAddress),
+			RememberMe: creds.RememberMe,
+			CreatedAt:  now,
+			ExpiresAt:  expiresAt,
 		}, opts)
 		if err != nil {
 			return Token{}, err
 		}
 
-		return Token{AccessToken: token, Lifespan: int(lifespan.Seconds())}, nil
+		err = event.Emit(s.Repo, event.Event{
+			Type:   event.LoginSucceeded,
+			Target: event.Target{Type: event.TargetSession, ID: sessionID.String()},
+			Actor:  &event.Actor{Type: event.ActorUser, ID: user.ID.String()},
+		}, opts)
+		if err != nil {
+			return Token{}, err
+		}
+
+		return Token{AccessToken: token, Lifespan: int(expiresAt.Sub(now).Seconds())}, nil
 	})
 }
 
-func (s Service) Logout(ctx context.Context, token string) error {
-	err := s.Repo.DeleteSession(token, store.QueryOptions{Ctx: ctx})
-	// When logging out, we don't care about missing session errors. If the
-	// session doesn't exist, then there's just nothing to do.
-	if !errors.Is(err, store.NotFoundError{}) {
-		return err
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
 	}
 
-	return nil
+	return &s
+}
+
+func (s Service) Logout(ctx context.Context, token string) error {
+	ctx, span := tracing.Start(ctx, "auth.Service.Logout")
+	defer span.End()
+
+	hash := crypto.HashToken(token)
+
+	_, err := store.RunUnitOfWork(ctx, s.Repo, func(tx *sqlx.Tx) (struct{}, error) {
+		opts := store.QueryOptions{Ctx: ctx, Txn: tx}
+
+		// The session is read first to find out whose it is. An expired
+		// session is still deleted, but there's no logout to record since
+		// the user was already logged out.
+		session, sessionErr := s.Repo.GetSession(hash, opts)
+
+		err := s.Repo.DeleteSession(hash, opts)
+		// When logging out, we don't care about missing session errors. If
+		// the session doesn't exist, then there's just nothing to do.
+		if errors.Is(err, store.NotFoundError{}) {
+			return struct{}{}, nil
+		} else if err != nil {
+			return struct{}{}, err
+		}
+
+		if sessionErr != nil {
+			return struct{}{}, nil
+		}
+
+		return struct{}{}, event.Emit(s.Repo, event.Event{
+			Type:   event.Logout,
+			Target: event.Target{Type: event.TargetSession, ID: session.ID.String()},
+			Actor:  &event.Actor{Type: event.ActorUser, ID: session.UserId.String()},
+		}, opts)
+	})
+
+	return err
 }
 
+// IntrospectToken describes a session token or a client's access token. A
+// client token that's bound to a certificate or a DPoP key reports the key's
+// thumbprint in its confirmation, for the resource server to compare with the
+// certificate or DPoP proof it was presented with, as described by RFC 8705
+// and RFC 9449.
 func (s Service) IntrospectToken(ctx context.Context, token string) (TokenInfo, error) {
+	ctx, span := tracing.Start(ctx, "auth.Service.IntrospectToken")
+	defer span.End()
+
+	// Session tokens are base64 and never contain a dot, unlike JWTs.
+	if strings.Count(token, ".") == 2 {
+		return s.introspectClientToken(ctx, token)
+	}
+
 	return store.RunUnitOfWork(ctx, s.Repo, func(tx *sqlx.Tx) (TokenInfo, error) {
 		opts := store.QueryOptions{Ctx: ctx, Txn: tx}
 
-		session, err := s.Repo.GetSession(token, opts)
+		session, err := s.Repo.GetSession(crypto.HashToken(token), opts)
 		if err != nil {
 			return TokenInfo{}, err
 		}
 
+		settings := s.sessionSettings()
+
+		now := time.Now().UTC()
+		if settings.isExpired(session, now) {
+			return TokenInfo{}, ErrSessionExpired
+		}
+
+		if now.Sub(session.LastSeenAt) > lastSeenResolution {
+			session.LastSeenAt = now
+			session.ExpiresAt = settings.expiresAt(session.RememberMe, session.CreatedAt, now)
+
+			err = s.Repo.TouchSession(session.ID, session.LastSeenAt, session.ExpiresAt, opts)
+			if err != nil {
+				return TokenInfo{}, err
+			}
+		}
+
 		return TokenInfo{
-			Active:    true,
-			ExpiresAt: int(session.ExpiresAt.Sub(time.Now()).Seconds()),
-			UserID:    session.UserId.String(),
+			Active:     true,
+			ExpiresAt:  int(session.ExpiresAt.Sub(now).Seconds()),
+			UserID:     session.UserId.String(),
+			SessionID:  session.ID.String(),
+			AuthTime:   session.AuthenticatedAt.Unix(),
+			RememberMe: session.RememberMe,
 		}, nil
 	})
 }
 
-func (s Service) ValidateAPIKey(ctx context.Context, key string) error {
+// ErrClientTokensDisabled is returned when issuing a client an access token
+// without any signing settings.
+var ErrClientTokensDisabled = errors.New("client access tokens aren't enabled")
+
+// ErrSessionExpired is returned for session tokens whose session has been idle
+// for too long or has reached its absolute lifetime.
+var ErrSessionExpired = errors.New("session expired")
+
+// ErrInvalidAccessToken is returned for tokens that aren't valid client access
+// tokens, or whose client has been disabled.
+var ErrInvalidAccessToken = errors.New("invalid access token")
+
+// errTokenNotBound is returned for bound tokens that are presented without
+// proof of the key they're bound to.
+var errTokenNotBound = errors.New("token is bound to a different key")
+
+// IssueClientToken issues an access token to an already authenticated client.
+// When the client authenticated with a certificate, or sent a DPoP proof, the
+// token is bound to that key, so that it can't be used by anyone who doesn't
+// also hold the key. An invalid DPoP proof results in ErrInvalidDPoPProof.
+func (s Service) IssueClientToken(ctx context.Context, clientID uuid.UUID, proof TokenProof) (Token, error) {
+	_, span := tracing.Start(ctx, "auth.Service.IssueClientToken")
+	defer span.End()
+
+	if !s.Tokens.enabled() {
+		return Token{}, ErrClientTokensDisabled
+	}
+
+	var cnf Confirmation
+	if proof.Certificate != nil {
+		cnf.CertificateThumbprint = CertificateThumbprint(proof.Certificate)
+	}
+
+	if proof.DPoP != nil {
+		thumbprint, err := verifyDPoPProof(proof.DPoP, "", s.ProofReplays, time.Now())
+		if err != nil {
+			return Token{}, err
+		}
+
+		cnf.JWKThumbprint = thumbprint
+	}
+
+	if cnf == (Confirmation{}) {
+		return generateClientJWT(clientID, nil, s.Tokens)
+	}
+
+	return generateClientJWT(clientID, &cnf, s.Tokens)
+}
+
+// ValidateAccessToken checks an access token presented to Heimdall itself,
+// rather than to a resource server that introspects it. Only client tokens are
+// accepted. A token bound to a certificate must be presented over a connection
+// made with that certificate, and one bound to a DPoP key with a proof signed
+// by that key.
+func (s Service) ValidateAccessToken(ctx context.Context, token string, proof TokenProof) (TokenInfo, error) {
+	ctx, span := tracing.Start(ctx, "auth.Service.ValidateAccessToken")
+	defer span.End()
+
+	if strings.Count(token, ".") != 2 {
+		return TokenInfo{}, ErrInvalidAccessToken
+	}
+
+	info, err := s.introspectClientToken(ctx, token)
+	if err != nil {
+		return TokenInfo{}, err
+	}
+
+	if cnf := info.Confirmation; cnf != nil && cnf.CertificateThumbprint != "" {
+		if proof.Certificate == nil || CertificateThumbprint(proof.Certificate) != cnf.CertificateThumbprint {
+			return TokenInfo{}, errTokenNotBound
+		}
+	}
+
+	if cnf := info.Confirmation; cnf != nil && cnf.JWKThumbprint != "" {
+		if proof.DPoP == nil {
+			return TokenInfo{}, fmt.Errorf("%w: missing proof for a DPoP bound token", ErrInvalidDPoPProof)
+		}
+
+		thumbprint, err := verifyDPoPProof(proof.DPoP, token, s.ProofReplays, time.Now())
+		if err != nil {
+			return TokenInfo{}, err
+		} else if thumbprint != cnf.JWKThumbprint {
+			return TokenInfo{}, errTokenNotBound
+		}
+	}
+
+	return info, nil
+}
+
+// introspectClientToken checks a client's access token. The client must still
+// be enabled, so that disabling or deleting it also revokes its tokens.
+func (s Service) introspectClientToken(ctx context.Context, token string) (TokenInfo, error) {
+	if !s.Tokens.enabled() {
+		return TokenInfo{}, ErrClientTokensDisabled
+	}
+
+	claims, err := parseClientJWT(token, s.Tokens)
+	if err != nil {
+		return TokenInfo{}, fmt.Errorf("%w: %v", ErrInvalidAccessToken, err)
+	}
+
+	clientID, err := uuid.FromString(claims.ClientID)
+	if err != nil {
+		return TokenInfo{}, fmt.Errorf("%w: %v", ErrInvalidAccessToken, err)
+	}
+
+	client, err := s.Repo.GetClientById(clientID, store.QueryOptions{Ctx: ctx})
+	if err != nil {
+		return TokenInfo{}, err
+	}
+
+	if !client.Enabled {
+		return TokenInfo{}, fmt.Errorf("%w: client is disabled", ErrInvalidAccessToken)
+	}
+
+	return TokenInfo{
+		Active:       true,
+		ClientID:     claims.ClientID,
+		ExpiresAt:    int(time.Until(claims.ExpiresAt.Time).Seconds()),
+		Confirmation: claims.Confirmation,
+	}, nil
+}
+
+// CheckSigningKey reports whether the settings used to sign client access
+// tokens are usable.
+func (s Service) CheckSigningKey(ctx context.Context) error {
+	return s.Tokens.validate()
+}
+
+// errInvalidAPIKey is returned for API keys that don't belong to any client.
+var errInvalidAPIKey = errors.New("invalid API key")
+
+// ValidateAPIKey checks that the provided key belongs to a client and returns
+// the ID of that client.
+func (s Service) ValidateAPIKey(ctx context.Context, key string) (uuid.UUID, error) {
+	ctx, span := tracing.Start(ctx, "auth.Service.ValidateAPIKey")
+	defer span.End()
+
+	clientID, err := s.validateAPIKey(ctx, key)
+
+	switch {
+	case err == nil:
+		metrics.APIKeyValidated(metrics.ResultValid)
+	case errors.Is(err, errInvalidAPIKey), errors.Is(err, store.NotFoundError{}):
+		metrics.APIKeyValidated(metrics.ResultInvalid)
+	default:
+		metrics.APIKeyValidated(metrics.ResultError)
+	}
+
+	return clientID, err
+}
+
+func (s Service) validateAPIKey(ctx context.Context, key string) (uuid.UUID, error) {
 	clientIDStr, token, found := strings.Cut(key, ":")
 	if !found {
-		return errors.New("malformed API key")
+		return uuid.Nil, fmt.Errorf("%w: malformed key", errInvalidAPIKey)
 	}
 
 	clientID, err := uuid.FromString(clientIDStr)
-	if !found {
-		return errors.New("invalid client ID")
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%w: invalid client ID", errInvalidAPIKey)
 	}
 
 	prefix, suffix, found := strings.Cut(token, ".")
 	if !found {
-		return errors.New("malformed API key")
+		return uuid.Nil, fmt.Errorf("%w: malformed key", errInvalidAPIKey)
 	}
 
 	k, err := s.Repo.GetClientAPIKey(clientID, prefix, store.QueryOptions{Ctx: ctx})
 	if err != nil {
-		return err
+		return uuid.Nil, err
 	}
 
+	_, hashSpan := tracing.Start(ctx, "crypto.ValidatePassword")
 	ok, err := crypto.ValidatePassword(suffix, k.Hash)
+	hashSpan.End()
 	if err != nil {
-		return err
+		return uuid.Nil, err
 	} else if !ok {
-		return errors.New("invalid API key")
+		return uuid.Nil, errInvalidAPIKey
 	}
 
-	return nil
+	return clientID, nil
+}
+
+// errInvalidClientCertificate is returned for client certificates that don't
+// name an enabled client.
+var errInvalidClientCertificate = errors.New("invalid client certificate")
+
+// ValidateClientCertificate returns the ID of the client that a certificate
+// was issued to. The certificate must already have been verified against the
+// trusted client CAs, and its subject's common name must be the ID of an
+// enabled client.
+func (s Service) ValidateClientCertificate(ctx context.Context, cert *x509.Certificate) (uuid.UUID, error) {
+	ctx, span := tracing.Start(ctx, "auth.Service.ValidateClientCertificate")
+	defer span.End()
+
+	clientID, err := uuid.FromString(cert.Subject.CommonName)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%w: common name isn't a client ID", errInvalidClientCertificate)
+	}
+
+	client, err := s.Repo.GetClientById(clientID, store.QueryOptions{Ctx: ctx})
+	if errors.Is(err, store.NotFoundError{}) {
+		return uuid.Nil, errInvalidClientCertificate
+	} else if err != nil {
+		return uuid.Nil, err
+	}
+
+	if !client.Enabled {
+		return uuid.Nil, fmt.Errorf("%w: client is disabled", errInvalidClientCertificate)
+	}
+
+	return clientID, nil
+}
+
+// Reauthenticate confirms a user's password for an existing session. This is
+// required before a remember me session can be used for sensitive operations.
+func (s Service) Reauthenticate(ctx context.Context, userID, sessionID uuid.UUID, password string) error {
+	ctx, span := tracing.Start(ctx, "auth.Service.Reauthenticate")
+	defer span.End()
+
+	_, err := store.RunUnitOfWork(ctx, s.Repo, func(tx *sqlx.Tx) (struct{}, error) {
+		opts := store.QueryOptions{Ctx: ctx, Txn: tx}
+
+		hash, err := s.Repo.GetUserPasswordHash(userID, opts)
+		if errors.Is(err, store.NotFoundError{}) {
+			return struct{}{}, ErrInvalidCredentials
+		} else if err != nil {
+			return struct{}{}, err
+		}
+
+		_, hashSpan := tracing.Start(ctx, "crypto.ValidatePassword")
+		correctPassword, err := crypto.ValidatePassword(password, hash)
+		hashSpan.End()
+		if err != nil {
+			return struct{}{}, err
+		} else if !correctPassword {
+			return struct{}{}, ErrInvalidCredentials
+		}
+
+		err = s.Repo.ReauthenticateSession(sessionID, time.Now().UTC(), opts)
+		if err != nil {
+			return struct{}{}, err
+		}
+
+		return struct{}{}, event.Emit(s.Repo, event.Event{
+			Type:   event.Reauthenticated,
+			Target: event.Target{Type: event.TargetSession, ID: sessionID.String()},
+		}, opts)
+	})
+
+	return err
+}
+
+func (s Service) ListUserSessions(ctx context.Context, userID uuid.UUID) ([]store.Session, error) {
+	ctx, span := tracing.Start(ctx, "auth.Service.ListUserSessions")
+	defer span.End()
+
+	return s.Repo.ListUserSessions(userID, store.QueryOptions{Ctx: ctx})
+}
+
+func (s Service) RevokeUserSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "auth.Service.RevokeUserSession")
+	defer span.End()
+
+	_, err := store.RunUnitOfWork(ctx, s.Repo, func(tx *sqlx.Tx) (struct{}, error) {
+		opts := store.QueryOptions{Ctx: ctx, Txn: tx}
+
+		if err := s.Repo.DeleteUserSession(userID, sessionID, opts); err != nil {
+			return struct{}{}, err
+		}
+
+		return struct{}{}, event.Emit(s.Repo, event.Event{
+			Type:   event.SessionRevoked,
+			Target: event.Target{Type: event.TargetSession, ID: sessionID.String()},
+		}, opts)
+	})
+
+	return err
+}
+
+// RevokeUserSessions ends all of a user's sessions other than the one
+// identified by keepID. Passing uuid.Nil ends every session.
+func (s Service) RevokeUserSessions(ctx context.Context, userID, keepID uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "auth.Service.RevokeUserSessions")
+	defer span.End()
+
+	_, err := store.RunUnitOfWork(ctx, s.Repo, func(tx *sqlx.Tx) (struct{}, error) {
+		opts := store.QueryOptions{Ctx: ctx, Txn: tx}
+
+		if err := s.Repo.DeleteUserSessions(userID, keepID, opts); err != nil {
+			return struct{}{}, err
+		}
+
+		return struct{}{}, event.Emit(s.Repo, event.Event{
+			Type:   event.SessionsRevoked,
+			Target: event.Target{Type: event.TargetUser, ID: userID.String()},
+		}, opts)
+	})
+
+	return err
 }
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ninth-realm/heimdall/store"
+)
+
+// SessionSettings control how long a session may be used for.
+type SessionSettings struct {
+	// IdleTimeout is how long a session can go unused before it expires. Every
+	// use of the session pushes its expiration back to this far in the future.
+	IdleTimeout time.Duration
+	// AbsoluteTimeout is the max lifetime of a session, no matter how often it
+	// is used.
+	AbsoluteTimeout time.Duration
+	// RememberMeLifetime is the fixed lifetime of sessions created with the
+	// remember me option. These sessions are not subject to the idle or
+	// absolute timeouts.
+	RememberMeLifetime time.Duration
+}
+
+// DefaultSessionSettings are used when a Service is not given any settings.
+var DefaultSessionSettings = SessionSettings{
+	IdleTimeout:        24 * time.Hour,
+	AbsoluteTimeout:    7 * 24 * time.Hour,
+	RememberMeLifetime: 30 * 24 * time.Hour,
+}
+
+// Validate checks that sessions can be used at all. Every duration must be
+// positive, and the idle timeout can't be longer than the absolute timeout
+// that caps it.
+func (s SessionSettings) Validate() error {
+	if s.IdleTimeout <= 0 || s.AbsoluteTimeout <= 0 || s.RememberMeLifetime <= 0 {
+		return errors.New("session timeouts and lifetimes must be positive")
+	}
+
+	if s.IdleTimeout > s.AbsoluteTimeout {
+		return errors.New("session idle timeout must not exceed the absolute timeout")
+	}
+
+	return nil
+}
+
+// maxLifetime is the absolute latest that a session can be used.
+func (s SessionSettings) maxLifetime(rememberMe bool) time.Duration {
+	if rememberMe {
+		return s.RememberMeLifetime
+	}
+
+	return s.AbsoluteTimeout
+}
+
+// expiresAt determines when a session should expire if it is used at the
+// provided time. The idle timeout is capped by the absolute timeout, and remember
+// me sessions always expire at the end of their lifetime.
+func (s SessionSettings) expiresAt(rememberMe bool, createdAt, usedAt time.Time) time.Time {
+	absolute := createdAt.Add(s.maxLifetime(rememberMe))
+	if rememberMe {
+		return absolute
+	}
+
+	idle := usedAt.Add(s.IdleTimeout)
+	if absolute.Before(idle) {
+		return absolute
+	}
+
+	return idle
+}
+
+// isExpired determines if a session can no longer be used at the provided time.
+func (s SessionSettings) isExpired(session store.Session, at time.Time) bool {
+	maxExpiration := session.CreatedAt.Add(s.maxLifetime(session.RememberMe))
+
+	return !at.Before(session.ExpiresAt) || !at.Before(maxExpiration)
+}
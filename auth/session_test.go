@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ninth-realm/heimdall/store"
+)
+
+func TestSessionSettings_expiresAt(t *testing.T) {
+	createdAt := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	settings := SessionSettings{
+		IdleTimeout:        time.Hour,
+		AbsoluteTimeout:    24 * time.Hour,
+		RememberMeLifetime: 30 * 24 * time.Hour,
+	}
+
+	tests := []struct {
+		name       string
+		rememberMe bool
+		usedAt     time.Time
+		want       time.Time
+	}{
+		{
+			name:   "New session expires after idle timeout",
+			usedAt: createdAt,
+			want:   createdAt.Add(time.Hour),
+		},
+		{
+			name:   "Use extends the session",
+			usedAt: createdAt.Add(10 * time.Hour),
+			want:   createdAt.Add(11 * time.Hour),
+		},
+		{
+			name:   "Extension is capped by absolute timeout",
+			usedAt: createdAt.Add(23*time.Hour + 30*time.Minute),
+			want:   createdAt.Add(24 * time.Hour),
+		},
+		{
+			name:       "Remember me session expires at end of lifetime",
+			rememberMe: true,
+			usedAt:     createdAt.Add(10 * time.Hour),
+			want:       createdAt.Add(30 * 24 * time.Hour),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := settings.expiresAt(tt.rememberMe, createdAt, tt.usedAt); !got.Equal(tt.want) {
+				t.Errorf("SessionSettings.expiresAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionSettings_isExpired(t *testing.T) {
+	createdAt := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	settings := SessionSettings{
+		IdleTimeout:        time.Hour,
+		AbsoluteTimeout:    24 * time.Hour,
+		RememberMeLifetime: 30 * 24 * time.Hour,
+	}
+
+	tests := []struct {
+		name    string
+		session store.Session
+		at      time.Time
+		want    bool
+	}{
+		{
+			name:    "Active session",
+			session: store.Session{CreatedAt: createdAt, ExpiresAt: createdAt.Add(time.Hour)},
+			at:      createdAt.Add(time.Minute),
+			want:    false,
+		},
+		{
+			name:    "Idle session",
+			session: store.Session{CreatedAt: createdAt, ExpiresAt: createdAt.Add(time.Hour)},
+			at:      createdAt.Add(2 * time.Hour),
+			want:    true,
+		},
+		{
+			name:    "Session past absolute timeout",
+			session: store.Session{CreatedAt: createdAt, ExpiresAt: createdAt.Add(48 * time.Hour)},
+			at:      createdAt.Add(25 * time.Hour),
+			want:    true,
+		},
+		{
+			name: "Remember me session past absolute timeout",
+			session: store.Session{
+				CreatedAt:  createdAt,
+				ExpiresAt:  createdAt.Add(30 * 24 * time.Hour),
+				RememberMe: true,
+			},
+			at:   createdAt.Add(25 * time.Hour),
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := settings.isExpired(tt.session, tt.at); got != tt.want {
+				t.Errorf("SessionSettings.isExpired() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionSettings_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings SessionSettings
+		wantErr  bool
+	}{
+		{name: "Defaults", settings: DefaultSessionSettings},
+		{
+			name:     "Idle equal to absolute",
+			settings: SessionSettings{IdleTimeout: time.Hour, AbsoluteTimeout: time.Hour, RememberMeLifetime: time.Hour},
+		},
+		{
+			name:     "Zero idle timeout",
+			settings: SessionSettings{AbsoluteTimeout: time.Hour, RememberMeLifetime: time.Hour},
+			wantErr:  true,
+		},
+		{
+			name:     "Zero absolute timeout",
+			settings: SessionSettings{IdleTimeout: time.Hour, RememberMeLifetime: time.Hour},
+			wantErr:  true,
+		},
+		{
+			name:     "Negative remember me lifetime",
+			settings: SessionSettings{IdleTimeout: time.Hour, AbsoluteTimeout: time.Hour, RememberMeLifetime: -time.Hour},
+			wantErr:  true,
+		},
+		{
+			name:     "Idle longer than absolute",
+			settings: SessionSettings{IdleTimeout: 2 * time.Hour, AbsoluteTimeout: time.Hour, RememberMeLifetime: time.Hour},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.settings.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,10 +1,14 @@
 package auth
 
 import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
 	"errors"
 	"strings"
 	"time"
 
+	"github.com/gofrs/uuid/v5"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/ninth-realm/heimdall/store"
 )
@@ -13,13 +17,53 @@ import (
 type Token struct {
 	AccessToken string `json:"accessToken"`
 	Lifespan    int    `json:"lifespan"`
+	// TokenType is how the token must be presented: "Bearer", or "DPoP" for
+	// tokens that must be sent with a DPoP proof.
+	TokenType string `json:"tokenType,omitempty"`
+}
+
+// Confirmation binds a token to a key that whoever presents it must prove they
+// hold, as described by RFC 7800.
+type Confirmation struct {
+	// CertificateThumbprint is the thumbprint of the client certificate the
+	// token was issued over. See RFC 8705.
+	CertificateThumbprint string `json:"x5t#S256,omitempty"`
+	// JWKThumbprint is the thumbprint of the key the token's DPoP proofs
+	// must be signed with. See RFC 9449.
+	JWKThumbprint string `json:"jkt,omitempty"`
+}
+
+// TokenProof is what was presented alongside a token to show that it's being
+// used by whoever it was issued to.
+type TokenProof struct {
+	// Certificate is the verified client certificate of the connection the
+	// token was presented over.
+	Certificate *x509.Certificate
+	// DPoP is the DPoP proof sent with the token, if any.
+	DPoP *DPoPProof
+}
+
+// CertificateThumbprint is the base64url encoded SHA-256 hash of a
+// certificate, as used by the x5t#S256 confirmation method.
+func CertificateThumbprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
 }
 
 type TokenInfo struct {
 	Active    bool   `json:"active"`
 	UserID    string `json:"sub,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
 	Username  string `json:"username,omitempty"`
 	ExpiresAt int    `json:"exp,omitempty"`
+	SessionID string `json:"sid,omitempty"`
+	// AuthTime is the unix timestamp of when the user last entered their password
+	// for the session.
+	AuthTime   int64 `json:"auth_time,omitempty"`
+	RememberMe bool  `json:"rememberMe,omitempty"`
+	// Confirmation is set when the token is bound to a key, and can only be
+	// used along with proof of that key.
+	Confirmation *Confirmation `json:"cnf,omitempty"`
 }
 
 type signingAlgorithm string
@@ -61,21 +105,68 @@ func (s JWTSettings) validate() error {
 	return nil
 }
 
-func generateJWT(user store.User, settings JWTSettings) (Token, error) {
-	if err := settings.validate(); err != nil {
-		return Token{}, err
-	}
-
-	t := jwt.New(jwt.GetSigningMethod(string(settings.Algorithm)))
+// enabled reports whether any JWT settings have been configured.
+func (s JWTSettings) enabled() bool {
+	return s != JWTSettings{}
+}
 
+func generateJWT(user store.User, settings JWTSettings) (Token, error) {
 	now := time.Now()
-	t.Claims = &jwt.MapClaims{
+
+	return signJWT(&jwt.MapClaims{
 		"iss": settings.Issuer,
 		"iat": jwt.NewNumericDate(now),
 		"exp": jwt.NewNumericDate(now.Add(time.Second * time.Duration(settings.Lifespan))),
 		"sub": user.ID,
+	}, settings)
+}
+
+// clientClaims are the claims of an access token issued to a client.
+type clientClaims struct {
+	jwt.RegisteredClaims
+	ClientID     string        `json:"client_id"`
+	Confirmation *Confirmation `json:"cnf,omitempty"`
+}
+
+// generateClientJWT issues an access token to a client, bound to cnf if it's
+// set.
+func generateClientJWT(clientID uuid.UUID, cnf *Confirmation, settings JWTSettings) (Token, error) {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return Token{}, err
 	}
 
+	now := time.Now()
+
+	token, err := signJWT(clientClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        id.String(),
+			Issuer:    settings.Issuer,
+			Subject:   clientID.String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Second * time.Duration(settings.Lifespan))),
+		},
+		ClientID:     clientID.String(),
+		Confirmation: cnf,
+	}, settings)
+	if err != nil {
+		return Token{}, err
+	}
+
+	if cnf != nil && cnf.JWKThumbprint != "" {
+		token.TokenType = "DPoP"
+	}
+
+	return token, nil
+}
+
+func signJWT(claims jwt.Claims, settings JWTSettings) (Token, error) {
+	if err := settings.validate(); err != nil {
+		return Token{}, err
+	}
+
+	t := jwt.NewWithClaims(jwt.GetSigningMethod(string(settings.Algorithm)), claims)
+
 	signed, err := t.SignedString([]byte(settings.SigningKey))
 	if err != nil {
 		return Token{}, err
@@ -84,6 +175,7 @@ func generateJWT(user store.User, settings JWTSettings) (Token, error) {
 	return Token{
 		AccessToken: signed,
 		Lifespan:    settings.Lifespan,
+		TokenType:   "Bearer",
 	}, nil
 }
 
@@ -91,5 +183,27 @@ func validateJWT(token string, settings JWTSettings) (*jwt.Token, error) {
 	return jwt.Parse(
 		token,
 		func(t *jwt.Token) (interface{}, error) { return []byte(settings.SigningKey), nil },
+		jwt.WithValidMethods([]string{string(settings.Algorithm)}),
+	)
+}
+
+// parseClientJWT checks a client's access token's signature, issuer and
+// expiry, and returns its claims.
+func parseClientJWT(token string, settings JWTSettings) (clientClaims, error) {
+	var claims clientClaims
+	_, err := jwt.ParseWithClaims(
+		token,
+		&claims,
+		func(t *jwt.Token) (interface{}, error) { return []byte(settings.SigningKey), nil },
+		jwt.WithValidMethods([]string{string(settings.Algorithm)}),
 	)
+	if err != nil {
+		return clientClaims{}, err
+	}
+
+	if !claims.VerifyIssuer(settings.Issuer, true) {
+		return clientClaims{}, errors.New("token has the wrong issuer")
+	}
+
+	return claims, nil
 }
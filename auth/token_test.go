@@ -1,9 +1,12 @@
 package auth
 
 import (
+	"context"
+	"crypto/x509"
 	"testing"
 
 	"github.com/gofrs/uuid/v5"
+	"github.com/golang-jwt/jwt/v4"
 	"github.com/ninth-realm/heimdall/store"
 )
 
@@ -129,3 +132,153 @@ func Test_generateJWT(t *testing.T) {
 		})
 	}
 }
+
+func Test_parseClientJWT(t *testing.T) {
+	settings := JWTSettings{
+		Issuer:     "Heimdall",
+		Lifespan:   60,
+		SigningKey: "secretkey",
+		Algorithm:  HMAC256Algorithm,
+	}
+	clientID := uuid.Must(uuid.NewV4())
+	cnf := &Confirmation{CertificateThumbprint: "thumbprint"}
+
+	token, err := generateClientJWT(clientID, cnf, settings)
+	if err != nil {
+		t.Fatalf("generateClientJWT() error = %v", err)
+	}
+
+	if token.TokenType != "Bearer" {
+		t.Errorf("token type = %s, want Bearer", token.TokenType)
+	}
+
+	t.Run("Valid token", func(t *testing.T) {
+		claims, err := parseClientJWT(token.AccessToken, settings)
+		if err != nil {
+			t.Fatalf("parseClientJWT() error = %v", err)
+		}
+
+		if claims.ClientID != clientID.String() {
+			t.Errorf("client ID = %s, want %s", claims.ClientID, clientID)
+		}
+
+		if claims.Confirmation == nil || *claims.Confirmation != *cnf {
+			t.Errorf("confirmation = %+v, want %+v", claims.Confirmation, cnf)
+		}
+	})
+
+	t.Run("Wrong signing key", func(t *testing.T) {
+		other := settings
+		other.SigningKey = "otherkey"
+
+		if _, err := parseClientJWT(token.AccessToken, other); err == nil {
+			t.Error("parseClientJWT() error = nil, want an invalid signature")
+		}
+	})
+
+	t.Run("Wrong issuer", func(t *testing.T) {
+		other := settings
+		other.Issuer = "Someone else"
+
+		if _, err := parseClientJWT(token.AccessToken, other); err == nil {
+			t.Error("parseClientJWT() error = nil, want the wrong issuer")
+		}
+	})
+
+	t.Run("Unsigned token", func(t *testing.T) {
+		unsigned := jwt.NewWithClaims(jwt.SigningMethodNone, clientClaims{ClientID: clientID.String()})
+		raw, err := unsigned.SignedString(jwt.UnsafeAllowNoneSignatureType)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := parseClientJWT(raw, settings); err == nil {
+			t.Error("parseClientJWT() error = nil, want the none algorithm to be rejected")
+		}
+	})
+}
+
+// clientRepo holds a single client. Only the methods used by client token
+// introspection are implemented.
+type clientRepo struct {
+	store.Repository
+	client store.Client
+}
+
+func (r clientRepo) GetClientById(id uuid.UUID, opts store.QueryOptions) (store.Client, error) {
+	if id != r.client.ID {
+		return store.Client{}, store.NotFoundError{ResourceType: "client", ResourceID: id.String()}
+	}
+
+	return r.client, nil
+}
+
+func TestService_ValidateAccessToken_certificateBinding(t *testing.T) {
+	clientID := uuid.Must(uuid.NewV4())
+	s := Service{
+		Repo: clientRepo{client: store.Client{ID: clientID, Enabled: true}},
+		Tokens: JWTSettings{
+			Issuer:     "Heimdall",
+			Lifespan:   60,
+			SigningKey: "secretkey",
+			Algorithm:  HMAC256Algorithm,
+		},
+	}
+
+	cert := &x509.Certificate{Raw: []byte("certificate")}
+	other := &x509.Certificate{Raw: []byte("another certificate")}
+
+	bound, err := s.IssueClientToken(context.Background(), clientID, TokenProof{Certificate: cert})
+	if err != nil {
+		t.Fatalf("IssueClientToken() error = %v", err)
+	}
+
+	unbound, err := s.IssueClientToken(context.Background(), clientID, TokenProof{})
+	if err != nil {
+		t.Fatalf("IssueClientToken() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		token      string
+		proof      TokenProof
+		wantActive bool
+	}{
+		{"Bound with its certificate", bound.AccessToken, TokenProof{Certificate: cert}, true},
+		{"Bound without a certificate", bound.AccessToken, TokenProof{}, false},
+		{"Bound with another certificate", bound.AccessToken, TokenProof{Certificate: other}, false},
+		{"Unbound without a certificate", unbound.AccessToken, TokenProof{}, true},
+		{"Unbound with a certificate", unbound.AccessToken, TokenProof{Certificate: cert}, true},
+		{"Not a client token", "c2Vzc2lvbg==", TokenProof{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := s.ValidateAccessToken(context.Background(), tt.token, tt.proof)
+			if tt.wantActive != (err == nil) {
+				t.Fatalf("ValidateAccessToken() error = %v, want active %v", err, tt.wantActive)
+			}
+
+			if tt.wantActive && (!info.Active || info.ClientID != clientID.String()) {
+				t.Errorf("ValidateAccessToken() = %+v, want active for client %s", info, clientID)
+			}
+		})
+	}
+
+	// Introspection is made by resource servers with their own certificates,
+	// so it reports the binding for them to check instead of checking it.
+	t.Run("Introspection reports the certificate", func(t *testing.T) {
+		info, err := s.IntrospectToken(context.Background(), bound.AccessToken)
+		if err != nil {
+			t.Fatalf("IntrospectToken() error = %v", err)
+		}
+
+		if !info.Active {
+			t.Errorf("IntrospectToken() = %+v, want active", info)
+		}
+
+		want := CertificateThumbprint(cert)
+		if info.Confirmation == nil || info.Confirmation.CertificateThumbprint != want {
+			t.Errorf("confirmation = %+v, want x5t#S256 %s", info.Confirmation, want)
+		}
+	})
+}
@@ -8,30 +8,70 @@ import (
 	"github.com/gofrs/uuid/v5"
 	"github.com/jmoiron/sqlx"
 	"github.com/ninth-realm/heimdall/crypto"
+	"github.com/ninth-realm/heimdall/event"
 	"github.com/ninth-realm/heimdall/store"
+	"github.com/ninth-realm/heimdall/tracing"
 )
 
 type Service struct {
 	Repo store.Repository
 }
 
-func (s Service) ListClients(ctx context.Context) ([]store.Client, error) {
-	return s.Repo.ListClients(store.QueryOptions{Ctx: ctx})
+// ListClients returns a single page of clients. An invalid sort or cursor
+// results in store.ErrInvalidSort or store.ErrInvalidCursor.
+func (s Service) ListClients(ctx context.Context, list store.ClientListOptions) (store.Page[store.Client], error) {
+	ctx, span := tracing.Start(ctx, "client.Service.ListClients")
+	defer span.End()
+
+	if err := list.Validate(); err != nil {
+		return store.Page[store.Client]{}, err
+	}
+
+	// Fetch an extra client to find out whether there is another page.
+	limit := list.Pagination.Limit
+	if limit > 0 {
+		list.Pagination.Limit++
+	}
+
+	clients, err := s.Repo.ListClients(list, store.QueryOptions{Ctx: ctx})
+	if err != nil {
+		return store.Page[store.Client]{}, err
+	}
+
+	return store.NewPage(clients, limit, func(client store.Client) store.Cursor {
+		return store.ClientCursor(list.Sort, client)
+	}), nil
 }
 
 func (s Service) GetClient(ctx context.Context, id uuid.UUID) (store.Client, error) {
+	ctx, span := tracing.Start(ctx, "client.Service.GetClient")
+	defer span.End()
+
 	return s.Repo.GetClientById(id, store.QueryOptions{Ctx: ctx})
 }
 
 func (s Service) CreateClient(ctx context.Context, client store.NewClient) (store.Client, error) {
+	ctx, span := tracing.Start(ctx, "client.Service.CreateClient")
+	defer span.End()
+
 	client = cleanNewClient(client)
 	return store.RunUnitOfWork(ctx, s.Repo, func(txn *sqlx.Tx) (store.Client, error) {
-		id, err := s.Repo.InsertClient(client, store.QueryOptions{Ctx: ctx, Txn: txn})
+		opts := store.QueryOptions{Ctx: ctx, Txn: txn}
+
+		id, err := s.Repo.InsertClient(client, opts)
 		if err != nil {
 			return store.Client{}, err
 		}
 
-		return s.Repo.GetClientById(id, store.QueryOptions{Ctx: ctx, Txn: txn})
+		err = event.Emit(s.Repo, event.Event{
+			Type:   event.ClientCreated,
+			Target: event.Target{Type: event.TargetClient, ID: id.String()},
+		}, opts)
+		if err != nil {
+			return store.Client{}, err
+		}
+
+		return s.Repo.GetClientById(id, opts)
 	})
 }
 
@@ -41,37 +81,113 @@ func cleanNewClient(client store.NewClient) store.NewClient {
 	return client
 }
 
-func (s Service) UpdateClient(ctx context.Context, id uuid.UUID, patch store.ClientPatch) (store.Client, error) {
-	client, err := s.Repo.GetClientById(id, store.QueryOptions{Ctx: ctx})
-	if err != nil {
-		return store.Client{}, err
-	}
+// UpdateClient applies the patch to version of the client. It fails with a
+// store.VersionMismatchError if the client has been changed since that version.
+func (s Service) UpdateClient(ctx context.Context, id uuid.UUID, version int64, patch store.ClientPatch) (store.Client, error) {
+	ctx, span := tracing.Start(ctx, "client.Service.UpdateClient")
+	defer span.End()
+
+	return store.RunUnitOfWork(ctx, s.Repo, func(txn *sqlx.Tx) (store.Client, error) {
+		opts := store.QueryOptions{Ctx: ctx, Txn: txn}
 
-	client = patch.ApplyTo(client)
+		client, err := s.Repo.GetClientById(id, opts)
+		if err != nil {
+			return store.Client{}, err
+		}
 
-	err = s.Repo.SaveClient(client, store.QueryOptions{Ctx: ctx})
-	if err != nil {
-		return store.Client{}, err
-	}
+		if client.Version != version {
+			return store.Client{}, store.VersionMismatchError{ResourceType: "client", ResourceID: id.String()}
+		}
 
-	return s.Repo.GetClientById(id, store.QueryOptions{Ctx: ctx})
+		if err = s.Repo.SaveClient(patch.ApplyTo(client), opts); err != nil {
+			return store.Client{}, err
+		}
+
+		err = event.Emit(s.Repo, event.Event{
+			Type:   event.ClientUpdated,
+			Target: event.Target{Type: event.TargetClient, ID: id.String()},
+		}, opts)
+		if err != nil {
+			return store.Client{}, err
+		}
+
+		return s.Repo.GetClientById(id, opts)
+	})
 }
 
+// DeleteClient soft deletes a client. It can be restored until it's purged.
 func (s Service) DeleteClient(ctx context.Context, id uuid.UUID) error {
-	return s.Repo.DeleteClient(id, store.QueryOptions{Ctx: ctx})
+	ctx, span := tracing.Start(ctx, "client.Service.DeleteClient")
+	defer span.End()
+
+	_, err := store.RunUnitOfWork(ctx, s.Repo, func(txn *sqlx.Tx) (struct{}, error) {
+		opts := store.QueryOptions{Ctx: ctx, Txn: txn}
+
+		if err := s.Repo.DeleteClient(id, opts); err != nil {
+			return struct{}{}, err
+		}
+
+		return struct{}{}, event.Emit(s.Repo, event.Event{
+			Type:   event.ClientDeleted,
+			Target: event.Target{Type: event.TargetClient, ID: id.String()},
+		}, opts)
+	})
+
+	return err
+}
+
+func (s Service) RestoreClient(ctx context.Context, id uuid.UUID) (store.Client, error) {
+	ctx, span := tracing.Start(ctx, "client.Service.RestoreClient")
+	defer span.End()
+
+	return store.RunUnitOfWork(ctx, s.Repo, func(txn *sqlx.Tx) (store.Client, error) {
+		opts := store.QueryOptions{Ctx: ctx, Txn: txn}
+
+		if err := s.Repo.RestoreClient(id, opts); err != nil {
+			return store.Client{}, err
+		}
+
+		err := event.Emit(s.Repo, event.Event{
+			Type:   event.ClientRestored,
+			Target: event.Target{Type: event.TargetClient, ID: id.String()},
+		}, opts)
+		if err != nil {
+			return store.Client{}, err
+		}
+
+		return s.Repo.GetClientById(id, opts)
+	})
+}
+
+// ListAllowedOrigins returns the browser origins that any enabled client
+// allows to call the API cross-origin. Together they're trusted by the whole
+// API, rather than only for their own client.
+func (s Service) ListAllowedOrigins(ctx context.Context) ([]string, error) {
+	ctx, span := tracing.Start(ctx, "client.Service.ListAllowedOrigins")
+	defer span.End()
+
+	return s.Repo.ListAllowedOrigins(store.QueryOptions{Ctx: ctx})
 }
 
 func (s Service) ListClientAPIKeys(ctx context.Context, clientID uuid.UUID) ([]store.APIKey, error) {
+	ctx, span := tracing.Start(ctx, "client.Service.ListClientAPIKeys")
+	defer span.End()
+
 	return s.Repo.ListClientAPIKeys(clientID, store.QueryOptions{Ctx: ctx})
 }
 
 func (s Service) GenerateAPIKey(ctx context.Context, newKey store.NewAPIKey) (string, error) {
+	ctx, span := tracing.Start(ctx, "client.Service.GenerateAPIKey")
+	defer span.End()
+
 	prefix, suffix, err := generateAPIKey()
 	if err != nil {
 		return "", err
 	}
 
+	_, hashSpan := tracing.Start(ctx, "crypto.GetPasswordHash")
 	hash, err := crypto.GetPasswordHash(suffix, crypto.DefaultParams)
+	hashSpan.End()
 	if err != nil {
 		return "", err
 	}
@@ -79,7 +195,26 @@ func (s Service) GenerateAPIKey(ctx context.Context, newKey store.NewAPIKey) (st
 	newKey.Prefix = prefix
 	newKey.Hash = hash
 
-	_, err = s.Repo.InsertAPIKey(newKey, store.QueryOptions{Ctx: ctx})
+	_, err = store.RunUnitOfWork(ctx, s.Repo, func(txn *sqlx.Tx) (uuid.UUID, error) {
+		opts := store.QueryOptions{Ctx: ctx, Txn: txn}
+
+		// Keys can't be used while their client is deleted, so there's no
+		// point in creating them.
+		_, err := s.Repo.GetClientById(newKey.ClientID, opts)
+		if err != nil {
+			return uuid.Nil, err
+		}
+
+		id, err := s.Repo.InsertAPIKey(newKey, opts)
+		if err != nil {
+			return uuid.Nil, err
+		}
+
+		return id, event.Emit(s.Repo, event.Event{
+			Type:   event.APIKeyCreated,
+			Target: event.Target{Type: event.TargetAPIKey, ID: id.String()},
+		}, opts)
+	})
 	if err != nil {
 		return "", err
 	}
@@ -88,5 +223,21 @@ func (s Service) GenerateAPIKey(ctx context.Context, newKey store.NewAPIKey) (st
 }
 
 func (s Service) DeleteClientAPIKey(ctx context.Context, clientID, keyID uuid.UUID) error {
-	return s.Repo.DeleteClientAPIKey(clientID, keyID, store.QueryOptions{Ctx: ctx})
+	ctx, span := tracing.Start(ctx, "client.Service.DeleteClientAPIKey")
+	defer span.End()
+
+	_, err := store.RunUnitOfWork(ctx, s.Repo, func(txn *sqlx.Tx) (struct{}, error) {
+		opts := store.QueryOptions{Ctx: ctx, Txn: txn}
+
+		if err := s.Repo.DeleteClientAPIKey(clientID, keyID, opts); err != nil {
+			return struct{}{}, err
+		}
+
+		return struct{}{}, event.Emit(s.Repo, event.Event{
+			Type:   event.APIKeyRevoked,
+			Target: event.Target{Type: event.TargetAPIKey, ID: keyID.String()},
+		}, opts)
+	})
+
+	return err
 }
@@ -3,7 +3,15 @@ package main
 import (
 	"encoding/json"
 	"flag"
+	"fmt"
+	nethttp "net/http"
 	"os"
+	"time"
+
+	"github.com/ninth-realm/heimdall/auth"
+	"github.com/ninth-realm/heimdall/http"
+	"github.com/ninth-realm/heimdall/logging"
+	"github.com/ninth-realm/heimdall/webhook"
 )
 
 type Config struct {
@@ -13,14 +21,217 @@ type Config struct {
 	configPath    string
 	setupMode     bool
 
-	Driver string        `json:"driver"`
-	SQLite *SQLiteConfig `json:"sqlite"`
+	Log      LogConfig       `json:"log"`
+	Server   ServerConfig    `json:"server"`
+	Driver   string          `json:"driver"`
+	SQLite   *SQLiteConfig   `json:"sqlite"`
+	Postgres *PostgresConfig `json:"postgres"`
+	MySQL    *MySQLConfig    `json:"mysql"`
+	Jobs     JobsConfig      `json:"jobs"`
+	Sessions SessionsConfig  `json:"sessions"`
+	CORS     CORSConfig      `json:"cors"`
+	Tokens   TokensConfig    `json:"tokens"`
+	Webhooks WebhooksConfig  `json:"webhooks"`
+	Metrics  MetricsConfig   `json:"metrics"`
+	Tracing  TracingConfig   `json:"tracing"`
+}
+
+type LogConfig struct {
+	// Level is the min level that is logged: debug, info, warn or error. The
+	// -log-level flag overrides it.
+	Level string `json:"level"`
+	// Format is how log lines are written: "json" or "text".
+	Format string `json:"format"`
+}
+
+type ServerConfig struct {
+	// ReadHeaderTimeout is how long a client has to send a request's headers.
+	ReadHeaderTimeout duration `json:"readHeaderTimeout"`
+	// ReadTimeout is how long a client has to send a whole request.
+	ReadTimeout duration `json:"readTimeout"`
+	// WriteTimeout is how long a request has to be handled and responded to.
+	WriteTimeout duration `json:"writeTimeout"`
+	// IdleTimeout is how long a keep-alive connection is kept open between
+	// requests.
+	IdleTimeout duration `json:"idleTimeout"`
+	// MaxHeaderBytes is the max size of a request's headers.
+	MaxHeaderBytes int `json:"maxHeaderBytes"`
+	// ShutdownTimeout is how long in-flight requests have to finish after
+	// SIGINT or SIGTERM before they're cut off.
+	ShutdownTimeout duration `json:"shutdownTimeout"`
+	// TLS serves the API over HTTPS. The metrics listener is always plain
+	// HTTP.
+	TLS TLSConfig `json:"tls"`
+	// PublicURL is the scheme and host that clients use to reach the API,
+	// e.g. https://auth.example.com, when it's behind a proxy. It's used to
+	// check the URLs in DPoP proofs and to recognise same-origin browser
+	// requests.
+	PublicURL string `json:"publicURL"`
+}
+
+type TLSConfig struct {
+	// CertFile and KeyFile are PEM encoded files holding the certificate,
+	// with any intermediates, and its private key. They're reloaded when they
+	// change. TLS is disabled when the whole section is empty.
+	CertFile string `json:"certFile"`
+	KeyFile  string `json:"keyFile"`
+	// ClientCAFile is a PEM encoded file of the CAs that client certificates
+	// are verified against. When set, clients can authenticate with a
+	// certificate whose common name is their client ID.
+	ClientCAFile string `json:"clientCAFile"`
 }
 
 type SQLiteConfig struct {
 	Path string `json:"path"`
 }
 
+type PostgresConfig struct {
+	DSN string `json:"dsn"`
+}
+
+type MySQLConfig struct {
+	DSN string `json:"dsn"`
+}
+
+type JobsConfig struct {
+	// SessionSweepInterval is how often expired sessions are removed from the
+	// database. A zero or negative interval disables the job.
+	SessionSweepInterval duration `json:"sessionSweepInterval"`
+	// PurgeInterval is how often soft deleted users and clients are checked
+	// for removal. A zero or negative interval disables the job.
+	PurgeInterval duration `json:"purgeInterval"`
+	// PurgeRetention is how long soft deleted users and clients are kept,
+	// and can be restored, before they're permanently removed.
+	PurgeRetention duration `json:"purgeRetention"`
+	// EventDispatchInterval is how often new events are handed to the audit
+	// log, webhooks and metrics. A zero or negative interval disables the job, and
+	// events wait in the outbox until it's enabled.
+	EventDispatchInterval duration `json:"eventDispatchInterval"`
+	// WebhookDispatchInterval is how often due webhook deliveries are sent.
+	// A zero or negative interval disables the job.
+	WebhookDispatchInterval duration `json:"webhookDispatchInterval"`
+}
+
+type SessionsConfig struct {
+	// IdleTimeout is how long a session can go unused before it expires.
+	IdleTimeout duration `json:"idleTimeout"`
+	// AbsoluteTimeout is the max lifetime of a session regardless of use.
+	AbsoluteTimeout duration `json:"absoluteTimeout"`
+	// RememberMeLifetime is the lifetime of sessions created with remember me.
+	RememberMeLifetime duration `json:"rememberMeLifetime"`
+	// ReauthenticationWindow is how recently a remember me session must have
+	// confirmed its password before performing sensitive operations.
+	ReauthenticationWindow duration `json:"reauthenticationWindow"`
+	// CookieSameSite is the SameSite attribute of the session cookie: "lax",
+	// "strict" or "none". It must be "none" for browser apps on other sites to
+	// use sessions.
+	CookieSameSite string `json:"cookieSameSite"`
+}
+
+type CORSConfig struct {
+	// AllowedOrigins are browser origins, such as https://app.example.com,
+	// that can call the API cross-origin, on top of those allowed by each
+	// client.
+	AllowedOrigins []string `json:"allowedOrigins"`
+}
+
+type TokensConfig struct {
+	// SigningKey is the HMAC secret that access tokens issued to clients are
+	// signed with. Clients can't request tokens when it's empty.
+	SigningKey string `json:"signingKey"`
+	// Issuer is the iss claim of issued tokens.
+	Issuer string `json:"issuer"`
+	// Lifespan is how long an issued token is valid for.
+	Lifespan duration `json:"lifespan"`
+}
+
+type WebhooksConfig struct {
+	// Timeout is how long a receiver has to respond to a delivery.
+	Timeout duration `json:"timeout"`
+	// MaxAttempts is how many times a delivery is attempted before it's
+	// marked as failed.
+	MaxAttempts int `json:"maxAttempts"`
+	// BaseDelay is how long to wait before retrying a failed delivery. The
+	// delay doubles after each attempt, up to MaxDelay.
+	BaseDelay duration `json:"baseDelay"`
+	MaxDelay  duration `json:"maxDelay"`
+}
+
+type MetricsConfig struct {
+	// Address is where Prometheus metrics are served, at /metrics. It is a
+	// separate listener from the API so that it needn't be exposed publicly.
+	// Metrics aren't served when it's empty.
+	Address string `json:"address"`
+}
+
+type TracingConfig struct {
+	// Exporter is where spans are sent: "otlp" for an OpenTelemetry collector,
+	// or "stdout" for local testing. Tracing is disabled when it's empty.
+	Exporter string `json:"exporter"`
+	// Endpoint is the host and port of the OTLP collector.
+	Endpoint string `json:"endpoint"`
+	// Insecure sends spans to the collector over plain HTTP.
+	Insecure bool `json:"insecure"`
+	// SampleRatio is the fraction of new traces that are recorded, from 0 to
+	// 1. Traces continued from a caller follow the caller's decision.
+	SampleRatio float64 `json:"sampleRatio"`
+}
+
+// duration is a time.Duration that is written in config files as a string
+// understood by time.ParseDuration, e.g. "1h30m".
+type duration time.Duration
+
+func (d *duration) UnmarshalJSON(b []byte) error {
+	var str string
+	if err := json.Unmarshal(b, &str); err != nil {
+		return err
+	}
+
+	parsed, err := time.ParseDuration(str)
+	if err != nil {
+		return err
+	}
+
+	*d = duration(parsed)
+
+	return nil
+}
+
+// settings are the session settings used by the auth service.
+func (c SessionsConfig) settings() auth.SessionSettings {
+	return auth.SessionSettings{
+		IdleTimeout:        time.Duration(c.IdleTimeout),
+		AbsoluteTimeout:    time.Duration(c.AbsoluteTimeout),
+		RememberMeLifetime: time.Duration(c.RememberMeLifetime),
+	}
+}
+
+// sameSite is the session cookie's SameSite attribute.
+func (c SessionsConfig) sameSite() (nethttp.SameSite, error) {
+	switch c.CookieSameSite {
+	case "lax":
+		return nethttp.SameSiteLaxMode, nil
+	case "strict":
+		return nethttp.SameSiteStrictMode, nil
+	case "none":
+		return nethttp.SameSiteNoneMode, nil
+	default:
+		return 0, fmt.Errorf("unknown sessions.cookieSameSite %q", c.CookieSameSite)
+	}
+}
+
+// listenConfig is the server section as used by the API and metrics listeners.
+func (c ServerConfig) listenConfig() http.ListenConfig {
+	return http.ListenConfig{
+		ReadHeaderTimeout: time.Duration(c.ReadHeaderTimeout),
+		ReadTimeout:       time.Duration(c.ReadTimeout),
+		WriteTimeout:      time.Duration(c.WriteTimeout),
+		IdleTimeout:       time.Duration(c.IdleTimeout),
+		MaxHeaderBytes:    c.MaxHeaderBytes,
+		ShutdownTimeout:   time.Duration(c.ShutdownTimeout),
+	}
+}
+
 func loadConfig() (Config, error) {
 	config := initFlags()
 
@@ -28,11 +239,11 @@ func loadConfig() (Config, error) {
 }
 
 func initFlags() Config {
-	var config Config
+	config := defaultConfig()
 
 	flag.IntVar(&config.port, "port", 8080, "Port to run on.")
 	flag.BoolVar(&config.runMigrations, "migrate", false, "Run db migrations. Ignored for mem driver.")
-	flag.StringVar(&config.logLevel, "log-level", "info", "Min log level: debug, info, warn, error, fatal")
+	flag.StringVar(&config.logLevel, "log-level", "", "Min log level: debug, info, warn, error. Overrides the config file.")
 	flag.StringVar(&config.configPath, "config", "./config.json", "Path to the config file.")
 	flag.BoolVar(
 		&config.setupMode,
@@ -46,6 +257,50 @@ func initFlags() Config {
 	return config
 }
 
+func defaultConfig() Config {
+	return Config{
+		Log: LogConfig{
+			Level:  "info",
+			Format: logging.FormatJSON,
+		},
+		Server: ServerConfig{
+			ReadHeaderTimeout: duration(5 * time.Second),
+			ReadTimeout:       duration(15 * time.Second),
+			WriteTimeout:      duration(30 * time.Second),
+			IdleTimeout:       duration(2 * time.Minute),
+			MaxHeaderBytes:    64 << 10,
+			ShutdownTimeout:   duration(20 * time.Second),
+		},
+		Jobs: JobsConfig{
+			SessionSweepInterval:    duration(time.Hour),
+			PurgeInterval:           duration(24 * time.Hour),
+			PurgeRetention:          duration(30 * 24 * time.Hour),
+			EventDispatchInterval:   duration(time.Second),
+			WebhookDispatchInterval: duration(10 * time.Second),
+		},
+		Sessions: SessionsConfig{
+			IdleTimeout:            duration(auth.DefaultSessionSettings.IdleTimeout),
+			AbsoluteTimeout:        duration(auth.DefaultSessionSettings.AbsoluteTimeout),
+			RememberMeLifetime:     duration(auth.DefaultSessionSettings.RememberMeLifetime),
+			ReauthenticationWindow: duration(10 * time.Minute),
+			CookieSameSite:         "lax",
+		},
+		Tokens: TokensConfig{
+			Issuer:   "heimdall",
+			Lifespan: duration(time.Hour),
+		},
+		Webhooks: WebhooksConfig{
+			Timeout:     duration(webhook.DefaultTimeout),
+			MaxAttempts: webhook.DefaultMaxAttempts,
+			BaseDelay:   duration(webhook.DefaultBaseDelay),
+			MaxDelay:    duration(webhook.DefaultMaxDelay),
+		},
+		Tracing: TracingConfig{
+			SampleRatio: 1,
+		},
+	}
+}
+
 func readConfig(config Config, path string) (Config, error) {
 	fileContents, err := os.ReadFile(path)
 	if err != nil {
@@ -57,5 +312,13 @@ func readConfig(config Config, path string) (Config, error) {
 		return Config{}, err
 	}
 
+	if config.logLevel != "" {
+		config.Log.Level = config.logLevel
+	}
+
+	if err = config.Sessions.settings().Validate(); err != nil {
+		return Config{}, fmt.Errorf("sessions: %w", err)
+	}
+
 	return config, nil
 }
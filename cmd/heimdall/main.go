@@ -1,21 +1,42 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/url"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gofrs/uuid/v5"
 	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	migratemysql "github.com/golang-migrate/migrate/v4/database/mysql"
+	migratepg "github.com/golang-migrate/migrate/v4/database/postgres"
 	"github.com/golang-migrate/migrate/v4/database/sqlite3"
+	"github.com/golang-migrate/migrate/v4/source"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
-	"github.com/mattmeyers/level"
+	"github.com/ninth-realm/heimdall/audit"
 	"github.com/ninth-realm/heimdall/auth"
 	"github.com/ninth-realm/heimdall/client"
+	"github.com/ninth-realm/heimdall/event"
+	"github.com/ninth-realm/heimdall/health"
 	"github.com/ninth-realm/heimdall/http"
+	"github.com/ninth-realm/heimdall/job"
+	"github.com/ninth-realm/heimdall/logging"
+	"github.com/ninth-realm/heimdall/metrics"
 	"github.com/ninth-realm/heimdall/store"
+	"github.com/ninth-realm/heimdall/store/mysql"
+	"github.com/ninth-realm/heimdall/store/postgres"
 	"github.com/ninth-realm/heimdall/store/sqlite"
+	"github.com/ninth-realm/heimdall/tracing"
 	"github.com/ninth-realm/heimdall/user"
+	"github.com/ninth-realm/heimdall/webhook"
 	_ "modernc.org/sqlite"
 )
 
@@ -32,27 +53,25 @@ func run() error {
 		return err
 	}
 
-	logLevel, err := level.ParseLevel(config.logLevel)
+	logger, err := logging.New(os.Stdout, config.Log.Format, config.Log.Level)
 	if err != nil {
 		return err
 	}
+	slog.SetDefault(logger)
 
-	logger, err := level.NewBasicLogger(logLevel, nil)
-	if err != nil {
-		return err
-	}
-
-	var db sqlite.DB
+	var db store.Repository
 	switch config.Driver {
 	case "mem":
 		db, err = getSqliteDB("file::memory:", true)
-		db.UUIDGenerator = uuidV4Fn(uuid.NewV4)
 	case "sqlite":
 		db, err = getSqliteDB(
 			fmt.Sprintf("file:%s?mode=rwc", config.SQLite.Path),
 			config.runMigrations,
 		)
-		db.UUIDGenerator = uuidV4Fn(uuid.NewV4)
+	case "postgres":
+		db, err = getPostgresDB(config.Postgres.DSN, config.runMigrations)
+	case "mysql":
+		db, err = getMySQLDB(config.MySQL.DSN, config.runMigrations)
 	default:
 		return errors.New("unknown driver")
 	}
@@ -61,20 +80,203 @@ func run() error {
 		return err
 	}
 
-	logger.Info("Using DB driver: %s", config.Driver)
+	logger.Info("Using DB driver", "driver", config.Driver)
+	defer closeDB(db, logger)
+
+	db = store.ObservedRepository{Repo: db, Observer: metrics.ObserveQuery}
+	db = store.ObservedRepository{Repo: db, Observer: tracing.ObserveQuery}
+	metrics.RegisterSessions(db)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// Once shutdown has started, a second signal exits immediately.
+	context.AfterFunc(ctx, stop)
+
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Options{
+		Exporter:    config.Tracing.Exporter,
+		Endpoint:    config.Tracing.Endpoint,
+		Insecure:    config.Tracing.Insecure,
+		SampleRatio: config.Tracing.SampleRatio,
+	})
+	if err != nil {
+		return err
+	}
+	defer shutdownTracing(context.Background())
+
+	// Anything done in setup mode can't be attributed to anyone, so the audit
+	// log at least shows when it was possible.
+	if config.setupMode {
+		err = event.Emit(db, event.Event{
+			Type:  event.SetupModeStarted,
+			Actor: &event.Actor{Type: event.ActorSystem},
+		}, store.QueryOptions{Ctx: ctx})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Jobs keep running while requests are drained, since those requests may
+	// still emit events, and are only stopped once the servers have returned.
+	scheduler := buildScheduler(config, db, logger)
+	scheduler.Start(context.Background())
+	defer scheduler.Stop()
+
+	srv, err := buildServer(config, db, logger)
+	if err != nil {
+		return err
+	}
+
+	errs := make(chan error, 2)
+	servers := 1
+	go func() {
+		errs <- srv.ListenAndServe(ctx, fmt.Sprintf(":%d", config.port))
+	}()
+
+	if config.Metrics.Address != "" {
+		servers++
+		go func() {
+			errs <- http.Serve(ctx, config.Metrics.Address, metrics.ServeMux(), config.Server.listenConfig(), logger)
+		}()
+	}
+
+	// If a server fails, the others are shut down too.
+	for i := 0; i < servers; i++ {
+		if serveErr := <-errs; serveErr != nil && err == nil {
+			err = serveErr
+			stop()
+		}
+	}
+
+	logger.Info("Shutting down")
+
+	return err
+}
+
+func buildScheduler(config Config, db store.Repository, logger *slog.Logger) *job.Scheduler {
+	events := &event.Dispatcher{Repo: db}
+	events.Subscribe("audit", audit.Subscriber(db))
+	events.Subscribe("webhooks", webhook.Subscriber(db))
+	events.Subscribe("metrics", metrics.Subscriber())
 
-	return buildServer(config, db, logger).ListenAndServe(fmt.Sprintf(":%d", config.port))
+	scheduler := &job.Scheduler{Logger: logger}
+	scheduler.Add(
+		"event-dispatcher",
+		time.Duration(config.Jobs.EventDispatchInterval),
+		events.Dispatch,
+	)
+	scheduler.Add(
+		"session-sweeper",
+		time.Duration(config.Jobs.SessionSweepInterval),
+		job.SessionSweeper(db, logger),
+	)
+	scheduler.Add(
+		"purger",
+		time.Duration(config.Jobs.PurgeInterval),
+		job.Purger(db, time.Duration(config.Jobs.PurgeRetention), events.Subscribers(), logger),
+	)
+	scheduler.Add(
+		"webhook-dispatcher",
+		time.Duration(config.Jobs.WebhookDispatchInterval),
+		webhook.Dispatcher{
+			Repo:        db,
+			Logger:      logger,
+			Timeout:     time.Duration(config.Webhooks.Timeout),
+			MaxAttempts: config.Webhooks.MaxAttempts,
+			BaseDelay:   time.Duration(config.Webhooks.BaseDelay),
+			MaxDelay:    time.Duration(config.Webhooks.MaxDelay),
+		}.Dispatch,
+	)
+
+	return scheduler
 }
 
-func buildServer(config Config, db store.Repository, logger level.Logger) *http.Server {
+func buildServer(config Config, db store.Repository, logger *slog.Logger) (*http.Server, error) {
 	srv := http.NewServer()
 	srv.Logger = logger
 	srv.DisableAuth = config.setupMode
+	srv.Listen = config.Server.listenConfig()
+	if tls := config.Server.TLS; tls != (TLSConfig{}) {
+		tlsConfig, err := http.NewTLSConfig(http.TLSConfig{
+			CertFile:     tls.CertFile,
+			KeyFile:      tls.KeyFile,
+			ClientCAFile: tls.ClientCAFile,
+		}, logger)
+		if err != nil {
+			return nil, err
+		}
+
+		srv.Listen.TLS = tlsConfig
+	}
+	if config.Server.PublicURL != "" {
+		u, err := url.Parse(config.Server.PublicURL)
+		if err != nil {
+			return nil, err
+		} else if u.Scheme == "" || u.Host == "" {
+			return nil, errors.New("server.publicURL must include a scheme and host")
+		}
+
+		srv.PublicURL = u
+	}
+	for _, o := range config.CORS.AllowedOrigins {
+		origin, err := http.ParseOrigin(o)
+		if err != nil {
+			return nil, fmt.Errorf("cors.allowedOrigins: %w", err)
+		}
+
+		srv.AllowedOrigins = append(srv.AllowedOrigins, origin)
+	}
+	sameSite, err := config.Sessions.sameSite()
+	if err != nil {
+		return nil, err
+	}
+	srv.SessionCookieSameSite = sameSite
+	srv.ReauthenticationWindow = time.Duration(config.Sessions.ReauthenticationWindow)
 	srv.UserService = user.Service{Repo: db}
 	srv.ClientService = client.Service{Repo: db}
-	srv.AuthService = auth.Service{Repo: db}
+	srv.AuditService = audit.Service{Repo: db}
+	srv.WebhookService = webhook.Service{Repo: db}
+	authService := auth.Service{
+		Repo:     db,
+		Sessions: config.Sessions.settings(),
+	}
+	checks := []health.Check{
+		health.DatabaseCheck(db),
+		migrationsCheck(config, db, logger),
+	}
+	if config.Tokens.SigningKey != "" {
+		authService.Tokens = auth.JWTSettings{
+			Issuer:     config.Tokens.Issuer,
+			Lifespan:   int(time.Duration(config.Tokens.Lifespan).Seconds()),
+			SigningKey: config.Tokens.SigningKey,
+			Algorithm:  auth.HMAC256Algorithm,
+		}
+		authService.ProofReplays = auth.NewReplayCache()
+		checks = append(checks, health.Check{Name: "signingKey", Run: authService.CheckSigningKey})
+	}
+
+	srv.AuthService = authService
+	srv.HealthService = health.Service{Logger: logger, Checks: checks}
+
+	return srv, nil
+}
 
-	return srv
+// migrationsCheck compares the database against the migrations shipped
+// alongside the binary. Without them the server can still run, but it can't
+// tell whether the database is up to date, so it isn't reported as ready.
+func migrationsCheck(config Config, db store.Repository, logger *slog.Logger) health.Check {
+	latest, err := latestMigration(migrationsName(config.Driver))
+	if err != nil {
+		logger.Warn("Unable to read migrations for readiness checks", "error", err)
+
+		return health.Check{
+			Name: "migrations",
+			Run: func(ctx context.Context) error {
+				return health.Error("migrations not found")
+			},
+		}
+	}
+
+	return health.MigrationsCheck(db, latest)
 }
 
 func getSqliteDB(dsn string, runMigrations bool) (sqlite.DB, error) {
@@ -83,28 +285,138 @@ func getSqliteDB(dsn string, runMigrations bool) (sqlite.DB, error) {
 		return sqlite.DB{}, err
 	}
 
+	db.UUIDGenerator = uuidV4Fn(uuid.NewV4)
+
 	if runMigrations {
 		driver, err := sqlite3.WithInstance(db.Conn.DB, &sqlite3.Config{})
 		if err != nil {
 			return sqlite.DB{}, err
 		}
 
-		m, err := migrate.NewWithDatabaseInstance(
-			"file://./db/migrations/sqlite",
-			"sqlite", driver)
-		if err != nil {
+		if err = migrateUp(driver, "sqlite"); err != nil {
 			return sqlite.DB{}, err
 		}
+	}
 
-		err = m.Up()
-		if err != migrate.ErrNoChange && err != nil {
-			return sqlite.DB{}, err
+	return db, nil
+}
+
+func getPostgresDB(dsn string, runMigrations bool) (postgres.DB, error) {
+	db, err := postgres.NewDB(dsn)
+	if err != nil {
+		return postgres.DB{}, err
+	}
+
+	db.UUIDGenerator = uuidV4Fn(uuid.NewV4)
+
+	if runMigrations {
+		driver, err := migratepg.WithInstance(db.Conn.DB, &migratepg.Config{})
+		if err != nil {
+			return postgres.DB{}, err
+		}
+
+		if err = migrateUp(driver, "postgres"); err != nil {
+			return postgres.DB{}, err
 		}
 	}
 
 	return db, nil
 }
 
+func getMySQLDB(dsn string, runMigrations bool) (mysql.DB, error) {
+	db, err := mysql.NewDB(dsn)
+	if err != nil {
+		return mysql.DB{}, err
+	}
+
+	db.UUIDGenerator = uuidV4Fn(uuid.NewV4)
+
+	if runMigrations {
+		// Migrations get their own connection, since only they need to run
+		// multiple statements at once.
+		conn, err := mysql.OpenMigrationDB(dsn)
+		if err != nil {
+			return mysql.DB{}, err
+		}
+
+		driver, err := migratemysql.WithInstance(conn, &migratemysql.Config{})
+		if err != nil {
+			conn.Close()
+			return mysql.DB{}, err
+		}
+		defer driver.Close()
+
+		if err = migrateUp(driver, "mysql"); err != nil {
+			return mysql.DB{}, err
+		}
+	}
+
+	return db, nil
+}
+
+// migrateUp applies all migrations found in the db/migrations directory for the
+// named driver.
+func migrateUp(driver database.Driver, name string) error {
+	m, err := migrate.NewWithDatabaseInstance(
+		fmt.Sprintf("file://./db/migrations/%s", name),
+		name, driver)
+	if err != nil {
+		return err
+	}
+
+	err = m.Up()
+	if err != migrate.ErrNoChange && err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// closeDB closes the database's connections, if its driver holds any.
+func closeDB(db store.Repository, logger *slog.Logger) {
+	closer, ok := db.(io.Closer)
+	if !ok {
+		return
+	}
+
+	if err := closer.Close(); err != nil {
+		logger.Error("Closing database", "error", err)
+	}
+}
+
+// migrationsName is the db/migrations directory used by the driver.
+func migrationsName(driver string) string {
+	if driver == "mem" {
+		return "sqlite"
+	}
+
+	return driver
+}
+
+// latestMigration finds the version of the newest migration in the
+// db/migrations directory for the named driver.
+func latestMigration(name string) (uint, error) {
+	src, err := source.Open(fmt.Sprintf("file://./db/migrations/%s", name))
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+
+	version, err := src.First()
+	for err == nil {
+		var next uint
+		next, err = src.Next(version)
+		if err == nil {
+			version = next
+		}
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return 0, err
+	}
+
+	return version, nil
+}
+
 type uuidV4Fn func() (uuid.UUID, error)
 
 func (f uuidV4Fn) GenerateUUID() uuid.UUID {
@@ -7,7 +7,9 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/ninth-realm/heimdall/metrics"
 	"golang.org/x/crypto/argon2"
 )
 
@@ -84,6 +86,9 @@ func GetPasswordHash(password string, p ArgonParams) (string, error) {
 }
 
 func hashPassword(password string, salt []byte, p ArgonParams) ([]byte, error) {
+	start := time.Now()
+	defer func() { metrics.ObserveArgon2(time.Since(start)) }()
+
 	return argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, p.KeyLen), nil
 }
 
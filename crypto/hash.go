@@ -0,0 +1,14 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// HashToken produces a deterministic hash of a high entropy token so that it
+// can be stored and looked up without keeping the token itself. This must not
+// be used for passwords, which should be hashed with GetPasswordHash instead.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
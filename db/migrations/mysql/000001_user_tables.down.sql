@@ -0,0 +1,3 @@
+DROP TABLE `email`;
+DROP TABLE `password`;
+DROP TABLE `user`;
@@ -0,0 +1,27 @@
+CREATE TABLE `user` (
+    `id` CHAR(36) PRIMARY KEY NOT NULL,
+    `first_name` VARCHAR(255) NOT NULL,
+    `last_name` VARCHAR(255) NOT NULL,
+    `created_at` DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    `updated_at` DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6) ON UPDATE CURRENT_TIMESTAMP(6)
+);
+
+CREATE TABLE `email` (
+    `id` CHAR(36) PRIMARY KEY NOT NULL,
+    `user_id` CHAR(36) NOT NULL,
+    `email` VARCHAR(255) NOT NULL UNIQUE,
+    `created_at` DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    `updated_at` DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6) ON UPDATE CURRENT_TIMESTAMP(6),
+    FOREIGN KEY (`user_id`) REFERENCES `user` (`id`)
+        ON DELETE CASCADE
+);
+
+CREATE TABLE `password` (
+    `id` CHAR(36) PRIMARY KEY NOT NULL,
+    `user_id` CHAR(36) NOT NULL UNIQUE,
+    `hash` VARCHAR(255) NOT NULL,
+    `created_at` DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    `updated_at` DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6) ON UPDATE CURRENT_TIMESTAMP(6),
+    FOREIGN KEY (`user_id`) REFERENCES `user` (`id`)
+        ON DELETE CASCADE
+);
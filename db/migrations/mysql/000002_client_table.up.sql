@@ -0,0 +1,7 @@
+CREATE TABLE `client` (
+    `id` CHAR(36) PRIMARY KEY NOT NULL,
+    `name` VARCHAR(255) NOT NULL UNIQUE,
+    `enabled` BOOLEAN NOT NULL DEFAULT TRUE,
+    `created_at` DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    `updated_at` DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6) ON UPDATE CURRENT_TIMESTAMP(6)
+);
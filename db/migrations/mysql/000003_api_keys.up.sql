@@ -0,0 +1,11 @@
+CREATE TABLE `api_key` (
+    `id` CHAR(36) PRIMARY KEY NOT NULL,
+    `client_id` CHAR(36) NOT NULL,
+    `description` TEXT NULL,
+    `prefix` VARCHAR(16) NOT NULL UNIQUE,
+    `hash` VARCHAR(255) NOT NULL,
+    `created_at` DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    `updated_at` DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6) ON UPDATE CURRENT_TIMESTAMP(6),
+    FOREIGN KEY (`client_id`) REFERENCES `client` (`id`)
+        ON DELETE CASCADE
+);
@@ -0,0 +1,15 @@
+CREATE TABLE `session` (
+    `id` CHAR(36) PRIMARY KEY NOT NULL,
+    `token_hash` CHAR(64) NOT NULL UNIQUE,
+    `user_id` CHAR(36) NOT NULL,
+    `user_agent` TEXT NULL,
+    `ip_address` VARCHAR(45) NULL,
+    `created_at` DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    `last_seen_at` DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    `expires_at` DATETIME(6) NOT NULL,
+    `remember_me` BOOLEAN NOT NULL DEFAULT FALSE,
+    `authenticated_at` DATETIME(6) NOT NULL,
+    INDEX `session_user_id` (`user_id`),
+    FOREIGN KEY (`user_id`) REFERENCES `user` (`id`)
+        ON DELETE CASCADE
+);
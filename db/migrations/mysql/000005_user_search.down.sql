@@ -0,0 +1,4 @@
+DROP TRIGGER `user_search_user_update`;
+DROP TRIGGER `user_search_email_update`;
+DROP TRIGGER `user_search_email_insert`;
+DROP TABLE `user_search`;
@@ -0,0 +1,47 @@
+-- user_search indexes every email address along with the name of the user it
+-- belongs to, so users can be found by a partial name or email. It's kept in
+-- sync with the user and email tables by the triggers below, and rows are
+-- removed along with the email or user by the foreign keys.
+CREATE TABLE `user_search` (
+    `email_id` CHAR(36) PRIMARY KEY NOT NULL,
+    `user_id` CHAR(36) NOT NULL,
+    `document` TEXT NOT NULL,
+    INDEX `user_search_user_id` (`user_id`),
+    FULLTEXT INDEX `user_search_document` (`document`),
+    FOREIGN KEY (`email_id`) REFERENCES `email` (`id`)
+        ON DELETE CASCADE,
+    FOREIGN KEY (`user_id`) REFERENCES `user` (`id`)
+        ON DELETE CASCADE
+);
+
+INSERT INTO `user_search` (`email_id`, `user_id`, `document`)
+SELECT e.`id`, u.`id`, CONCAT_WS(' ', u.`first_name`, u.`last_name`, e.`email`)
+FROM `email` e
+INNER JOIN `user` u ON u.`id` = e.`user_id`;
+
+CREATE TRIGGER `user_search_email_insert`
+    AFTER INSERT
+    ON `email`
+    FOR EACH ROW
+    INSERT INTO `user_search` (`email_id`, `user_id`, `document`)
+    SELECT NEW.`id`, u.`id`, CONCAT_WS(' ', u.`first_name`, u.`last_name`, NEW.`email`)
+    FROM `user` u
+    WHERE u.`id` = NEW.`user_id`;
+
+CREATE TRIGGER `user_search_email_update`
+    AFTER UPDATE
+    ON `email`
+    FOR EACH ROW
+    UPDATE `user_search` s
+    INNER JOIN `user` u ON u.`id` = NEW.`user_id`
+    SET s.`user_id` = u.`id`, s.`document` = CONCAT_WS(' ', u.`first_name`, u.`last_name`, NEW.`email`)
+    WHERE s.`email_id` = NEW.`id`;
+
+CREATE TRIGGER `user_search_user_update`
+    AFTER UPDATE
+    ON `user`
+    FOR EACH ROW
+    UPDATE `user_search` s
+    INNER JOIN `email` e ON e.`id` = s.`email_id`
+    SET s.`document` = CONCAT_WS(' ', NEW.`first_name`, NEW.`last_name`, e.`email`)
+    WHERE s.`user_id` = NEW.`id`;
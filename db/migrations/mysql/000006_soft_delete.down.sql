@@ -0,0 +1,12 @@
+-- Soft deleted rows can't be represented without the column, so they're
+-- removed for good.
+DELETE FROM `user` WHERE `deleted_at` IS NOT NULL;
+DELETE FROM `client` WHERE `deleted_at` IS NOT NULL;
+
+ALTER TABLE `user`
+    DROP INDEX `user_deleted_at`,
+    DROP COLUMN `deleted_at`;
+
+ALTER TABLE `client`
+    DROP INDEX `client_deleted_at`,
+    DROP COLUMN `deleted_at`;
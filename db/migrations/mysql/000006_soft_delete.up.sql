@@ -0,0 +1,7 @@
+ALTER TABLE `user`
+    ADD COLUMN `deleted_at` DATETIME(6) NULL,
+    ADD INDEX `user_deleted_at` (`deleted_at`);
+
+ALTER TABLE `client`
+    ADD COLUMN `deleted_at` DATETIME(6) NULL,
+    ADD INDEX `client_deleted_at` (`deleted_at`);
@@ -0,0 +1,4 @@
+DROP TABLE `audit_chain`;
+DROP TRIGGER `audit_event_no_delete`;
+DROP TRIGGER `audit_event_no_update`;
+DROP TABLE `audit_event`;
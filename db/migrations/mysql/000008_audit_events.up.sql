@@ -0,0 +1,45 @@
+-- audit_event is an append-only log. Each event includes the hash of the one
+-- before it, so that editing or removing events can be detected.
+CREATE TABLE `audit_event` (
+    `id` CHAR(36) PRIMARY KEY NOT NULL,
+    `sequence` BIGINT NOT NULL UNIQUE,
+    `type` VARCHAR(255) NOT NULL,
+    `occurred_at` DATETIME(6) NOT NULL,
+    `actor_type` VARCHAR(255) NOT NULL,
+    `actor_id` VARCHAR(255) NULL,
+    `ip_address` VARCHAR(255) NULL,
+    `request_id` VARCHAR(255) NULL,
+    `target_type` VARCHAR(255) NULL,
+    `target_id` VARCHAR(255) NULL,
+    `prev_hash` CHAR(64) NOT NULL,
+    `hash` CHAR(64) NOT NULL
+);
+
+CREATE INDEX `audit_event_type` ON `audit_event` (`type`);
+CREATE INDEX `audit_event_actor_id` ON `audit_event` (`actor_id`);
+CREATE INDEX `audit_event_target` ON `audit_event` (`target_type`, `target_id`);
+CREATE INDEX `audit_event_occurred_at` ON `audit_event` (`occurred_at`);
+
+CREATE TRIGGER `audit_event_no_update`
+    BEFORE UPDATE
+    ON `audit_event`
+    FOR EACH ROW
+    SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit events cannot be changed';
+
+CREATE TRIGGER `audit_event_no_delete`
+    BEFORE DELETE
+    ON `audit_event`
+    FOR EACH ROW
+    SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit events cannot be deleted';
+
+-- audit_chain holds the sequence and hash of the last event. Appending an event
+-- updates it first, which locks it until the transaction ends so that events
+-- are appended one at a time.
+CREATE TABLE `audit_chain` (
+    `id` INT PRIMARY KEY NOT NULL CHECK (`id` = 1),
+    `sequence` BIGINT NOT NULL,
+    `hash` CHAR(64) NOT NULL
+);
+
+INSERT INTO `audit_chain` (`id`, `sequence`, `hash`)
+VALUES (1, 0, '0000000000000000000000000000000000000000000000000000000000000000');
@@ -0,0 +1,44 @@
+CREATE TABLE `webhook` (
+    `id` CHAR(36) PRIMARY KEY NOT NULL,
+    `url` TEXT NOT NULL,
+    `event_types` TEXT NOT NULL,
+    `secret` VARCHAR(255) NOT NULL,
+    `enabled` BOOLEAN NOT NULL DEFAULT TRUE,
+    `created_at` DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    `updated_at` DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6) ON UPDATE CURRENT_TIMESTAMP(6),
+    `version` BIGINT NOT NULL DEFAULT 1
+);
+
+-- webhook_delivery is an outbox of events to send to webhooks. Deliveries are
+-- written in the same transaction as the change that caused the event, and sent
+-- by a background job.
+CREATE TABLE `webhook_delivery` (
+    `id` CHAR(36) PRIMARY KEY NOT NULL,
+    `webhook_id` CHAR(36) NOT NULL,
+    `event_id` CHAR(36) NOT NULL,
+    `event_type` VARCHAR(255) NOT NULL,
+    `payload` TEXT NOT NULL,
+    `status` VARCHAR(16) NOT NULL DEFAULT 'pending',
+    `attempts` INT NOT NULL DEFAULT 0,
+    `next_attempt_at` DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    `last_attempt_at` DATETIME(6) NULL,
+    `created_at` DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    FOREIGN KEY (`webhook_id`) REFERENCES `webhook` (`id`)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX `webhook_delivery_webhook_id` ON `webhook_delivery` (`webhook_id`, `created_at`);
+CREATE INDEX `webhook_delivery_due` ON `webhook_delivery` (`status`, `next_attempt_at`);
+
+CREATE TABLE `webhook_delivery_attempt` (
+    `id` CHAR(36) PRIMARY KEY NOT NULL,
+    `delivery_id` CHAR(36) NOT NULL,
+    `number` INT NOT NULL,
+    `attempted_at` DATETIME(6) NOT NULL,
+    `status_code` INT NULL,
+    `error` TEXT NULL,
+    FOREIGN KEY (`delivery_id`) REFERENCES `webhook_delivery` (`id`)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX `webhook_delivery_attempt_delivery_id` ON `webhook_delivery_attempt` (`delivery_id`);
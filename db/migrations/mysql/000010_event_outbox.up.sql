@@ -0,0 +1,32 @@
+-- event_outbox holds domain events, written in the same transaction as the
+-- change that caused them, until every subscriber has handled them.
+CREATE TABLE `event_outbox` (
+    `id` CHAR(36) PRIMARY KEY NOT NULL,
+    `sequence` BIGINT NOT NULL UNIQUE,
+    `type` VARCHAR(255) NOT NULL,
+    `occurred_at` DATETIME(6) NOT NULL,
+    `actor_type` VARCHAR(255) NOT NULL,
+    `actor_id` VARCHAR(255) NULL,
+    `ip_address` VARCHAR(255) NULL,
+    `request_id` VARCHAR(255) NULL,
+    `target_type` VARCHAR(255) NULL,
+    `target_id` VARCHAR(255) NULL,
+    `data` TEXT NOT NULL
+);
+
+-- event_outbox_head holds the sequence of the last event. Appending an event
+-- updates it first, which locks it until the transaction ends so that events
+-- are committed in sequence order.
+CREATE TABLE `event_outbox_head` (
+    `id` INT PRIMARY KEY NOT NULL CHECK (`id` = 1),
+    `sequence` BIGINT NOT NULL
+);
+
+INSERT INTO `event_outbox_head` (`id`, `sequence`) VALUES (1, 0);
+
+-- event_subscriber tracks the sequence of the last event each subscriber has
+-- handled.
+CREATE TABLE `event_subscriber` (
+    `name` VARCHAR(255) PRIMARY KEY NOT NULL,
+    `position` BIGINT NOT NULL
+);
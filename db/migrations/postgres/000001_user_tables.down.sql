@@ -0,0 +1,4 @@
+DROP TABLE email;
+DROP TABLE password;
+DROP TABLE "user";
+DROP FUNCTION set_updated_at;
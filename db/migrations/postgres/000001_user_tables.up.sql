@@ -0,0 +1,55 @@
+CREATE FUNCTION set_updated_at()
+    RETURNS TRIGGER
+    LANGUAGE plpgsql
+AS $$
+BEGIN
+    NEW.updated_at = CURRENT_TIMESTAMP;
+    RETURN NEW;
+END;
+$$;
+
+CREATE TABLE "user" (
+    id UUID PRIMARY KEY NOT NULL,
+    first_name TEXT NOT NULL,
+    last_name TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER update_user_timestamp
+    BEFORE UPDATE
+    ON "user"
+    FOR EACH ROW
+    EXECUTE FUNCTION set_updated_at();
+
+CREATE TABLE email (
+    id UUID PRIMARY KEY NOT NULL,
+    user_id UUID NOT NULL,
+    email TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES "user" (id)
+        ON DELETE CASCADE
+);
+
+CREATE TRIGGER update_email_timestamp
+    BEFORE UPDATE
+    ON email
+    FOR EACH ROW
+    EXECUTE FUNCTION set_updated_at();
+
+CREATE TABLE password (
+    id UUID PRIMARY KEY NOT NULL,
+    user_id UUID NOT NULL UNIQUE,
+    hash TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES "user" (id)
+        ON DELETE CASCADE
+);
+
+CREATE TRIGGER update_password_timestamp
+    BEFORE UPDATE
+    ON password
+    FOR EACH ROW
+    EXECUTE FUNCTION set_updated_at();
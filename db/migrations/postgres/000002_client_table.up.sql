@@ -0,0 +1,13 @@
+CREATE TABLE client (
+    id UUID PRIMARY KEY NOT NULL,
+    name TEXT NOT NULL UNIQUE,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER update_client_timestamp
+    BEFORE UPDATE
+    ON client
+    FOR EACH ROW
+    EXECUTE FUNCTION set_updated_at();
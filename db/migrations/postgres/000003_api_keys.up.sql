@@ -0,0 +1,17 @@
+CREATE TABLE api_key (
+    id UUID PRIMARY KEY NOT NULL,
+    client_id UUID NOT NULL,
+    description TEXT NULL,
+    prefix TEXT NOT NULL UNIQUE,
+    hash TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (client_id) REFERENCES client (id)
+        ON DELETE CASCADE
+);
+
+CREATE TRIGGER update_api_key_timestamp
+    BEFORE UPDATE
+    ON api_key
+    FOR EACH ROW
+    EXECUTE FUNCTION set_updated_at();
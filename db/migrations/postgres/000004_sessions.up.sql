@@ -0,0 +1,16 @@
+CREATE TABLE session (
+    id UUID PRIMARY KEY NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    user_id UUID NOT NULL,
+    user_agent TEXT NULL,
+    ip_address TEXT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMPTZ NOT NULL,
+    remember_me BOOLEAN NOT NULL DEFAULT FALSE,
+    authenticated_at TIMESTAMPTZ NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES "user" (id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX session_user_id ON session (user_id);
@@ -0,0 +1,6 @@
+DROP TRIGGER user_search_user ON "user";
+DROP FUNCTION index_user_search();
+DROP TRIGGER user_search_email ON email;
+DROP FUNCTION index_email_search();
+DROP TABLE user_search;
+DROP FUNCTION user_search_document(TEXT, TEXT, TEXT);
@@ -0,0 +1,70 @@
+-- user_search indexes every email address along with the name of the user it
+-- belongs to, so users can be found by a partial name or email. It's kept in
+-- sync with the user and email tables by the triggers below.
+CREATE FUNCTION user_search_document(first_name TEXT, last_name TEXT, email TEXT)
+    RETURNS TSVECTOR
+    LANGUAGE sql
+    IMMUTABLE
+AS $$
+    -- The punctuation in email addresses is replaced with spaces so that each
+    -- part of the address is indexed as a separate word.
+    SELECT to_tsvector('simple', first_name || ' ' || last_name || ' ' || translate(email, '@.+-_', '     '));
+$$;
+
+CREATE TABLE user_search (
+    email_id UUID PRIMARY KEY NOT NULL,
+    user_id UUID NOT NULL,
+    document TSVECTOR NOT NULL,
+    FOREIGN KEY (email_id) REFERENCES email (id)
+        ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES "user" (id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX user_search_document ON user_search USING GIN (document);
+CREATE INDEX user_search_user_id ON user_search (user_id);
+
+INSERT INTO user_search (email_id, user_id, document)
+SELECT e.id, u.id, user_search_document(u.first_name, u.last_name, e.email)
+FROM email e
+INNER JOIN "user" u ON u.id = e.user_id;
+
+CREATE FUNCTION index_email_search()
+    RETURNS TRIGGER
+    LANGUAGE plpgsql
+AS $$
+BEGIN
+    INSERT INTO user_search (email_id, user_id, document)
+    SELECT NEW.id, u.id, user_search_document(u.first_name, u.last_name, NEW.email)
+    FROM "user" u
+    WHERE u.id = NEW.user_id
+    ON CONFLICT (email_id) DO UPDATE
+        SET user_id = excluded.user_id, document = excluded.document;
+    RETURN NULL;
+END;
+$$;
+
+CREATE TRIGGER user_search_email
+    AFTER INSERT OR UPDATE OF email, user_id
+    ON email
+    FOR EACH ROW
+    EXECUTE FUNCTION index_email_search();
+
+CREATE FUNCTION index_user_search()
+    RETURNS TRIGGER
+    LANGUAGE plpgsql
+AS $$
+BEGIN
+    UPDATE user_search s
+    SET document = user_search_document(NEW.first_name, NEW.last_name, e.email)
+    FROM email e
+    WHERE e.id = s.email_id AND s.user_id = NEW.id;
+    RETURN NULL;
+END;
+$$;
+
+CREATE TRIGGER user_search_user
+    AFTER UPDATE OF first_name, last_name
+    ON "user"
+    FOR EACH ROW
+    EXECUTE FUNCTION index_user_search();
@@ -0,0 +1,2 @@
+ALTER TABLE "user" DROP COLUMN version;
+ALTER TABLE client DROP COLUMN version;
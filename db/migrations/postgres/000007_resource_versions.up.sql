@@ -0,0 +1,4 @@
+-- version is incremented by every update, so that writes based on a stale read
+-- can be detected.
+ALTER TABLE "user" ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE client ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
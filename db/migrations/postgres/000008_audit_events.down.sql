@@ -0,0 +1,4 @@
+DROP TABLE audit_chain;
+DROP TRIGGER audit_event_append_only ON audit_event;
+DROP FUNCTION reject_audit_event_change();
+DROP TABLE audit_event;
@@ -0,0 +1,50 @@
+CREATE TABLE webhook (
+    id UUID PRIMARY KEY NOT NULL,
+    url TEXT NOT NULL,
+    event_types TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    version BIGINT NOT NULL DEFAULT 1
+);
+
+CREATE TRIGGER update_webhook_timestamp
+    BEFORE UPDATE
+    ON webhook
+    FOR EACH ROW
+    EXECUTE FUNCTION set_updated_at();
+
+-- webhook_delivery is an outbox of events to send to webhooks. Deliveries are
+-- written in the same transaction as the change that caused the event, and sent
+-- by a background job.
+CREATE TABLE webhook_delivery (
+    id UUID PRIMARY KEY NOT NULL,
+    webhook_id UUID NOT NULL,
+    event_id UUID NOT NULL,
+    event_type TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_attempt_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (webhook_id) REFERENCES webhook (id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX webhook_delivery_webhook_id ON webhook_delivery (webhook_id, created_at);
+CREATE INDEX webhook_delivery_due ON webhook_delivery (status, next_attempt_at);
+
+CREATE TABLE webhook_delivery_attempt (
+    id UUID PRIMARY KEY NOT NULL,
+    delivery_id UUID NOT NULL,
+    number INTEGER NOT NULL,
+    attempted_at TIMESTAMPTZ NOT NULL,
+    status_code INTEGER NULL,
+    error TEXT NULL,
+    FOREIGN KEY (delivery_id) REFERENCES webhook_delivery (id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX webhook_delivery_attempt_delivery_id ON webhook_delivery_attempt (delivery_id);
@@ -0,0 +1,32 @@
+-- event_outbox holds domain events, written in the same transaction as the
+-- change that caused them, until every subscriber has handled them.
+CREATE TABLE event_outbox (
+    id UUID PRIMARY KEY NOT NULL,
+    sequence BIGINT NOT NULL UNIQUE,
+    type TEXT NOT NULL,
+    occurred_at TIMESTAMPTZ NOT NULL,
+    actor_type TEXT NOT NULL,
+    actor_id TEXT NULL,
+    ip_address TEXT NULL,
+    request_id TEXT NULL,
+    target_type TEXT NULL,
+    target_id TEXT NULL,
+    data TEXT NOT NULL
+);
+
+-- event_outbox_head holds the sequence of the last event. Appending an event
+-- updates it first, which locks it until the transaction ends so that events
+-- are committed in sequence order.
+CREATE TABLE event_outbox_head (
+    id INTEGER PRIMARY KEY NOT NULL CHECK (id = 1),
+    sequence BIGINT NOT NULL
+);
+
+INSERT INTO event_outbox_head (id, sequence) VALUES (1, 0);
+
+-- event_subscriber tracks the sequence of the last event each subscriber has
+-- handled.
+CREATE TABLE event_subscriber (
+    name TEXT PRIMARY KEY NOT NULL,
+    position BIGINT NOT NULL
+);
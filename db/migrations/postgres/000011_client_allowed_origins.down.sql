@@ -0,0 +1 @@
+ALTER TABLE client DROP COLUMN allowed_origins;
@@ -0,0 +1,3 @@
+-- allowed_origins is a comma separated list of the browser origins that can
+-- call the API cross-origin on the client's behalf.
+ALTER TABLE client ADD COLUMN allowed_origins TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,19 @@
+-- Session tokens were previously stored in plain text. There is no way to hash
+-- them in place, so all existing sessions are dropped and users must log in
+-- again.
+DROP TABLE `session`;
+
+CREATE TABLE `session` (
+    `id` TEXT PRIMARY KEY NOT NULL,
+    `token_hash` TEXT NOT NULL UNIQUE,
+    `user_id` TEXT NOT NULL,
+    `user_agent` TEXT NULL,
+    `ip_address` TEXT NULL,
+    `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `last_seen_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `expires_at` DATETIME NOT NULL,
+    FOREIGN KEY (`user_id`) REFERENCES `user` (`id`)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX `session_user_id` ON `session` (`user_id`);
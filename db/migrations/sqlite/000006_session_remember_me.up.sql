@@ -0,0 +1,4 @@
+ALTER TABLE `session` ADD COLUMN `remember_me` BOOLEAN NOT NULL DEFAULT 0;
+ALTER TABLE `session` ADD COLUMN `authenticated_at` DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00';
+
+UPDATE `session` SET `authenticated_at` = `created_at`;
@@ -0,0 +1,5 @@
+DROP TRIGGER [user_search_user_update];
+DROP TRIGGER [user_search_email_delete];
+DROP TRIGGER [user_search_email_update];
+DROP TRIGGER [user_search_email_insert];
+DROP TABLE `user_search`;
@@ -0,0 +1,53 @@
+-- user_search indexes every email address along with the name of the user it
+-- belongs to, so users can be found by a partial name or email. It's kept in
+-- sync with the user and email tables by the triggers below.
+CREATE VIRTUAL TABLE `user_search` USING fts5(
+    `email_id` UNINDEXED,
+    `user_id` UNINDEXED,
+    `first_name`,
+    `last_name`,
+    `email`,
+    tokenize = 'unicode61'
+);
+
+INSERT INTO `user_search` (`email_id`, `user_id`, `first_name`, `last_name`, `email`)
+SELECT e.`id`, u.`id`, u.`first_name`, u.`last_name`, e.`email`
+FROM `email` e
+INNER JOIN `user` u ON u.`id` = e.`user_id`;
+
+CREATE TRIGGER [user_search_email_insert]
+    AFTER INSERT
+    ON `email`
+    FOR EACH ROW
+BEGIN
+    INSERT INTO `user_search` (`email_id`, `user_id`, `first_name`, `last_name`, `email`)
+    SELECT new.`id`, u.`id`, u.`first_name`, u.`last_name`, new.`email`
+    FROM `user` u
+    WHERE u.`id` = new.`user_id`;
+END;
+
+CREATE TRIGGER [user_search_email_update]
+    AFTER UPDATE OF `email`
+    ON `email`
+    FOR EACH ROW
+BEGIN
+    UPDATE `user_search` SET `email` = new.`email` WHERE `email_id` = old.`id`;
+END;
+
+CREATE TRIGGER [user_search_email_delete]
+    AFTER DELETE
+    ON `email`
+    FOR EACH ROW
+BEGIN
+    DELETE FROM `user_search` WHERE `email_id` = old.`id`;
+END;
+
+CREATE TRIGGER [user_search_user_update]
+    AFTER UPDATE OF `first_name`, `last_name`
+    ON `user`
+    FOR EACH ROW
+BEGIN
+    UPDATE `user_search`
+    SET `first_name` = new.`first_name`, `last_name` = new.`last_name`
+    WHERE `user_id` = old.`id`;
+END;
@@ -0,0 +1,10 @@
+-- Soft deleted rows can't be represented without the column, so they're
+-- removed for good.
+DELETE FROM `user` WHERE `deleted_at` IS NOT NULL;
+DELETE FROM `client` WHERE `deleted_at` IS NOT NULL;
+
+DROP INDEX `user_deleted_at`;
+DROP INDEX `client_deleted_at`;
+
+ALTER TABLE `user` DROP COLUMN `deleted_at`;
+ALTER TABLE `client` DROP COLUMN `deleted_at`;
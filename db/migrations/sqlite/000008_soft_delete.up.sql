@@ -0,0 +1,5 @@
+ALTER TABLE `user` ADD COLUMN `deleted_at` DATETIME NULL;
+ALTER TABLE `client` ADD COLUMN `deleted_at` DATETIME NULL;
+
+CREATE INDEX `user_deleted_at` ON `user` (`deleted_at`);
+CREATE INDEX `client_deleted_at` ON `client` (`deleted_at`);
@@ -0,0 +1,47 @@
+-- audit_event is an append-only log. Each event includes the hash of the one
+-- before it, so that editing or removing events can be detected.
+CREATE TABLE `audit_event` (
+    `id` TEXT PRIMARY KEY NOT NULL,
+    `sequence` INTEGER NOT NULL UNIQUE,
+    `type` TEXT NOT NULL,
+    `occurred_at` DATETIME NOT NULL,
+    `actor_type` TEXT NOT NULL,
+    `actor_id` TEXT NULL,
+    `ip_address` TEXT NULL,
+    `request_id` TEXT NULL,
+    `target_type` TEXT NULL,
+    `target_id` TEXT NULL,
+    `prev_hash` TEXT NOT NULL,
+    `hash` TEXT NOT NULL
+);
+
+CREATE INDEX `audit_event_type` ON `audit_event` (`type`);
+CREATE INDEX `audit_event_actor_id` ON `audit_event` (`actor_id`);
+CREATE INDEX `audit_event_target` ON `audit_event` (`target_type`, `target_id`);
+CREATE INDEX `audit_event_occurred_at` ON `audit_event` (`occurred_at`);
+
+CREATE TRIGGER [audit_event_no_update]
+    BEFORE UPDATE
+    ON `audit_event`
+BEGIN
+    SELECT RAISE(ABORT, 'audit events cannot be changed');
+END;
+
+CREATE TRIGGER [audit_event_no_delete]
+    BEFORE DELETE
+    ON `audit_event`
+BEGIN
+    SELECT RAISE(ABORT, 'audit events cannot be deleted');
+END;
+
+-- audit_chain holds the sequence and hash of the last event. Appending an event
+-- updates it first, which locks it until the transaction ends so that events
+-- are appended one at a time.
+CREATE TABLE `audit_chain` (
+    `id` INTEGER PRIMARY KEY NOT NULL CHECK (`id` = 1),
+    `sequence` INTEGER NOT NULL,
+    `hash` TEXT NOT NULL
+);
+
+INSERT INTO `audit_chain` (`id`, `sequence`, `hash`)
+VALUES (1, 0, '0000000000000000000000000000000000000000000000000000000000000000');
@@ -0,0 +1,4 @@
+DROP TABLE `webhook_delivery_attempt`;
+DROP TABLE `webhook_delivery`;
+DROP TRIGGER [update_webhook_timestamp];
+DROP TABLE `webhook`;
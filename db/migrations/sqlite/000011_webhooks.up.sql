@@ -0,0 +1,52 @@
+CREATE TABLE `webhook` (
+    `id` TEXT PRIMARY KEY NOT NULL,
+    `url` TEXT NOT NULL,
+    `event_types` TEXT NOT NULL,
+    `secret` TEXT NOT NULL,
+    `enabled` BOOLEAN NOT NULL DEFAULT 1,
+    `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `version` INTEGER NOT NULL DEFAULT 1
+);
+
+CREATE TRIGGER [update_webhook_timestamp]
+    AFTER UPDATE
+    ON `webhook`
+    FOR EACH ROW
+BEGIN
+    UPDATE `webhook` SET updated_at = CURRENT_TIMESTAMP WHERE id = old.id;
+END;
+
+-- webhook_delivery is an outbox of events to send to webhooks. Deliveries are
+-- written in the same transaction as the change that caused the event, and sent
+-- by a background job.
+CREATE TABLE `webhook_delivery` (
+    `id` TEXT PRIMARY KEY NOT NULL,
+    `webhook_id` TEXT NOT NULL,
+    `event_id` TEXT NOT NULL,
+    `event_type` TEXT NOT NULL,
+    `payload` TEXT NOT NULL,
+    `status` TEXT NOT NULL DEFAULT 'pending',
+    `attempts` INTEGER NOT NULL DEFAULT 0,
+    `next_attempt_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `last_attempt_at` DATETIME NULL,
+    `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (`webhook_id`) REFERENCES `webhook` (`id`)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX `webhook_delivery_webhook_id` ON `webhook_delivery` (`webhook_id`, `created_at`);
+CREATE INDEX `webhook_delivery_due` ON `webhook_delivery` (`status`, `next_attempt_at`);
+
+CREATE TABLE `webhook_delivery_attempt` (
+    `id` TEXT PRIMARY KEY NOT NULL,
+    `delivery_id` TEXT NOT NULL,
+    `number` INTEGER NOT NULL,
+    `attempted_at` DATETIME NOT NULL,
+    `status_code` INTEGER NULL,
+    `error` TEXT NULL,
+    FOREIGN KEY (`delivery_id`) REFERENCES `webhook_delivery` (`id`)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX `webhook_delivery_attempt_delivery_id` ON `webhook_delivery_attempt` (`delivery_id`);
@@ -0,0 +1,3 @@
+DROP TABLE `event_subscriber`;
+DROP TABLE `event_outbox_head`;
+DROP TABLE `event_outbox`;
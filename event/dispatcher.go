@@ -0,0 +1,109 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/ninth-realm/heimdall/store"
+)
+
+// DefaultBatchSize is used when the Dispatcher's batch size is zero.
+const DefaultBatchSize = 100
+
+// Handler handles a single event for a subscriber. opts.Txn is the transaction
+// that marks the event as handled, so changes made with it are kept only if the
+// event is. Returning an error rolls it back, and the event is handled again on
+// the next dispatch.
+type Handler func(event store.OutboxEvent, opts store.QueryOptions) error
+
+type subscriber struct {
+	name    string
+	handler Handler
+}
+
+// Dispatcher hands events from the outbox to its subscribers. Each subscriber
+// gets every event, in order, and keeps its own position in the outbox, so one
+// that is failing holds up only itself.
+type Dispatcher struct {
+	Repo store.Repository
+	// BatchSize is the max number of events handed to each subscriber each
+	// time Dispatch is called.
+	BatchSize int
+
+	subscribers []subscriber
+}
+
+// Subscribe registers a handler for every event. The name identifies the
+// subscriber's position in the outbox, so it must be unique and shouldn't
+// change between releases. A new subscriber starts with the oldest event still
+// in the outbox. Subscribers must be added before Dispatch is first called.
+func (d *Dispatcher) Subscribe(name string, handler Handler) {
+	d.subscribers = append(d.subscribers, subscriber{name: name, handler: handler})
+}
+
+// Subscribers returns the names of the registered subscribers.
+func (d *Dispatcher) Subscribers() []string {
+	names := make([]string, 0, len(d.subscribers))
+	for _, s := range d.subscribers {
+		names = append(names, s.name)
+	}
+
+	return names
+}
+
+// Dispatch hands each subscriber the events it hasn't handled yet, up to the
+// batch size. A subscriber stops at the first event its handler fails on.
+func (d *Dispatcher) Dispatch(ctx context.Context) error {
+	var errs []error
+	for _, s := range d.subscribers {
+		if err := d.dispatchTo(ctx, s); err != nil {
+			errs = append(errs, fmt.Errorf("subscriber %s: %w", s.name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (d *Dispatcher) dispatchTo(ctx context.Context, s subscriber) error {
+	opts := store.QueryOptions{Ctx: ctx}
+
+	position, err := d.Repo.GetEventSubscriberPosition(s.name, opts)
+	if err != nil {
+		return err
+	}
+
+	batchSize := d.BatchSize
+	if batchSize == 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	events, err := d.Repo.ListOutboxEvents(position, batchSize, opts)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range events {
+		_, err := store.RunUnitOfWork(ctx, d.Repo, func(txn *sqlx.Tx) (struct{}, error) {
+			opts := store.QueryOptions{Ctx: ctx, Txn: txn}
+
+			if err := s.handler(e, opts); err != nil {
+				return struct{}{}, err
+			}
+
+			return struct{}{}, d.Repo.SaveEventSubscriberPosition(s.name, position, e.Sequence, opts)
+		})
+		if errors.Is(err, store.VersionMismatchError{}) {
+			// Another dispatcher handled the event first, and will carry on
+			// from there.
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("event %d: %w", e.Sequence, err)
+		}
+
+		position = e.Sequence
+	}
+
+	return nil
+}
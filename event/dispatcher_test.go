@@ -0,0 +1,186 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jmoiron/sqlx"
+	"github.com/ninth-realm/heimdall/store"
+	"github.com/ninth-realm/heimdall/store/sqlite/sqlitetest"
+)
+
+// recorder is a subscriber that keeps the events it's given. It fails while
+// failing is set.
+type recorder struct {
+	failing bool
+	events  []store.OutboxEvent
+}
+
+func (r *recorder) handle(e store.OutboxEvent, opts store.QueryOptions) error {
+	if r.failing {
+		return errors.New("subscriber is down")
+	}
+
+	r.events = append(r.events, e)
+	return nil
+}
+
+func (r *recorder) types() []string {
+	types := []string{}
+	for _, e := range r.events {
+		types = append(types, e.Type)
+	}
+
+	return types
+}
+
+func TestDispatcher_Dispatch(t *testing.T) {
+	ctx := context.Background()
+
+	emit := func(t *testing.T, repo store.Repository, eventTypes ...string) {
+		for _, eventType := range eventTypes {
+			err := Emit(repo, Event{Type: eventType}, store.QueryOptions{Ctx: ctx})
+			if err != nil {
+				t.Fatalf("Emit() error = %v", err)
+			}
+		}
+	}
+
+	t.Run("Every subscriber gets every event in order", func(t *testing.T) {
+		repo := sqlitetest.NewDB(t)
+		emit(t, repo, UserCreated, UserUpdated, UserDeleted)
+
+		audit, webhooks := &recorder{}, &recorder{}
+		d := &Dispatcher{Repo: repo, BatchSize: 2}
+		d.Subscribe("audit", audit.handle)
+		d.Subscribe("webhooks", webhooks.handle)
+
+		for i := 0; i < 3; i++ {
+			if err := d.Dispatch(ctx); err != nil {
+				t.Fatalf("Dispatch() error = %v", err)
+			}
+		}
+
+		want := []string{UserCreated, UserUpdated, UserDeleted}
+		for name, r := range map[string]*recorder{"audit": audit, "webhooks": webhooks} {
+			if got := r.types(); !cmp.Equal(got, want) {
+				t.Errorf("%s got %v, want %v", name, got, want)
+			}
+		}
+	})
+
+	t.Run("Rolled back events are not dispatched", func(t *testing.T) {
+		repo := sqlitetest.NewDB(t)
+
+		errAbort := errors.New("abort")
+		_, err := store.RunUnitOfWork(ctx, repo, func(txn *sqlx.Tx) (struct{}, error) {
+			opts := store.QueryOptions{Ctx: ctx, Txn: txn}
+			if err := Emit(repo, Event{Type: UserCreated}, opts); err != nil {
+				return struct{}{}, err
+			}
+
+			return struct{}{}, errAbort
+		})
+		if !errors.Is(err, errAbort) {
+			t.Fatalf("RunUnitOfWork() error = %v, want %v", err, errAbort)
+		}
+
+		r := &recorder{}
+		d := &Dispatcher{Repo: repo}
+		d.Subscribe("audit", r.handle)
+		if err := d.Dispatch(ctx); err != nil {
+			t.Fatalf("Dispatch() error = %v", err)
+		}
+
+		if len(r.events) != 0 {
+			t.Errorf("subscriber got %v, want no events", r.types())
+		}
+	})
+
+	t.Run("Failing subscriber is retried without holding up others", func(t *testing.T) {
+		repo := sqlitetest.NewDB(t)
+		emit(t, repo, UserCreated, UserUpdated)
+
+		failing, healthy := &recorder{failing: true}, &recorder{}
+		d := &Dispatcher{Repo: repo}
+		d.Subscribe("failing", failing.handle)
+		d.Subscribe("healthy", healthy.handle)
+
+		if err := d.Dispatch(ctx); err == nil {
+			t.Fatal("Dispatch() error = nil, want the subscriber's error")
+		}
+
+		if got := healthy.types(); !cmp.Equal(got, []string{UserCreated, UserUpdated}) {
+			t.Errorf("healthy subscriber got %v, want both events", got)
+		}
+
+		failing.failing = false
+		if err := d.Dispatch(ctx); err != nil {
+			t.Fatalf("Dispatch() error = %v", err)
+		}
+
+		if got := failing.types(); !cmp.Equal(got, []string{UserCreated, UserUpdated}) {
+			t.Errorf("recovered subscriber got %v, want both events", got)
+		}
+
+		if len(healthy.events) != 2 {
+			t.Errorf("healthy subscriber got %d events after redispatch, want 2", len(healthy.events))
+		}
+	})
+
+	t.Run("Captures actor, request and data", func(t *testing.T) {
+		repo := sqlitetest.NewDB(t)
+
+		ctx := WithActor(ctx, Actor{Type: ActorUser, ID: "user-1"})
+		ctx = WithRequest(ctx, "192.0.2.1", "request-1")
+
+		err := Emit(repo, Event{
+			Type:   UserUpdated,
+			Target: Target{Type: TargetUser, ID: "user-2"},
+			Data:   map[string]string{"field": "value"},
+		}, store.QueryOptions{Ctx: ctx})
+		if err != nil {
+			t.Fatalf("Emit() error = %v", err)
+		}
+
+		r := &recorder{}
+		d := &Dispatcher{Repo: repo}
+		d.Subscribe("audit", r.handle)
+		if err := d.Dispatch(ctx); err != nil {
+			t.Fatalf("Dispatch() error = %v", err)
+		}
+
+		if len(r.events) != 1 {
+			t.Fatalf("subscriber got %d events, want 1", len(r.events))
+		}
+
+		e := r.events[0]
+		if e.ActorType != ActorUser || deref(e.ActorID) != "user-1" {
+			t.Errorf("actor = %s %s, want %s user-1", e.ActorType, deref(e.ActorID), ActorUser)
+		}
+
+		if deref(e.IPAddress) != "192.0.2.1" || deref(e.RequestID) != "request-1" {
+			t.Errorf("request = %s %s, want 192.0.2.1 request-1", deref(e.IPAddress), deref(e.RequestID))
+		}
+
+		if deref(e.TargetType) != TargetUser || deref(e.TargetID) != "user-2" {
+			t.Errorf("target = %s %s, want %s user-2", deref(e.TargetType), deref(e.TargetID), TargetUser)
+		}
+
+		var data map[string]string
+		if err := json.Unmarshal(e.Data, &data); err != nil || data["field"] != "value" {
+			t.Errorf("data = %s, want {\"field\":\"value\"}", e.Data)
+		}
+	})
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
+}
@@ -0,0 +1,188 @@
+// Package event lets services announce what they've changed without knowing
+// who is interested.
+//
+// Services emit domain events inside the transaction making the change, which
+// writes them to an outbox. A Dispatcher later hands each event to every
+// subscriber, such as the audit log and webhooks, in order. Events from a
+// rolled back transaction are never written, so subscribers only see changes
+// that were kept. Delivery is at least once: a subscriber whose handler fails
+// is given the same event again on the next dispatch.
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/ninth-realm/heimdall/store"
+)
+
+// Event types.
+const (
+	// LoginSucceeded is emitted when a session token is issued to a user.
+	LoginSucceeded = "login.succeeded"
+	LoginFailed    = "login.failed"
+	Logout         = "logout"
+	// Reauthenticated is emitted when a user confirms their password for an
+	// existing session.
+	Reauthenticated = "reauthenticated"
+	SessionRevoked  = "session.revoked"
+	// SessionsRevoked is emitted when all of a user's sessions, other than
+	// possibly the current one, are revoked at once.
+	SessionsRevoked = "sessions.revoked"
+
+	UserCreated         = "user.created"
+	UserUpdated         = "user.updated"
+	UserDeleted         = "user.deleted"
+	UserRestored        = "user.restored"
+	UserPasswordChanged = "user.password_changed"
+
+	ClientCreated  = "client.created"
+	ClientUpdated  = "client.updated"
+	ClientDeleted  = "client.deleted"
+	ClientRestored = "client.restored"
+
+	APIKeyCreated = "api_key.created"
+	APIKeyRevoked = "api_key.deleted"
+
+	// SetupModeStarted is emitted when the server starts with authentication
+	// disabled. Events emitted while it runs have a setup actor.
+	SetupModeStarted = "setup_mode.started"
+)
+
+// Actor types.
+const (
+	ActorAnonymous = "anonymous"
+	ActorUser      = "user"
+	ActorClient    = "client"
+	// ActorSetup is anyone using the server while it's in setup mode, since
+	// they can't be identified.
+	ActorSetup = "setup"
+	// ActorSystem is Heimdall itself.
+	ActorSystem = "system"
+)
+
+// Target types.
+const (
+	TargetUser    = "user"
+	TargetClient  = "client"
+	TargetAPIKey  = "api_key"
+	TargetSession = "session"
+	// TargetEmail is the username given in a failed login, which may not
+	// belong to any user.
+	TargetEmail = "email"
+)
+
+// Actor is whoever caused an event.
+type Actor struct {
+	Type string
+	ID   string
+}
+
+// Target is the resource an event happened to.
+type Target struct {
+	Type string
+	ID   string
+}
+
+// Event describes something that happened. The time, IP address and request ID
+// are filled in when it's emitted.
+type Event struct {
+	Type   string
+	Target Target
+	// Actor overrides the actor attached to the context. This is needed when
+	// the caller only becomes known because of the event, such as a login.
+	Actor *Actor
+	// Data describes the event in more detail for subscribers that need it.
+	// It's encoded as JSON.
+	Data any
+}
+
+// UserData is the data of user events.
+type UserData struct {
+	User store.User `json:"user"`
+}
+
+type contextKey string
+
+const (
+	actorContextKey   contextKey = "actor"
+	requestContextKey contextKey = "request"
+)
+
+type request struct {
+	ipAddress string
+	requestID string
+}
+
+// WithActor attaches the caller to the context, so that events emitted with it
+// are attributed to them. Without one, events have an anonymous actor.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorContextKey, actor)
+}
+
+// WithRequest attaches details of the request being handled to the context, so
+// that events emitted with it can be traced back to the request.
+func WithRequest(ctx context.Context, ipAddress, requestID string) context.Context {
+	return context.WithValue(ctx, requestContextKey, request{ipAddress: ipAddress, requestID: requestID})
+}
+
+func actorFromContext(ctx context.Context) Actor {
+	actor, ok := ctx.Value(actorContextKey).(Actor)
+	if !ok {
+		return Actor{Type: ActorAnonymous}
+	}
+
+	return actor
+}
+
+// Emit writes an event to the outbox. opts.Txn should be the transaction making
+// the change that caused the event, so that the event is only kept if the
+// change is. Without a transaction, the event is written in its own.
+func Emit(repo store.Repository, event Event, opts store.QueryOptions) error {
+	ctx := opts.Context()
+
+	actor := actorFromContext(ctx)
+	if event.Actor != nil {
+		actor = *event.Actor
+	}
+
+	req, _ := ctx.Value(requestContextKey).(request)
+
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+
+	e := store.NewOutboxEvent{
+		Type:       event.Type,
+		OccurredAt: time.Now().UTC(),
+		ActorType:  actor.Type,
+		ActorID:    optionalString(actor.ID),
+		IPAddress:  optionalString(req.ipAddress),
+		RequestID:  optionalString(req.requestID),
+		TargetType: optionalString(event.Target.Type),
+		TargetID:   optionalString(event.Target.ID),
+		Data:       data,
+	}
+
+	if opts.Txn != nil {
+		_, err := repo.AppendOutboxEvent(e, opts)
+		return err
+	}
+
+	_, err = store.RunUnitOfWork(ctx, repo, func(txn *sqlx.Tx) (store.OutboxEvent, error) {
+		return repo.AppendOutboxEvent(e, store.QueryOptions{Ctx: ctx, Txn: txn})
+	})
+
+	return err
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+
+	return &s
+}
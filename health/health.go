@@ -0,0 +1,165 @@
+// Package health reports whether the server is ready to handle requests, by
+// running a set of checks against the things it depends on.
+//
+// Readiness is served without authentication, so a failing check only reports
+// its error when it's an Error, which is safe to show to anyone. Any other
+// error is logged and reported as a generic failure.
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/ninth-realm/heimdall/store"
+)
+
+// DefaultTimeout is how long checks are given to finish when the service has
+// no timeout set.
+const DefaultTimeout = 5 * time.Second
+
+// Statuses of a check or report.
+const (
+	StatusOK      = "ok"
+	StatusFailing = "failing"
+)
+
+// Error is a check failure whose message is safe to show to unauthenticated
+// callers.
+type Error string
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+// Check is a single dependency that must be healthy for the server to be
+// ready.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Result is the outcome of a single check.
+type Result struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Report is the outcome of all checks. It's only ok when every check is.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+type Service struct {
+	Checks []Check
+	// Timeout bounds how long the checks may take together. A check that
+	// hasn't finished in time fails.
+	Timeout time.Duration
+	Logger  *slog.Logger
+}
+
+// Ready runs every check at once and reports on each of them.
+func (s Service) Ready(ctx context.Context) Report {
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(s.Checks))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range s.Checks {
+		wg.Add(1)
+		go func(check Check) {
+			defer wg.Done()
+
+			result := s.run(ctx, check)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			report.Checks[check.Name] = result
+			if result.Status != StatusOK {
+				report.Status = StatusFailing
+			}
+		}(check)
+	}
+	wg.Wait()
+
+	return report
+}
+
+func (s Service) run(ctx context.Context, check Check) Result {
+	err := check.Run(ctx)
+	if err == nil {
+		return Result{Status: StatusOK}
+	}
+
+	s.logger().WarnContext(ctx, "Health check failed", "check", check.Name, "error", err)
+
+	var public Error
+	switch {
+	case errors.As(err, &public):
+		return Result{Status: StatusFailing, Error: public.Error()}
+	case errors.Is(err, context.DeadlineExceeded):
+		return Result{Status: StatusFailing, Error: "timed out"}
+	default:
+		return Result{Status: StatusFailing, Error: "check failed"}
+	}
+}
+
+// logger returns the service's logger, or the default logger if it has none.
+func (s Service) logger() *slog.Logger {
+	if s.Logger != nil {
+		return s.Logger
+	}
+
+	return slog.Default()
+}
+
+// DatabaseCheck checks that the database can be reached.
+func DatabaseCheck(repo store.Repository) Check {
+	return Check{
+		Name: "database",
+		Run: func(ctx context.Context) error {
+			if err := repo.Ping(ctx); err != nil {
+				return fmt.Errorf("%w: %v", Error("database unreachable"), err)
+			}
+
+			return nil
+		},
+	}
+}
+
+// MigrationsCheck checks that the database has been migrated to at least the
+// latest migration this build knows about. A newer database is accepted, so
+// that an older build can keep serving while a newer one is rolled out.
+func MigrationsCheck(repo store.Repository, latest uint) Check {
+	return Check{
+		Name: "migrations",
+		Run: func(ctx context.Context) error {
+			v, err := repo.GetMigrationVersion(store.QueryOptions{Ctx: ctx})
+			if err != nil {
+				return err
+			}
+
+			if v.Dirty {
+				return Error(fmt.Sprintf("migration %d failed and must be fixed by hand", v.Version))
+			}
+
+			if v.Version < latest {
+				return Error(fmt.Sprintf("database is at migration %d, want %d", v.Version, latest))
+			}
+
+			return nil
+		},
+	}
+}
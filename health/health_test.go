@@ -0,0 +1,128 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ninth-realm/heimdall/store"
+)
+
+// migrationRepo is at a fixed migration. Only the methods used by the checks
+// are implemented.
+type migrationRepo struct {
+	store.Repository
+	version store.MigrationVersion
+	err     error
+}
+
+func (r migrationRepo) Ping(ctx context.Context) error {
+	return r.err
+}
+
+func (r migrationRepo) GetMigrationVersion(opts store.QueryOptions) (store.MigrationVersion, error) {
+	return r.version, r.err
+}
+
+func TestService_Ready(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name   string
+		checks []Check
+		want   Report
+	}{
+		{
+			name: "All ok",
+			checks: []Check{
+				DatabaseCheck(migrationRepo{}),
+				MigrationsCheck(migrationRepo{version: store.MigrationVersion{Version: 12}}, 12),
+			},
+			want: Report{Status: StatusOK, Checks: map[string]Result{
+				"database":   {Status: StatusOK},
+				"migrations": {Status: StatusOK},
+			}},
+		},
+		{
+			name: "Database unreachable",
+			checks: []Check{
+				DatabaseCheck(migrationRepo{err: errors.New("dial tcp: connection refused")}),
+			},
+			want: Report{Status: StatusFailing, Checks: map[string]Result{
+				"database": {Status: StatusFailing, Error: "database unreachable"},
+			}},
+		},
+		{
+			name: "Migrations behind",
+			checks: []Check{
+				MigrationsCheck(migrationRepo{version: store.MigrationVersion{Version: 11}}, 12),
+			},
+			want: Report{Status: StatusFailing, Checks: map[string]Result{
+				"migrations": {Status: StatusFailing, Error: "database is at migration 11, want 12"},
+			}},
+		},
+		{
+			name: "Migrations ahead",
+			checks: []Check{
+				MigrationsCheck(migrationRepo{version: store.MigrationVersion{Version: 13}}, 12),
+			},
+			want: Report{Status: StatusOK, Checks: map[string]Result{
+				"migrations": {Status: StatusOK},
+			}},
+		},
+		{
+			name: "Dirty migration",
+			checks: []Check{
+				MigrationsCheck(migrationRepo{version: store.MigrationVersion{Version: 12, Dirty: true}}, 12),
+			},
+			want: Report{Status: StatusFailing, Checks: map[string]Result{
+				"migrations": {Status: StatusFailing, Error: "migration 12 failed and must be fixed by hand"},
+			}},
+		},
+		{
+			name: "Internal error is hidden",
+			checks: []Check{
+				MigrationsCheck(migrationRepo{err: errors.New("no such table: schema_migrations")}, 12),
+			},
+			want: Report{Status: StatusFailing, Checks: map[string]Result{
+				"migrations": {Status: StatusFailing, Error: "check failed"},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Service{Checks: tt.checks, Logger: logger}
+
+			got := s.Ready(context.Background())
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Ready() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestService_Ready_timeout(t *testing.T) {
+	s := Service{
+		Timeout: 10 * time.Millisecond,
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Checks: []Check{{
+			Name: "slow",
+			Run: func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		}},
+	}
+
+	got := s.Ready(context.Background())
+	want := Report{Status: StatusFailing, Checks: map[string]Result{
+		"slow": {Status: StatusFailing, Error: "timed out"},
+	}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Ready() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,69 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/ninth-realm/heimdall/store"
+)
+
+func (s *Server) handleAuditEventsList() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+
+		page, err := parsePagination(q)
+		if err != nil {
+			s.respondWithError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		occurredAfter, err := queryTime(q, "occurredAfter")
+		if err != nil {
+			s.respondWithError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		occurredBefore, err := queryTime(q, "occurredBefore")
+		if err != nil {
+			s.respondWithError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		// The most recent events are usually the interesting ones, so they
+		// come first unless asked otherwise.
+		sort := store.Sort{Field: store.SortBySequence, Descending: true}
+		if q.Get("sort") != "" {
+			sort = parseSort(q)
+		}
+
+		events, err := s.AuditService.ListEvents(r.Context(), store.AuditEventListOptions{
+			Filter: store.AuditEventFilter{
+				Type:           queryString(q, "type"),
+				ActorID:        queryString(q, "actorId"),
+				TargetType:     queryString(q, "targetType"),
+				TargetID:       queryString(q, "targetId"),
+				OccurredAfter:  occurredAfter,
+				OccurredBefore: occurredBefore,
+			},
+			Sort:       sort,
+			Pagination: page,
+		})
+		if err != nil {
+			s.respondWithError(w, r, errorStatus(err), err)
+			return
+		}
+
+		s.respondWithPage(w, r, http.StatusOK, events.Items, nextLink(r, events.Next))
+	})
+}
+
+func (s *Server) handleAuditEventsVerify() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		result, err := s.AuditService.Verify(r.Context())
+		if err != nil {
+			s.respondWithError(w, r, errorStatus(err), err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, result)
+	})
+}
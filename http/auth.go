@@ -1,54 +1,349 @@
 package http
 
 import (
+	"context"
+	"crypto/x509"
 	"errors"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/gofrs/uuid/v5"
+	"github.com/ninth-realm/heimdall/auth"
+	"github.com/ninth-realm/heimdall/event"
 )
 
 const APIKeyHeaderName = "X-API-Key"
 
 const SessionCookieName = "heimdall_sessionToken"
 
+// DPoPHeaderName is the header DPoP proofs are sent in.
+const DPoPHeaderName = "DPoP"
+
 var authErr = errors.New("missing or invalid auth token")
 
+var reauthErr = errors.New("re-authentication required")
+
+var errDPoPSchemeRequired = errors.New("DPoP bound tokens must be sent with the DPoP scheme")
+
+// defaultReauthenticationWindow is used when the server is not configured with
+// a window.
+const defaultReauthenticationWindow = 10 * time.Minute
+
+type contextKey string
+
+const principalContextKey contextKey = "principal"
+
+// principal identifies the caller of an authenticated request. Exactly one of
+// UserID or ClientID will be set depending on how the caller authenticated.
+type principal struct {
+	UserID    uuid.UUID
+	ClientID  uuid.UUID
+	SessionID uuid.UUID
+
+	// RememberMe and AuthTime describe the session used to authenticate.
+	RememberMe bool
+	AuthTime   time.Time
+
+	// Certificate is the client certificate the client authenticated with, if
+	// it used one.
+	Certificate *x509.Certificate
+}
+
+// withPrincipal attaches the caller to the context, along with the matching
+// actor for any audit events recorded while handling the request. The caller is
+// also added to the request's access log line.
+func withPrincipal(ctx context.Context, p principal) context.Context {
+	recordPrincipal(ctx, p)
+	ctx = event.WithActor(ctx, p.actor())
+	return context.WithValue(ctx, principalContextKey, p)
+}
+
+func (p principal) actor() event.Actor {
+	if p.ClientID != uuid.Nil {
+		return event.Actor{Type: event.ActorClient, ID: p.ClientID.String()}
+	}
+
+	return event.Actor{Type: event.ActorUser, ID: p.UserID.String()}
+}
+
+// principalFromContext returns the caller attached to the context by one of the
+// authentication middlewares. The zero value is returned if the request was not
+// authenticated, e.g. when auth is disabled.
+func principalFromContext(ctx context.Context) principal {
+	p, _ := ctx.Value(principalContextKey).(principal)
+	return p
+}
+
 func (s *Server) authenticateRoute(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if s.DisableAuth {
-			next.ServeHTTP(w, r)
+			ctx := event.WithActor(r.Context(), event.Actor{Type: event.ActorSetup})
+			next.ServeHTTP(w, r.WithContext(ctx))
 			return
 		}
 
-		err := s.authenticateAPIKey(r)
+		p, err := s.authenticateClientCertificate(r)
 		if err == nil {
-			next.ServeHTTP(w, r)
+			next.ServeHTTP(w, r.WithContext(withPrincipal(r.Context(), p)))
 			return
 		}
 
-		err = s.authenticateSessionToken(r)
+		p, err = s.authenticateAPIKey(r)
 		if err == nil {
-			next.ServeHTTP(w, r)
+			next.ServeHTTP(w, r.WithContext(withPrincipal(r.Context(), p)))
 			return
 		}
 
+		p, tokenErr := s.authenticateAccessToken(r)
+		if tokenErr == nil {
+			next.ServeHTTP(w, r.WithContext(withPrincipal(r.Context(), p)))
+			return
+		}
+
+		p, err = s.authenticateSessionToken(w, r)
+		if err == nil {
+			next.ServeHTTP(w, r.WithContext(withPrincipal(r.Context(), p)))
+			return
+		}
+
+		if challenge := dpopChallenge(r, tokenErr); challenge != "" {
+			w.Header().Set("WWW-Authenticate", challenge)
+		}
+
 		s.respondWithError(w, r, http.StatusUnauthorized, authErr)
 	})
 }
 
-func (s *Server) authenticateAPIKey(r *http.Request) error {
+// requireClient only allows clients authenticated with a certificate or an API
+// key. Like requireSession, this check is never disabled, since the routes it
+// protects act on behalf of the client.
+func (s *Server) requireClient(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, err := s.authenticateClientCertificate(r)
+		if err != nil {
+			p, err = s.authenticateAPIKey(r)
+		}
+		if err != nil {
+			s.respondWithError(w, r, http.StatusUnauthorized, authErr)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(withPrincipal(r.Context(), p)))
+	})
+}
+
+// requireSession only allows requests made with a valid session cookie. Unlike
+// authenticateRoute, this check is never disabled since the routes it protects
+// act on behalf of the logged in user.
+func (s *Server) requireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, err := s.authenticateSessionToken(w, r)
+		if err != nil {
+			s.respondWithError(w, r, http.StatusUnauthorized, authErr)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(withPrincipal(r.Context(), p)))
+	})
+}
+
+// requireRecentAuth guards sensitive routes. Remember me sessions may have been
+// left logged in on a shared device, so the user must have confirmed their
+// password recently. This must be used after requireSession or
+// authenticateRoute. Clients aren't affected, since only sessions are
+// remembered.
+func (s *Server) requireRecentAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := principalFromContext(r.Context())
+
+		window := s.ReauthenticationWindow
+		if window <= 0 {
+			window = defaultReauthenticationWindow
+		}
+
+		if p.RememberMe && time.Since(p.AuthTime) > window {
+			s.respondWithError(w, r, http.StatusForbidden, reauthErr)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// auditRequest attaches the caller's IP address and the request ID to the
+// context, so that they're included in any audit events.
+func auditRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := event.WithRequest(r.Context(), remoteIP(r), middleware.GetReqID(r.Context()))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// authenticateClientCertificate identifies the client from the certificate it
+// presented over mutual TLS. Only certificates that were verified against the
+// trusted client CAs during the handshake are considered.
+func (s *Server) authenticateClientCertificate(r *http.Request) (principal, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		return principal{}, authErr
+	}
+
+	cert := r.TLS.VerifiedChains[0][0]
+	clientID, err := s.AuthService.ValidateClientCertificate(r.Context(), cert)
+	if err != nil {
+		return principal{}, err
+	}
+
+	return principal{ClientID: clientID, Certificate: cert}, nil
+}
+
+// authenticateAccessToken validates an access token issued to a client, sent
+// with the Bearer or DPoP scheme. Tokens bound to a certificate are only
+// accepted over a connection made with that certificate, and tokens bound to a
+// DPoP key only with the DPoP scheme and a proof signed by that key.
+func (s *Server) authenticateAccessToken(r *http.Request) (principal, error) {
+	header := r.Header.Get("Authorization")
+	token, bearer := strings.CutPrefix(header, "Bearer ")
+	found := bearer
+	if !found {
+		token, found = strings.CutPrefix(header, "DPoP ")
+	}
+	if !found || token == "" {
+		return principal{}, authErr
+	}
+
+	info, err := s.AuthService.ValidateAccessToken(r.Context(), token, s.tokenProof(r))
+	if err != nil {
+		return principal{}, err
+	}
+
+	// RFC 9449 §7.1 doesn't let DPoP bound tokens be used as bearer tokens,
+	// even alongside a valid proof.
+	if cnf := info.Confirmation; bearer && cnf != nil && cnf.JWKThumbprint != "" {
+		return principal{}, errDPoPSchemeRequired
+	}
+
+	clientID, err := uuid.FromString(info.ClientID)
+	if err != nil {
+		return principal{}, authErr
+	}
+
+	return principal{ClientID: clientID}, nil
+}
+
+// dpopChallenge is the WWW-Authenticate challenge for a request whose access
+// token was rejected, when the request or its token used DPoP. It tells the
+// client whether its proof or its token was at fault, as described by RFC 9449.
+func dpopChallenge(r *http.Request, err error) string {
+	switch {
+	case errors.Is(err, auth.ErrInvalidDPoPProof):
+		return `DPoP error="invalid_dpop_proof"`
+	case errors.Is(err, errDPoPSchemeRequired):
+		return `DPoP error="invalid_token"`
+	case strings.HasPrefix(r.Header.Get("Authorization"), "DPoP "):
+		return `DPoP error="invalid_token"`
+	default:
+		return ""
+	}
+}
+
+// tokenProof collects what the request presented to prove it holds any key its
+// token is bound to.
+func (s *Server) tokenProof(r *http.Request) auth.TokenProof {
+	var proof auth.TokenProof
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		proof.Certificate = r.TLS.VerifiedChains[0][0]
+	}
+
+	if dpop := r.Header.Get(DPoPHeaderName); dpop != "" {
+		proof.DPoP = &auth.DPoPProof{JWT: dpop, Method: r.Method, URL: s.requestURL(r)}
+	}
+
+	return proof
+}
+
+// requestURL is the URL the client made the request to, without its query
+// string. The scheme and host come from PublicURL when it's set, since a proxy
+// in front of the server may have changed them.
+func (s *Server) requestURL(r *http.Request) string {
+	u := url.URL{Scheme: "http", Host: r.Host, Path: r.URL.Path}
+	if r.TLS != nil {
+		u.Scheme = "https"
+	}
+
+	if s.PublicURL != nil {
+		u.Scheme = s.PublicURL.Scheme
+		u.Host = s.PublicURL.Host
+	}
+
+	return u.String()
+}
+
+func (s *Server) authenticateAPIKey(r *http.Request) (principal, error) {
 	token := r.Header.Get(APIKeyHeaderName)
 	if token == "" {
-		return authErr
+		return principal{}, authErr
 	}
 
-	return s.AuthService.ValidateAPIKey(r.Context(), token)
+	clientID, err := s.AuthService.ValidateAPIKey(r.Context(), token)
+	if err != nil {
+		return principal{}, err
+	}
+
+	return principal{ClientID: clientID}, nil
 }
 
-func (s *Server) authenticateSessionToken(r *http.Request) error {
+// authenticateSessionToken validates the session cookie. Using a session extends
+// its expiration, so the cookie is refreshed to match.
+func (s *Server) authenticateSessionToken(w http.ResponseWriter, r *http.Request) (principal, error) {
 	cookie, err := r.Cookie(SessionCookieName)
 	if err != nil || cookie.Value == "" {
-		return authErr
+		return principal{}, authErr
+	}
+
+	info, err := s.AuthService.IntrospectToken(r.Context(), cookie.Value)
+	if err != nil {
+		return principal{}, err
+	}
+
+	userID, err := uuid.FromString(info.UserID)
+	if err != nil {
+		return principal{}, err
+	}
+
+	sessionID, err := uuid.FromString(info.SessionID)
+	if err != nil {
+		return principal{}, err
+	}
+
+	s.setSessionCookie(w, cookie.Value, info.ExpiresAt)
+
+	return principal{
+		UserID:     userID,
+		SessionID:  sessionID,
+		RememberMe: info.RememberMe,
+		AuthTime:   time.Unix(info.AuthTime, 0),
+	}, nil
+}
+
+func (s *Server) setSessionCookie(w http.ResponseWriter, token string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookieName,
+		Value:    token,
+		Path:     "/",
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: s.sessionCookieSameSite(),
+		MaxAge:   maxAge,
+	})
+}
+
+func (s *Server) sessionCookieSameSite() http.SameSite {
+	if s.SessionCookieSameSite == 0 {
+		return http.SameSiteLaxMode
 	}
 
-	_, err = s.AuthService.IntrospectToken(r.Context(), cookie.Value)
-	return err
+	return s.SessionCookieSameSite
 }
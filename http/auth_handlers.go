@@ -1,15 +1,19 @@
 package http
 
 import (
+	"errors"
+	"net"
 	"net/http"
 
 	"github.com/ninth-realm/heimdall/auth"
+	"github.com/ninth-realm/heimdall/store"
 )
 
 func (s *Server) handleAuthLogin() http.HandlerFunc {
 	type request struct {
-		Username nonEmptyString `json:"username"`
-		Password nonEmptyString `json:"password"`
+		Username   nonEmptyString `json:"username"`
+		Password   nonEmptyString `json:"password"`
+		RememberMe bool           `json:"rememberMe"`
 	}
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -20,25 +24,22 @@ func (s *Server) handleAuthLogin() http.HandlerFunc {
 			return
 		}
 
-		token, err := s.AuthService.Login(
-			r.Context(),
-			requestBody.Username.toString(),
-			requestBody.Password.toString(),
-		)
-		if err != nil {
+		token, err := s.AuthService.Login(r.Context(), auth.Credentials{
+			Username:   requestBody.Username.toString(),
+			Password:   requestBody.Password.toString(),
+			UserAgent:  r.UserAgent(),
+			IPAddress:  remoteIP(r),
+			RememberMe: requestBody.RememberMe,
+		})
+		if errors.Is(err, auth.ErrInvalidCredentials) {
 			s.respondWithError(w, r, http.StatusUnauthorized, err)
 			return
+		} else if err != nil {
+			s.respondWithError(w, r, errorStatus(err), err)
+			return
 		}
 
-		http.SetCookie(w, &http.Cookie{
-			Name:     SessionCookieName,
-			Value:    token.AccessToken,
-			Path:     "/",
-			Secure:   true,
-			HttpOnly: true,
-			SameSite: http.SameSiteLaxMode,
-			MaxAge:   token.Lifespan,
-		})
+		s.setSessionCookie(w, token.AccessToken, token.Lifespan)
 
 		s.respond(w, r, http.StatusNoContent, nil)
 	})
@@ -65,6 +66,7 @@ func (s *Server) handleAuthLogout() http.HandlerFunc {
 			Path:     "/",
 			Secure:   true,
 			HttpOnly: true,
+			SameSite: s.sessionCookieSameSite(),
 			MaxAge:   -1,
 		})
 
@@ -72,6 +74,34 @@ func (s *Server) handleAuthLogout() http.HandlerFunc {
 	})
 }
 
+func (s *Server) handleAuthReauthenticate() http.HandlerFunc {
+	type request struct {
+		Password nonEmptyString `json:"password"`
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := principalFromContext(r.Context())
+
+		var requestBody request
+		err := s.decode(r, &requestBody)
+		if err != nil {
+			s.respondWithError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		err = s.AuthService.Reauthenticate(r.Context(), p.UserID, p.SessionID, requestBody.Password.toString())
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			s.respondWithError(w, r, http.StatusUnauthorized, err)
+			return
+		} else if err != nil {
+			s.respondWithError(w, r, errorStatus(err), err)
+			return
+		}
+
+		s.respond(w, r, http.StatusNoContent, nil)
+	})
+}
+
 func (s *Server) handleAuthIntrospect() http.HandlerFunc {
 	type request struct {
 		Token string `json:"token"`
@@ -85,12 +115,82 @@ func (s *Server) handleAuthIntrospect() http.HandlerFunc {
 		}
 
 		token, err := s.AuthService.IntrospectToken(r.Context(), body.Token)
-		if err != nil {
-			token = auth.TokenInfo{Active: false}
-			s.respond(w, r, http.StatusUnauthorized, token)
+		if inactiveToken(err) {
+			s.respond(w, r, http.StatusUnauthorized, auth.TokenInfo{Active: false})
+			return
+		} else if err != nil {
+			s.respondWithError(w, r, http.StatusInternalServerError, err)
 			return
 		}
 
 		s.respond(w, r, http.StatusOK, token)
 	})
 }
+
+// inactiveToken reports whether introspection failed because the token isn't
+// active, rather than because it couldn't be checked.
+func inactiveToken(err error) bool {
+	return errors.Is(err, store.NotFoundError{}) ||
+		errors.Is(err, auth.ErrSessionExpired) ||
+		errors.Is(err, auth.ErrInvalidAccessToken) ||
+		errors.Is(err, auth.ErrClientTokensDisabled)
+}
+
+// grantClientCredentials is the grant type for a client requesting a token for
+// itself.
+const grantClientCredentials = "client_credentials"
+
+var errUnsupportedGrant = errors.New("unsupported grant type")
+
+// handleAuthToken issues an access token to the client that made the request.
+// Tokens requested over mutual TLS are bound to the client's certificate, and
+// tokens requested with a DPoP proof are bound to the proof's key.
+func (s *Server) handleAuthToken() http.HandlerFunc {
+	type request struct {
+		GrantType string `json:"grantType"`
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body request
+		if err := s.decode(r, &body); err != nil {
+			s.respondWithError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		if body.GrantType != grantClientCredentials {
+			s.respondWithError(w, r, http.StatusBadRequest, errUnsupportedGrant)
+			return
+		}
+
+		p := principalFromContext(r.Context())
+		proof := s.tokenProof(r)
+		// Only the certificate the client authenticated with is bound, rather
+		// than any that was sent alongside an API key.
+		proof.Certificate = p.Certificate
+
+		token, err := s.AuthService.IssueClientToken(r.Context(), p.ClientID, proof)
+		if errors.Is(err, auth.ErrClientTokensDisabled) {
+			s.respondWithError(w, r, http.StatusNotFound, err)
+			return
+		} else if errors.Is(err, auth.ErrInvalidDPoPProof) {
+			s.respondWithError(w, r, http.StatusBadRequest, err)
+			return
+		} else if err != nil {
+			s.respondWithError(w, r, errorStatus(err), err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, token)
+	})
+}
+
+// remoteIP returns the IP address of the peer that made the request. Forwarding
+// headers are ignored since they can be set by anyone.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
@@ -0,0 +1,308 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/ninth-realm/heimdall/auth"
+	"github.com/ninth-realm/heimdall/store"
+)
+
+func TestServer_requestURL(t *testing.T) {
+	tests := []struct {
+		name      string
+		publicURL string
+		tls       bool
+		want      string
+	}{
+		{
+			name: "Plain HTTP",
+			want: "http://auth.example.com/api/v1/clients",
+		},
+		{
+			name: "TLS",
+			tls:  true,
+			want: "https://auth.example.com/api/v1/clients",
+		},
+		{
+			name:      "Behind a proxy",
+			publicURL: "https://public.example.com",
+			want:      "https://public.example.com/api/v1/clients",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{}
+			if tt.publicURL != "" {
+				s.PublicURL, _ = url.Parse(tt.publicURL)
+			}
+
+			r := httptest.NewRequest(http.MethodGet, "http://auth.example.com/api/v1/clients?limit=5", nil)
+			if tt.tls {
+				r.TLS = &tls.ConnectionState{}
+			}
+
+			if got := s.requestURL(r); got != tt.want {
+				t.Errorf("requestURL() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// sessionAuthService accepts every session token as a remember me session
+// that last entered its password at authTime. Only the methods used to
+// authenticate sessions are implemented.
+type sessionAuthService struct {
+	AuthService
+	authTime time.Time
+}
+
+func (s sessionAuthService) IntrospectToken(ctx context.Context, token string) (auth.TokenInfo, error) {
+	return auth.TokenInfo{
+		Active:     true,
+		UserID:     uuid.Must(uuid.NewV4()).String(),
+		SessionID:  uuid.Must(uuid.NewV4()).String(),
+		AuthTime:   s.authTime.Unix(),
+		RememberMe: true,
+	}, nil
+}
+
+func Test_requireRecentAuth_routes(t *testing.T) {
+	id := uuid.Must(uuid.NewV4()).String()
+	routes := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodPost, "/api/v1/users"},
+		{http.MethodPatch, "/api/v1/users/" + id},
+		{http.MethodDelete, "/api/v1/users/" + id},
+		{http.MethodDelete, "/api/v1/users/" + id + "/sessions"},
+		{http.MethodPost, "/api/v1/clients"},
+		{http.MethodDelete, "/api/v1/clients/" + id},
+		{http.MethodPost, "/api/v1/clients/" + id + "/api-keys"},
+		{http.MethodPost, "/api/v1/webhooks"},
+		{http.MethodDelete, "/api/v1/webhooks/" + id},
+		{http.MethodPut, "/api/v1/me/password"},
+		{http.MethodDelete, "/api/v1/me/sessions"},
+		{http.MethodDelete, "/api/v1/me/sessions/" + id},
+	}
+	for _, tt := range routes {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			s := NewServer()
+			s.AuthService = sessionAuthService{authTime: time.Now().Add(-time.Hour)}
+
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			r.AddCookie(&http.Cookie{Name: SessionCookieName, Value: "token"})
+			w := httptest.NewRecorder()
+
+			s.ServeHTTP(w, r)
+
+			if w.Code != http.StatusForbidden {
+				t.Errorf("status for stale remember me session = %d, want %d", w.Code, http.StatusForbidden)
+			}
+		})
+	}
+}
+
+func Test_requireRecentAuth(t *testing.T) {
+	tests := []struct {
+		name       string
+		principal  principal
+		wantStatus int
+	}{
+		{
+			name:       "Recent remember me session",
+			principal:  principal{RememberMe: true, AuthTime: time.Now().Add(-time.Minute)},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Stale remember me session",
+			principal:  principal{RememberMe: true, AuthTime: time.Now().Add(-time.Hour)},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Normal session",
+			principal:  principal{AuthTime: time.Now().Add(-time.Hour)},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Client",
+			principal:  principal{ClientID: uuid.Must(uuid.NewV4())},
+			wantStatus: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{}
+			handler := s.requireRecentAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			r := httptest.NewRequest(http.MethodDelete, "/api/v1/users", nil)
+			r = r.WithContext(withPrincipal(r.Context(), tt.principal))
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+// accessTokenAuthService accepts every access token as one issued to a client,
+// bound to the DPoP key jkt when it's set, unless err is. Only the methods used
+// to authenticate access tokens are implemented.
+type accessTokenAuthService struct {
+	AuthService
+	jkt string
+	err error
+}
+
+func (s accessTokenAuthService) ValidateAccessToken(ctx context.Context, token string, proof auth.TokenProof) (auth.TokenInfo, error) {
+	if s.err != nil {
+		return auth.TokenInfo{}, s.err
+	}
+
+	info := auth.TokenInfo{Active: true, ClientID: uuid.Must(uuid.NewV4()).String()}
+	if s.jkt != "" {
+		info.Confirmation = &auth.Confirmation{JWKThumbprint: s.jkt}
+	}
+
+	return info, nil
+}
+
+func Test_authenticateRoute_accessToken(t *testing.T) {
+	tests := []struct {
+		name          string
+		authorization string
+		service       accessTokenAuthService
+		wantStatus    int
+		wantChallenge string
+	}{
+		{
+			name:          "Bearer token",
+			authorization: "Bearer token",
+			wantStatus:    http.StatusOK,
+		},
+		{
+			name:          "DPoP bound token",
+			authorization: "DPoP token",
+			service:       accessTokenAuthService{jkt: "thumbprint"},
+			wantStatus:    http.StatusOK,
+		},
+		{
+			name:          "DPoP bound token sent as a bearer token",
+			authorization: "Bearer token",
+			service:       accessTokenAuthService{jkt: "thumbprint"},
+			wantStatus:    http.StatusUnauthorized,
+			wantChallenge: `DPoP error="invalid_token"`,
+		},
+		{
+			name:          "Invalid proof",
+			authorization: "DPoP token",
+			service:       accessTokenAuthService{err: auth.ErrInvalidDPoPProof},
+			wantStatus:    http.StatusUnauthorized,
+			wantChallenge: `DPoP error="invalid_dpop_proof"`,
+		},
+		{
+			name:          "Invalid DPoP token",
+			authorization: "DPoP token",
+			service:       accessTokenAuthService{err: errors.New("invalid access token")},
+			wantStatus:    http.StatusUnauthorized,
+			wantChallenge: `DPoP error="invalid_token"`,
+		},
+		{
+			name:          "Invalid bearer token",
+			authorization: "Bearer token",
+			service:       accessTokenAuthService{err: errors.New("invalid access token")},
+			wantStatus:    http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{AuthService: tt.service}
+			handler := s.authenticateRoute(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+			r.Header.Set("Authorization", tt.authorization)
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			if got := w.Header().Get("WWW-Authenticate"); got != tt.wantChallenge {
+				t.Errorf("WWW-Authenticate = %q, want %q", got, tt.wantChallenge)
+			}
+		})
+	}
+}
+
+// introspectAuthService fails every introspection with err. Only the methods
+// used by the introspection endpoint are implemented.
+type introspectAuthService struct {
+	AuthService
+	err error
+}
+
+func (s introspectAuthService) IntrospectToken(ctx context.Context, token string) (auth.TokenInfo, error) {
+	return auth.TokenInfo{}, s.err
+}
+
+func Test_handleAuthIntrospect_errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{
+			name:       "Unknown session",
+			err:        store.NotFoundError{ResourceType: "session"},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "Expired session",
+			err:        auth.ErrSessionExpired,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "Invalid access token",
+			err:        fmt.Errorf("%w: client is disabled", auth.ErrInvalidAccessToken),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "Database failure",
+			err:        errors.New("connection refused"),
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{AuthService: introspectAuthService{err: tt.err}}
+
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/auth/introspect", strings.NewReader(`{"token":"token"}`))
+			w := httptest.NewRecorder()
+
+			s.handleAuthIntrospect().ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
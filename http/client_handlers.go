@@ -10,20 +10,42 @@ import (
 
 func (s *Server) handleClientsList() http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		clients, err := s.ClientService.ListClients(r.Context())
+		q := r.URL.Query()
+
+		page, err := parsePagination(q)
+		if err != nil {
+			s.respondWithError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		enabled, err := queryBool(q, "enabled")
+		if err != nil {
+			s.respondWithError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		clients, err := s.ClientService.ListClients(r.Context(), store.ClientListOptions{
+			Filter: store.ClientFilter{
+				Name:    queryString(q, "name"),
+				Enabled: enabled,
+			},
+			Sort:       parseSort(q),
+			Pagination: page,
+		})
 		if err != nil {
-			s.respondWithError(w, r, http.StatusInternalServerError, err)
+			s.respondWithError(w, r, errorStatus(err), err)
 			return
 		}
 
-		s.respond(w, r, http.StatusOK, clients)
+		s.respondWithPage(w, r, http.StatusOK, clients.Items, nextLink(r, clients.Next))
 	})
 }
 
 func (s *Server) handleClientsCreate() http.HandlerFunc {
 	type request struct {
-		Name    nonEmptyString `json:"name"`
-		Enabled bool           `json:"enabled"`
+		Name           nonEmptyString `json:"name"`
+		Enabled        bool           `json:"enabled"`
+		AllowedOrigins originList     `json:"allowedOrigins"`
 	}
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -35,14 +57,16 @@ func (s *Server) handleClientsCreate() http.HandlerFunc {
 		}
 
 		client, err := s.ClientService.CreateClient(r.Context(), store.NewClient{
-			Name:    requestBody.Name.toString(),
-			Enabled: requestBody.Enabled,
+			Name:           requestBody.Name.toString(),
+			Enabled:        requestBody.Enabled,
+			AllowedOrigins: requestBody.AllowedOrigins,
 		})
 		if err != nil {
-			s.respondWithError(w, r, http.StatusUnprocessableEntity, err)
+			s.respondWithError(w, r, errorStatus(err), err)
 			return
 		}
 
+		setETag(w, client.Version)
 		s.respond(w, r, http.StatusCreated, client)
 	})
 }
@@ -57,18 +81,20 @@ func (s *Server) handleClientsGet() http.HandlerFunc {
 
 		client, err := s.ClientService.GetClient(r.Context(), id)
 		if err != nil {
-			s.respondWithError(w, r, http.StatusNotFound, err)
+			s.respondWithError(w, r, errorStatus(err), err)
 			return
 		}
 
+		setETag(w, client.Version)
 		s.respond(w, r, http.StatusOK, client)
 	})
 }
 
 func (s *Server) handleClientsUpdate() http.HandlerFunc {
 	type request struct {
-		Name    *nonEmptyString `json:"name"`
-		Enabled *bool           `json:"enabled"`
+		Name           *nonEmptyString `json:"name"`
+		Enabled        *bool           `json:"enabled"`
+		AllowedOrigins *originList     `json:"allowedOrigins"`
 	}
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -78,6 +104,12 @@ func (s *Server) handleClientsUpdate() http.HandlerFunc {
 			return
 		}
 
+		version, err := ifMatch(r)
+		if err != nil {
+			s.respondWithError(w, r, errorStatus(err), err)
+			return
+		}
+
 		var requestBody request
 		err = s.decode(r, &requestBody)
 		if err != nil {
@@ -85,15 +117,17 @@ func (s *Server) handleClientsUpdate() http.HandlerFunc {
 			return
 		}
 
-		client, err := s.ClientService.UpdateClient(r.Context(), id, store.ClientPatch{
-			Name:    (*string)(requestBody.Name),
-			Enabled: requestBody.Enabled,
+		client, err := s.ClientService.UpdateClient(r.Context(), id, version, store.ClientPatch{
+			Name:           (*string)(requestBody.Name),
+			Enabled:        requestBody.Enabled,
+			AllowedOrigins: (*[]string)(requestBody.AllowedOrigins),
 		})
 		if err != nil {
-			s.respondWithError(w, r, http.StatusUnprocessableEntity, err)
+			s.respondWithError(w, r, errorStatus(err), err)
 			return
 		}
 
+		setETag(w, client.Version)
 		s.respond(w, r, http.StatusOK, client)
 	})
 }
@@ -108,7 +142,7 @@ func (s *Server) handleClientsDelete() http.HandlerFunc {
 
 		err = s.ClientService.DeleteClient(r.Context(), id)
 		if err != nil {
-			s.respondWithError(w, r, http.StatusNotFound, err)
+			s.respondWithError(w, r, errorStatus(err), err)
 			return
 		}
 
@@ -116,6 +150,25 @@ func (s *Server) handleClientsDelete() http.HandlerFunc {
 	})
 }
 
+func (s *Server) handleClientsRestore() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := uuid.FromString(chi.URLParamFromCtx(r.Context(), "clientID"))
+		if err != nil {
+			s.respondWithError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		client, err := s.ClientService.RestoreClient(r.Context(), id)
+		if err != nil {
+			s.respondWithError(w, r, errorStatus(err), err)
+			return
+		}
+
+		setETag(w, client.Version)
+		s.respond(w, r, http.StatusOK, client)
+	})
+}
+
 func (s *Server) handleClientsAPIKeysGet() http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		id, err := uuid.FromString(chi.URLParamFromCtx(r.Context(), "clientID"))
@@ -126,7 +179,7 @@ func (s *Server) handleClientsAPIKeysGet() http.HandlerFunc {
 
 		keys, err := s.ClientService.ListClientAPIKeys(r.Context(), id)
 		if err != nil {
-			s.respondWithError(w, r, http.StatusNotFound, err)
+			s.respondWithError(w, r, errorStatus(err), err)
 			return
 		}
 
@@ -158,7 +211,7 @@ func (s *Server) handleClientsAPIKeysCreate() http.HandlerFunc {
 
 		key, err := s.ClientService.GenerateAPIKey(r.Context(), store.NewAPIKey{ClientID: id, Description: body.Description})
 		if err != nil {
-			s.respondWithError(w, r, http.StatusNotFound, err)
+			s.respondWithError(w, r, errorStatus(err), err)
 			return
 		}
 
@@ -182,7 +235,7 @@ func (s *Server) handleClientsAPIKeysDelete() http.HandlerFunc {
 
 		err = s.ClientService.DeleteClientAPIKey(r.Context(), clientID, keyID)
 		if err != nil {
-			s.respondWithError(w, r, http.StatusNotFound, err)
+			s.respondWithError(w, r, errorStatus(err), err)
 			return
 		}
 
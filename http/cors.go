@@ -0,0 +1,175 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// corsMaxAge is how long browsers may cache the result of a preflight request.
+const corsMaxAge = 10 * time.Minute
+
+// clientOriginsTTL is how long the origins allowed by clients are cached, so
+// that cross-origin requests don't each query the database. Changes to a
+// client's allowed origins can take this long to apply.
+const clientOriginsTTL = 30 * time.Second
+
+// The methods and headers that cross-origin requests may use. Only ETag needs
+// exposing, since the others that scripts read are safelisted.
+var (
+	corsAllowedMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	corsAllowedHeaders = []string{"Authorization", "Content-Type", DPoPHeaderName, "If-Match", APIKeyHeaderName, "X-Request-Id"}
+	corsExposedHeaders = []string{"ETag"}
+)
+
+var errOriginNotAllowed = errors.New("origin not allowed")
+
+// ParseOrigin checks that s is a browser origin, a scheme and host with an
+// optional port such as https://app.example.com, and returns it in the
+// lowercase form that browsers send.
+func ParseOrigin(s string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(s))
+	if err != nil {
+		return "", err
+	}
+
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("origin %q must have an http or https scheme and a host", s)
+	}
+
+	if u.User != nil || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("origin %q must only have a scheme, host and port", s)
+	}
+
+	return strings.ToLower(u.Scheme + "://" + u.Host), nil
+}
+
+// handleCORS lets browser apps on allowed origins call the API. An origin is
+// allowed when it's in AllowedOrigins or any enabled client's allowed origins.
+// Browser requests usually carry only the user's session, so there's no client
+// to check the origin against, and every allowed origin is trusted for every
+// route.
+// Requests from other origins are rejected, rather than just left without CORS
+// headers, so that they can't change anything with the user's session cookie.
+//
+// Responses to allowed origins include credentials, so the origin is echoed
+// back instead of using a wildcard, which browsers don't accept with cookies.
+func (s *Server) handleCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Responses differ by origin, so caches mustn't share them.
+		w.Header().Add("Vary", "Origin")
+
+		// Browsers also send the origin with same-origin POSTs.
+		if s.sameOrigin(r, origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		allowed, err := s.allowsOrigin(r, origin)
+		if err != nil {
+			s.respondWithError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		if !allowed {
+			s.respondWithError(w, r, http.StatusForbidden, errOriginNotAllowed)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+			w.Header().Set("Access-Control-Allow-Methods", strings.Join(corsAllowedMethods, ", "))
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join(corsAllowedHeaders, ", "))
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(corsMaxAge.Seconds())))
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		w.Header().Set("Access-Control-Expose-Headers", strings.Join(corsExposedHeaders, ", "))
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) allowsOrigin(r *http.Request, origin string) (bool, error) {
+	for _, allowed := range s.AllowedOrigins {
+		if strings.EqualFold(origin, allowed) {
+			return true, nil
+		}
+	}
+
+	origins, err := s.listClientOrigins(r.Context())
+	if err != nil {
+		return false, err
+	}
+
+	for _, allowed := range origins {
+		if strings.EqualFold(origin, allowed) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// originCache holds the origins allowed by clients, as of fetchedAt.
+type originCache struct {
+	mu        sync.Mutex
+	origins   []string
+	fetchedAt time.Time
+}
+
+// listClientOrigins returns the origins allowed by clients, only querying them
+// when the cached list is older than clientOriginsTTL. The lock is held while
+// querying so that concurrent requests share one query.
+func (s *Server) listClientOrigins(ctx context.Context) ([]string, error) {
+	c := &s.clientOrigins
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.fetchedAt.IsZero() && time.Since(c.fetchedAt) < clientOriginsTTL {
+		return c.origins, nil
+	}
+
+	origins, err := s.ClientService.ListAllowedOrigins(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.origins = origins
+	c.fetchedAt = time.Now()
+
+	return origins, nil
+}
+
+// sameOrigin reports whether the request's origin is the one clients reach the
+// server at. Without PublicURL, only the host is compared, since a proxy that
+// terminates TLS leaves the server seeing http while browsers send https.
+func (s *Server) sameOrigin(r *http.Request, origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	if s.PublicURL == nil {
+		return strings.EqualFold(u.Host, r.Host)
+	}
+
+	return strings.EqualFold(u.Scheme, s.PublicURL.Scheme) && strings.EqualFold(u.Host, s.PublicURL.Host)
+}
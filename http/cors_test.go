@@ -0,0 +1,252 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// originClientService allows a fixed set of origins, and counts how often
+// they're listed. Only the methods used by the CORS middleware are implemented.
+type originClientService struct {
+	ClientService
+	origins []string
+	lists   int
+}
+
+func (s *originClientService) ListAllowedOrigins(ctx context.Context) ([]string, error) {
+	s.lists++
+	return s.origins, nil
+}
+
+func Test_handleCORS(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		origin      string
+		preflight   bool
+		wantStatus  int
+		wantAllowed bool
+		wantMethods bool
+	}{
+		{
+			name:       "No origin",
+			method:     http.MethodPost,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Same origin",
+			method:     http.MethodPost,
+			origin:     "https://auth.example.com",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:        "Origin allowed by config",
+			method:      http.MethodPost,
+			origin:      "https://admin.example.com",
+			wantStatus:  http.StatusOK,
+			wantAllowed: true,
+		},
+		{
+			name:        "Origin allowed by client",
+			method:      http.MethodGet,
+			origin:      "https://app.example.com",
+			wantStatus:  http.StatusOK,
+			wantAllowed: true,
+		},
+		{
+			name:        "Preflight",
+			method:      http.MethodOptions,
+			origin:      "https://app.example.com",
+			preflight:   true,
+			wantStatus:  http.StatusNoContent,
+			wantAllowed: true,
+			wantMethods: true,
+		},
+		{
+			name:       "Disallowed origin",
+			method:     http.MethodPost,
+			origin:     "https://evil.example.com",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Disallowed preflight",
+			method:     http.MethodOptions,
+			origin:     "https://evil.example.com",
+			preflight:  true,
+			wantStatus: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				AllowedOrigins: []string{"https://admin.example.com"},
+				ClientService:  &originClientService{origins: []string{"https://app.example.com"}},
+			}
+
+			handler := s.handleCORS(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			r := httptest.NewRequest(tt.method, "https://auth.example.com/api/v1/auth/login", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if tt.preflight {
+				r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+				r.Header.Set("Access-Control-Request-Headers", "content-type")
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			h := w.Header()
+			if allowed := h.Get("Access-Control-Allow-Origin"); tt.wantAllowed && allowed != tt.origin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", allowed, tt.origin)
+			} else if !tt.wantAllowed && allowed != "" {
+				t.Errorf("Access-Control-Allow-Origin = %q, want none", allowed)
+			}
+
+			if got := h.Get("Access-Control-Allow-Credentials"); tt.wantAllowed && got != "true" {
+				t.Errorf("Access-Control-Allow-Credentials = %q, want true", got)
+			}
+
+			if got := h.Get("Access-Control-Allow-Methods"); tt.wantMethods && got == "" {
+				t.Error("Access-Control-Allow-Methods is missing from the preflight response")
+			} else if !tt.wantMethods && got != "" {
+				t.Errorf("Access-Control-Allow-Methods = %q, want none", got)
+			}
+
+			if tt.origin != "" && h.Get("Vary") == "" {
+				t.Error("Vary is missing, want Origin")
+			}
+		})
+	}
+}
+
+func Test_handleCORS_sameOrigin(t *testing.T) {
+	tests := []struct {
+		name       string
+		publicURL  string
+		url        string
+		origin     string
+		wantStatus int
+	}{
+		{
+			name:       "Behind a proxy that terminates TLS",
+			url:        "http://auth.example.com/api/v1/auth/login",
+			origin:     "https://auth.example.com",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Other host",
+			url:        "http://auth.example.com/api/v1/auth/login",
+			origin:     "https://app.example.com",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Public URL",
+			publicURL:  "https://auth.example.com",
+			url:        "http://10.0.0.1:8080/api/v1/auth/login",
+			origin:     "https://auth.example.com",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Public URL with another scheme",
+			publicURL:  "https://auth.example.com",
+			url:        "http://10.0.0.1:8080/api/v1/auth/login",
+			origin:     "http://auth.example.com",
+			wantStatus: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{ClientService: &originClientService{}}
+			if tt.publicURL != "" {
+				s.PublicURL, _ = url.Parse(tt.publicURL)
+			}
+
+			handler := s.handleCORS(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			r := httptest.NewRequest(http.MethodPost, tt.url, nil)
+			r.Header.Set("Origin", tt.origin)
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			if allowed := w.Header().Get("Access-Control-Allow-Origin"); allowed != "" {
+				t.Errorf("Access-Control-Allow-Origin = %q, want none", allowed)
+			}
+		})
+	}
+}
+
+func Test_handleCORS_cachesClientOrigins(t *testing.T) {
+	clients := &originClientService{origins: []string{"https://app.example.com"}}
+	s := &Server{ClientService: clients}
+	handler := s.handleCORS(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, origin := range []string{"https://app.example.com", "https://evil.example.com", "https://app.example.com"} {
+		r := httptest.NewRequest(http.MethodPost, "https://auth.example.com/api/v1/auth/login", nil)
+		r.Header.Set("Origin", origin)
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	if clients.lists != 1 {
+		t.Errorf("origins listed %d times, want 1", clients.lists)
+	}
+
+	// Once the cache expires, the origins are listed again.
+	s.clientOrigins.fetchedAt = time.Now().Add(-clientOriginsTTL)
+
+	r := httptest.NewRequest(http.MethodPost, "https://auth.example.com/api/v1/auth/login", nil)
+	r.Header.Set("Origin", "https://app.example.com")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	if clients.lists != 2 {
+		t.Errorf("origins listed %d times after the cache expired, want 2", clients.lists)
+	}
+}
+
+func TestParseOrigin(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "https://app.example.com", want: "https://app.example.com"},
+		{in: "HTTP://App.Example.com:8080/", want: "http://app.example.com:8080"},
+		{in: "https://app.example.com/login", wantErr: true},
+		{in: "https://app.example.com?x=1", wantErr: true},
+		{in: "ftp://app.example.com", wantErr: true},
+		{in: "app.example.com", wantErr: true},
+		{in: "*", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseOrigin(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseOrigin() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("ParseOrigin() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var (
+	// errIfMatchRequired is returned for updates without an If-Match header.
+	// Updates must name the version they were based on, so that they can't
+	// overwrite changes they haven't seen.
+	errIfMatchRequired = errors.New("If-Match header with the resource's ETag is required")
+	errIfMatchInvalid  = errors.New("If-Match must be a single ETag returned by a previous response")
+)
+
+// etag returns the entity tag of a version of a resource.
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+func setETag(w http.ResponseWriter, version int64) {
+	w.Header().Set("ETag", etag(version))
+}
+
+// ifMatch returns the version of the resource named by the request's If-Match
+// header. The wildcard is rejected like a missing header, since it would match
+// any version.
+func ifMatch(r *http.Request) (int64, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return 0, errIfMatchRequired
+	}
+
+	tag, ok := strings.CutPrefix(header, `"`)
+	if !ok {
+		return 0, errIfMatchInvalid
+	}
+
+	tag, ok = strings.CutSuffix(tag, `"`)
+	if !ok {
+		return 0, errIfMatchInvalid
+	}
+
+	version, err := strconv.ParseInt(tag, 10, 64)
+	if err != nil {
+		return 0, errIfMatchInvalid
+	}
+
+	return version, nil
+}
@@ -0,0 +1,42 @@
+package http
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_ifMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		want    int64
+		wantErr error
+	}{
+		{name: "ETag", header: etag(3), want: 3},
+		{name: "Surrounding whitespace", header: ` "3" `, want: 3},
+		{name: "Missing", header: "", wantErr: errIfMatchRequired},
+		{name: "Wildcard", header: "*", wantErr: errIfMatchRequired},
+		{name: "Weak ETag", header: `W/"3"`, wantErr: errIfMatchInvalid},
+		{name: "Unquoted", header: "3", wantErr: errIfMatchInvalid},
+		{name: "Several ETags", header: `"3", "4"`, wantErr: errIfMatchInvalid},
+		{name: "Not a version", header: `"abc"`, wantErr: errIfMatchInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("PATCH", "/api/v1/me", nil)
+			if tt.header != "" {
+				r.Header.Set("If-Match", tt.header)
+			}
+
+			got, err := ifMatch(r)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ifMatch() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("ifMatch() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
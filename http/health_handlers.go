@@ -0,0 +1,31 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/ninth-realm/heimdall/health"
+)
+
+// handleHealthz reports that the process is up and serving requests. It
+// doesn't check any dependencies, so that an outage elsewhere doesn't get the
+// server restarted.
+func (s *Server) handleHealthz() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.respond(w, r, http.StatusOK, health.Report{Status: health.StatusOK})
+	})
+}
+
+// handleReadyz reports whether the server's dependencies are healthy enough
+// for it to receive traffic, with the outcome of each check.
+func (s *Server) handleReadyz() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := s.HealthService.Ready(r.Context())
+
+		status := http.StatusOK
+		if report.Status != health.StatusOK {
+			status = http.StatusServiceUnavailable
+		}
+
+		s.respond(w, r, status, report)
+	})
+}
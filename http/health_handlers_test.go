@@ -0,0 +1,79 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ninth-realm/heimdall/health"
+)
+
+type healthService health.Report
+
+func (s healthService) Ready(ctx context.Context) health.Report {
+	return health.Report(s)
+}
+
+func Test_healthRoutes(t *testing.T) {
+	failing := health.Report{Status: health.StatusFailing, Checks: map[string]health.Result{
+		"database": {Status: health.StatusFailing, Error: "database unreachable"},
+	}}
+
+	tests := []struct {
+		name       string
+		path       string
+		report     health.Report
+		wantStatus int
+		want       health.Report
+	}{
+		{
+			name:       "Live",
+			path:       "/healthz",
+			report:     failing,
+			wantStatus: http.StatusOK,
+			want:       health.Report{Status: health.StatusOK},
+		},
+		{
+			name:       "Ready",
+			path:       "/readyz",
+			report:     health.Report{Status: health.StatusOK, Checks: map[string]health.Result{"database": {Status: health.StatusOK}}},
+			wantStatus: http.StatusOK,
+			want:       health.Report{Status: health.StatusOK, Checks: map[string]health.Result{"database": {Status: health.StatusOK}}},
+		},
+		{
+			name:       "Not ready",
+			path:       "/readyz",
+			report:     failing,
+			wantStatus: http.StatusServiceUnavailable,
+			want:       failing,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// No credentials are sent, since probes can't authenticate.
+			s := NewServer()
+			s.HealthService = healthService(tt.report)
+
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			var body struct {
+				Response health.Report `json:"response"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.want, body.Response); diff != "" {
+				t.Errorf("response mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
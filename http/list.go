@@ -0,0 +1,103 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ninth-realm/heimdall/store"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 100
+)
+
+// parsePagination reads the limit and cursor query parameters. The limit
+// defaults to defaultPageLimit and may not exceed maxPageLimit.
+func parsePagination(q url.Values) (store.Pagination, error) {
+	page := store.Pagination{Limit: defaultPageLimit}
+
+	if limit := q.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > maxPageLimit {
+			return store.Pagination{}, fmt.Errorf("limit must be between 1 and %d", maxPageLimit)
+		}
+		page.Limit = n
+	}
+
+	if cursor := q.Get("cursor"); cursor != "" {
+		c, err := store.DecodeCursor(cursor)
+		if err != nil {
+			return store.Pagination{}, err
+		}
+		page.After = &c
+	}
+
+	return page, nil
+}
+
+// parseSort reads the sort query parameter, sorting by creation time when it's
+// missing.
+func parseSort(q url.Values) store.Sort {
+	if sort := q.Get("sort"); sort != "" {
+		return store.ParseSort(sort)
+	}
+
+	return store.Sort{Field: store.SortByCreatedAt}
+}
+
+// queryString returns the named query parameter, or nil if it's missing.
+func queryString(q url.Values, key string) *string {
+	if !q.Has(key) {
+		return nil
+	}
+
+	v := q.Get(key)
+	return &v
+}
+
+// queryBool parses the named query parameter as a boolean, returning nil if
+// it's missing.
+func queryBool(q url.Values, key string) (*bool, error) {
+	if !q.Has(key) {
+		return nil, nil
+	}
+
+	v, err := strconv.ParseBool(q.Get(key))
+	if err != nil {
+		return nil, fmt.Errorf("%s must be true or false", key)
+	}
+
+	return &v, nil
+}
+
+// queryTime parses the named query parameter as an RFC 3339 timestamp,
+// returning nil if it's missing.
+func queryTime(q url.Values, key string) (*time.Time, error) {
+	if !q.Has(key) {
+		return nil, nil
+	}
+
+	v, err := time.Parse(time.RFC3339, q.Get(key))
+	if err != nil {
+		return nil, fmt.Errorf("%s must be an RFC 3339 timestamp", key)
+	}
+
+	return &v, nil
+}
+
+// nextLink returns a link to the page starting at next, keeping the rest of the
+// request's query parameters. It returns an empty string on the last page.
+func nextLink(r *http.Request, next *store.Cursor) string {
+	if next == nil {
+		return ""
+	}
+
+	q := r.URL.Query()
+	q.Set("cursor", next.Encode())
+
+	return (&url.URL{Path: r.URL.Path, RawQuery: q.Encode()}).String()
+}
@@ -0,0 +1,62 @@
+package http
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/ninth-realm/heimdall/store"
+)
+
+func Test_parsePagination(t *testing.T) {
+	cursor := store.Cursor{Sort: "createdAt", Value: "2023-01-01T00:00:00Z", ID: uuid.Must(uuid.NewV4())}
+
+	tests := []struct {
+		name      string
+		query     string
+		wantLimit int
+		wantAfter bool
+		wantErr   bool
+	}{
+		{name: "Defaults", query: "", wantLimit: defaultPageLimit},
+		{name: "Limit", query: "limit=10", wantLimit: 10},
+		{name: "Cursor", query: "cursor=" + cursor.Encode(), wantLimit: defaultPageLimit, wantAfter: true},
+		{name: "Limit too small", query: "limit=0", wantErr: true},
+		{name: "Limit too large", query: "limit=101", wantErr: true},
+		{name: "Limit not a number", query: "limit=ten", wantErr: true},
+		{name: "Invalid cursor", query: "cursor=nope", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, _ := url.ParseQuery(tt.query)
+
+			got, err := parsePagination(q)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePagination() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got.Limit != tt.wantLimit {
+				t.Errorf("parsePagination() limit = %d, want %d", got.Limit, tt.wantLimit)
+			}
+
+			if (got.After != nil) != tt.wantAfter {
+				t.Errorf("parsePagination() after = %v, want cursor %t", got.After, tt.wantAfter)
+			}
+		})
+	}
+}
+
+func Test_nextLink(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/v1/users?limit=2&sort=-createdAt&cursor=old", nil)
+	cursor := store.Cursor{Sort: "-createdAt", Value: "2023-01-01T00:00:00Z", ID: uuid.Must(uuid.NewV4())}
+
+	if got := nextLink(r, nil); got != "" {
+		t.Errorf("nextLink() on last page = %q, want empty", got)
+	}
+
+	want := "/api/v1/users?cursor=" + cursor.Encode() + "&limit=2&sort=-createdAt"
+	if got := nextLink(r, &cursor); got != want {
+		t.Errorf("nextLink() = %q, want %q", got, want)
+	}
+}
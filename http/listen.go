@@ -0,0 +1,94 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// ListenConfig sets the timeouts and limits of a listener's connections, so
+// that slow or idle clients can't hold on to them indefinitely. Zero values
+// mean no limit, except for MaxHeaderBytes, which falls back to
+// http.DefaultMaxHeaderBytes.
+type ListenConfig struct {
+	// ReadHeaderTimeout is how long a client has to send a request's headers.
+	ReadHeaderTimeout time.Duration
+	// ReadTimeout is how long a client has to send a whole request, including
+	// its body.
+	ReadTimeout time.Duration
+	// WriteTimeout is how long a request has to be handled and its response
+	// written, from the end of its headers.
+	WriteTimeout time.Duration
+	// IdleTimeout is how long a keep-alive connection is kept open waiting for
+	// the next request.
+	IdleTimeout time.Duration
+	// MaxHeaderBytes is the max size of a request's headers.
+	MaxHeaderBytes int
+	// ShutdownTimeout is how long in-flight requests have to finish once a
+	// shutdown starts, before their connections are closed.
+	ShutdownTimeout time.Duration
+	// TLS serves HTTPS when set, using the certificate from its
+	// GetCertificate or Certificates. Plain HTTP is served otherwise.
+	TLS *tls.Config
+}
+
+// Serve serves handler on addr until ctx is done. It then stops accepting new
+// connections and waits for in-flight requests to finish, up to the config's
+// ShutdownTimeout.
+func Serve(ctx context.Context, addr string, handler http.Handler, config ListenConfig, logger *slog.Logger) error {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: config.ReadHeaderTimeout,
+		ReadTimeout:       config.ReadTimeout,
+		WriteTimeout:      config.WriteTimeout,
+		IdleTimeout:       config.IdleTimeout,
+		MaxHeaderBytes:    config.MaxHeaderBytes,
+		TLSConfig:         config.TLS,
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		if config.TLS != nil {
+			logger.Info("Server listening", "address", addr, "tls", true)
+			// The certificate comes from the TLS config rather than files.
+			errs <- srv.ListenAndServeTLS("", "")
+			return
+		}
+
+		logger.Info("Server listening", "address", addr)
+		errs <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	logger.Info("Draining requests", "address", addr, "timeout", config.ShutdownTimeout.String())
+
+	// The server's context is already done, so the shutdown gets its own.
+	shutdownCtx := context.Background()
+	if config.ShutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		shutdownCtx, cancel = context.WithTimeout(shutdownCtx, config.ShutdownTimeout)
+		defer cancel()
+	}
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		srv.Close()
+		return fmt.Errorf("draining requests on %s: %w", addr, err)
+	}
+
+	if err := <-errs; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package http
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestServe_drainsRequests(t *testing.T) {
+	addr := freeAddr(t)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- Serve(ctx, addr, handler, ListenConfig{ShutdownTimeout: 5 * time.Second}, logger)
+	}()
+
+	responses := make(chan int, 1)
+	go func() {
+		res, err := getWhenListening(addr)
+		if err != nil {
+			t.Errorf("GET error = %v", err)
+			responses <- 0
+			return
+		}
+		res.Body.Close()
+		responses <- res.StatusCode
+	}()
+
+	<-started
+	cancel()
+
+	// The in-flight request must be allowed to finish before Serve returns.
+	select {
+	case err := <-served:
+		t.Fatalf("Serve() returned %v before the request finished", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+
+	if status := <-responses; status != http.StatusTeapot {
+		t.Errorf("status = %d, want %d", status, http.StatusTeapot)
+	}
+
+	if err := <-served; err != nil {
+		t.Errorf("Serve() error = %v", err)
+	}
+}
+
+func TestServe_shutdownTimeout(t *testing.T) {
+	addr := freeAddr(t)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- Serve(ctx, addr, handler, ListenConfig{ShutdownTimeout: 10 * time.Millisecond}, logger)
+	}()
+
+	go func() {
+		if res, err := getWhenListening(addr); err == nil {
+			res.Body.Close()
+		}
+	}()
+
+	<-started
+	cancel()
+
+	if err := <-served; err == nil {
+		t.Error("Serve() error = nil, want the shutdown to time out")
+	}
+}
+
+// freeAddr finds a local address that nothing is listening on.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer l.Close()
+
+	return l.Addr().String()
+}
+
+// getWhenListening retries the request until the server has started.
+func getWhenListening(addr string) (*http.Response, error) {
+	var err error
+	for i := 0; i < 100; i++ {
+		var res *http.Response
+		res, err = http.Get("http://" + addr)
+		if err == nil {
+			return res, nil
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return nil, err
+}
@@ -0,0 +1,86 @@
+package http
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/gofrs/uuid/v5"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const accessLogContextKey contextKey = "accessLog"
+
+// accessLog collects details that are only known deeper in the middleware
+// chain, such as the caller, for the request's access log line.
+type accessLog struct {
+	principal principal
+}
+
+// recordPrincipal adds the caller to the request's access log line, if it's
+// being logged.
+func recordPrincipal(ctx context.Context, p principal) {
+	if l, ok := ctx.Value(accessLogContextKey).(*accessLog); ok {
+		l.principal = p
+	}
+}
+
+// logRequest writes an access log line for every request once it has been
+// handled. Only the path is logged, since query strings may contain secrets.
+func (s *Server) logRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		entry := &accessLog{}
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r.WithContext(context.WithValue(r.Context(), accessLogContextKey, entry)))
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		attrs := []slog.Attr{
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.String("route", routePattern(r)),
+			slog.Int("status", status),
+			slog.Int("bytes", ww.BytesWritten()),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("remote_ip", remoteIP(r)),
+			slog.String("user_agent", r.UserAgent()),
+		}
+
+		if p := entry.principal; p.ClientID != uuid.Nil {
+			attrs = append(attrs, slog.Group("principal", "client_id", p.ClientID))
+		} else if p.UserID != uuid.Nil {
+			attrs = append(attrs, slog.Group("principal", "user_id", p.UserID, "session_id", p.SessionID))
+		}
+
+		if span := trace.SpanContextFromContext(r.Context()); span.IsValid() {
+			attrs = append(attrs, slog.String("trace_id", span.TraceID().String()))
+		}
+
+		level := slog.LevelInfo
+		if status >= 500 {
+			level = slog.LevelError
+		}
+
+		s.logger().LogAttrs(r.Context(), level, "Request handled", attrs...)
+	})
+}
+
+// routePattern is the pattern of the route that matched the request, or an
+// empty string if none did.
+func routePattern(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		return ""
+	}
+
+	return rctx.RoutePattern()
+}
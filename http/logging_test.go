@@ -0,0 +1,51 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gofrs/uuid/v5"
+)
+
+func TestServer_logRequest(t *testing.T) {
+	var buf bytes.Buffer
+	s := &Server{Logger: slog.New(slog.NewJSONHandler(&buf, nil))}
+
+	userID := uuid.Must(uuid.NewV4())
+	r := chi.NewRouter()
+	r.Use(s.logRequest)
+	r.With(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(withPrincipal(r.Context(), principal{UserID: userID})))
+		})
+	}).Get("/api/v1/users/{userID}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/users/123?token=secret", nil))
+
+	var line struct {
+		Path      string `json:"path"`
+		Route     string `json:"route"`
+		Status    int    `json:"status"`
+		Principal struct {
+			UserID string `json:"user_id"`
+		} `json:"principal"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("decoding access log line %q: %v", buf.String(), err)
+	}
+
+	if line.Path != "/api/v1/users/123" || line.Route != "/api/v1/users/{userID}" || line.Status != http.StatusNotFound {
+		t.Errorf("access log = %s, want the path without its query, the route and a 404", buf.String())
+	}
+
+	if line.Principal.UserID != userID.String() {
+		t.Errorf("access log principal = %s, want %s", line.Principal.UserID, userID)
+	}
+}
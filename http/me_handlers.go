@@ -0,0 +1,162 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gofrs/uuid/v5"
+	"github.com/ninth-realm/heimdall/store"
+	"github.com/ninth-realm/heimdall/user"
+)
+
+func (s *Server) handleMeGet() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := principalFromContext(r.Context())
+
+		user, err := s.UserService.GetUser(r.Context(), p.UserID)
+		if err != nil {
+			s.respondWithError(w, r, errorStatus(err), err)
+			return
+		}
+
+		setETag(w, user.Version)
+		s.respond(w, r, http.StatusOK, user)
+	})
+}
+
+func (s *Server) handleMeUpdate() http.HandlerFunc {
+	type request struct {
+		FirstName *nonEmptyString `json:"firstName"`
+		LastName  *nonEmptyString `json:"lastName"`
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := principalFromContext(r.Context())
+
+		version, err := ifMatch(r)
+		if err != nil {
+			s.respondWithError(w, r, errorStatus(err), err)
+			return
+		}
+
+		var requestBody request
+		err = s.decode(r, &requestBody)
+		if err != nil {
+			s.respondWithError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		user, err := s.UserService.UpdateUser(r.Context(), p.UserID, version, store.UserPatch{
+			FirstName: (*string)(requestBody.FirstName),
+			LastName:  (*string)(requestBody.LastName),
+		})
+		if err != nil {
+			s.respondWithError(w, r, errorStatus(err), err)
+			return
+		}
+
+		setETag(w, user.Version)
+		s.respond(w, r, http.StatusOK, user)
+	})
+}
+
+func (s *Server) handleMePasswordUpdate() http.HandlerFunc {
+	type request struct {
+		CurrentPassword nonEmptyString `json:"currentPassword"`
+		NewPassword     nonEmptyString `json:"newPassword"`
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := principalFromContext(r.Context())
+
+		var requestBody request
+		err := s.decode(r, &requestBody)
+		if err != nil {
+			s.respondWithError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		err = s.UserService.ChangePassword(
+			r.Context(),
+			p.UserID,
+			requestBody.CurrentPassword.toString(),
+			requestBody.NewPassword.toString(),
+		)
+		if errors.Is(err, user.ErrIncorrectPassword) {
+			s.respondWithError(w, r, http.StatusForbidden, err)
+			return
+		} else if err != nil {
+			s.respondWithError(w, r, errorStatus(err), err)
+			return
+		}
+
+		// Changing a password should kick out anyone else who may have been
+		// using the old one.
+		err = s.AuthService.RevokeUserSessions(r.Context(), p.UserID, p.SessionID)
+		if err != nil {
+			s.respondWithError(w, r, errorStatus(err), err)
+			return
+		}
+
+		s.respond(w, r, http.StatusNoContent, nil)
+	})
+}
+
+func (s *Server) handleMeSessionsList() http.HandlerFunc {
+	type session struct {
+		store.Session
+		Current bool `json:"current"`
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := principalFromContext(r.Context())
+
+		sessions, err := s.AuthService.ListUserSessions(r.Context(), p.UserID)
+		if err != nil {
+			s.respondWithError(w, r, errorStatus(err), err)
+			return
+		}
+
+		res := make([]session, len(sessions))
+		for i, sess := range sessions {
+			res[i] = session{Session: sess, Current: sess.ID == p.SessionID}
+		}
+
+		s.respond(w, r, http.StatusOK, res)
+	})
+}
+
+func (s *Server) handleMeSessionsRevoke() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := principalFromContext(r.Context())
+
+		err := s.AuthService.RevokeUserSessions(r.Context(), p.UserID, p.SessionID)
+		if err != nil {
+			s.respondWithError(w, r, errorStatus(err), err)
+			return
+		}
+
+		s.respond(w, r, http.StatusNoContent, nil)
+	})
+}
+
+func (s *Server) handleMeSessionsDelete() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := principalFromContext(r.Context())
+
+		sessionID, err := uuid.FromString(chi.URLParamFromCtx(r.Context(), "sessionID"))
+		if err != nil {
+			s.respondWithError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		err = s.AuthService.RevokeUserSession(r.Context(), p.UserID, sessionID)
+		if err != nil {
+			s.respondWithError(w, r, errorStatus(err), err)
+			return
+		}
+
+		s.respond(w, r, http.StatusNoContent, nil)
+	})
+}
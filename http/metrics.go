@@ -0,0 +1,45 @@
+package http
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/ninth-realm/heimdall/metrics"
+)
+
+// observeRequest records the latency and status of every request by the route
+// pattern that handled it. Requests that don't match any route, or that use an
+// unknown method, are grouped together so that they can't create new series.
+func observeRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		route := routePattern(r)
+		if route == "" {
+			route = "unmatched"
+		}
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		metrics.ObserveHTTPRequest(methodLabel(r.Method), route, status, time.Since(start))
+	})
+}
+
+// methodLabel bounds the method label in the same way as the route, since
+// clients can send any method they like.
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions, http.MethodHead:
+		return method
+	default:
+		return "other"
+	}
+}
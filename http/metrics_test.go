@@ -0,0 +1,24 @@
+package http
+
+import "testing"
+
+func Test_methodLabel(t *testing.T) {
+	tests := []struct {
+		method string
+		want   string
+	}{
+		{method: "GET", want: "GET"},
+		{method: "DELETE", want: "DELETE"},
+		{method: "OPTIONS", want: "OPTIONS"},
+		{method: "get", want: "other"},
+		{method: "PROPFIND", want: "other"},
+		{method: "X-RANDOM-1234", want: "other"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			if got := methodLabel(tt.method); got != tt.want {
+				t.Errorf("methodLabel() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
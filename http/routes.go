@@ -1,22 +1,51 @@
 package http
 
 func (s *Server) loadRoutes() {
+	s.Router.Get("/healthz", s.handleHealthz())
+	s.Router.Get("/readyz", s.handleReadyz())
+
 	s.Router.With(s.authenticateRoute).Get("/api/v1/users", s.handleUsersList())
-	s.Router.With(s.authenticateRoute).Post("/api/v1/users", s.handleUsersCreate())
+	s.Router.With(s.authenticateRoute, s.requireRecentAuth).Post("/api/v1/users", s.handleUsersCreate())
+	s.Router.With(s.authenticateRoute).Get("/api/v1/users/search", s.handleUsersSearch())
 	s.Router.With(s.authenticateRoute).Get("/api/v1/users/{userID}", s.handleUsersGet())
-	s.Router.With(s.authenticateRoute).Patch("/api/v1/users/{userID}", s.handleUsersUpdate())
-	s.Router.With(s.authenticateRoute).Delete("/api/v1/users/{userID}", s.handleUsersDelete())
+	s.Router.With(s.authenticateRoute, s.requireRecentAuth).Patch("/api/v1/users/{userID}", s.handleUsersUpdate())
+	s.Router.With(s.authenticateRoute, s.requireRecentAuth).Delete("/api/v1/users/{userID}", s.handleUsersDelete())
+	s.Router.With(s.authenticateRoute, s.requireRecentAuth).Post("/api/v1/users/{userID}/restore", s.handleUsersRestore())
+	s.Router.With(s.authenticateRoute).Get("/api/v1/users/{userID}/sessions", s.handleUsersSessionsList())
+	s.Router.With(s.authenticateRoute, s.requireRecentAuth).Delete("/api/v1/users/{userID}/sessions", s.handleUsersSessionsRevoke())
+	s.Router.With(s.authenticateRoute, s.requireRecentAuth).Delete("/api/v1/users/{userID}/sessions/{sessionID}", s.handleUsersSessionsDelete())
 
 	s.Router.With(s.authenticateRoute).Get("/api/v1/clients", s.handleClientsList())
-	s.Router.With(s.authenticateRoute).Post("/api/v1/clients", s.handleClientsCreate())
+	s.Router.With(s.authenticateRoute, s.requireRecentAuth).Post("/api/v1/clients", s.handleClientsCreate())
 	s.Router.With(s.authenticateRoute).Get("/api/v1/clients/{clientID}", s.handleClientsGet())
-	s.Router.With(s.authenticateRoute).Patch("/api/v1/clients/{clientID}", s.handleClientsUpdate())
-	s.Router.With(s.authenticateRoute).Delete("/api/v1/clients/{clientID}", s.handleClientsDelete())
+	s.Router.With(s.authenticateRoute, s.requireRecentAuth).Patch("/api/v1/clients/{clientID}", s.handleClientsUpdate())
+	s.Router.With(s.authenticateRoute, s.requireRecentAuth).Delete("/api/v1/clients/{clientID}", s.handleClientsDelete())
+	s.Router.With(s.authenticateRoute, s.requireRecentAuth).Post("/api/v1/clients/{clientID}/restore", s.handleClientsRestore())
 	s.Router.With(s.authenticateRoute).Get("/api/v1/clients/{clientID}/api-keys", s.handleClientsAPIKeysGet())
-	s.Router.With(s.authenticateRoute).Post("/api/v1/clients/{clientID}/api-keys", s.handleClientsAPIKeysCreate())
-	s.Router.With(s.authenticateRoute).Delete("/api/v1/clients/{clientID}/api-keys/{keyID}", s.handleClientsAPIKeysDelete())
+	s.Router.With(s.authenticateRoute, s.requireRecentAuth).Post("/api/v1/clients/{clientID}/api-keys", s.handleClientsAPIKeysCreate())
+	s.Router.With(s.authenticateRoute, s.requireRecentAuth).Delete("/api/v1/clients/{clientID}/api-keys/{keyID}", s.handleClientsAPIKeysDelete())
+
+	s.Router.With(s.requireSession).Get("/api/v1/me", s.handleMeGet())
+	s.Router.With(s.requireSession).Patch("/api/v1/me", s.handleMeUpdate())
+	s.Router.With(s.requireSession, s.requireRecentAuth).Put("/api/v1/me/password", s.handleMePasswordUpdate())
+	s.Router.With(s.requireSession).Get("/api/v1/me/sessions", s.handleMeSessionsList())
+	s.Router.With(s.requireSession, s.requireRecentAuth).Delete("/api/v1/me/sessions", s.handleMeSessionsRevoke())
+	s.Router.With(s.requireSession, s.requireRecentAuth).Delete("/api/v1/me/sessions/{sessionID}", s.handleMeSessionsDelete())
+
+	s.Router.With(s.authenticateRoute).Get("/api/v1/audit-events", s.handleAuditEventsList())
+	s.Router.With(s.authenticateRoute).Get("/api/v1/audit-events/verify", s.handleAuditEventsVerify())
+
+	s.Router.With(s.authenticateRoute).Get("/api/v1/webhooks", s.handleWebhooksList())
+	s.Router.With(s.authenticateRoute, s.requireRecentAuth).Post("/api/v1/webhooks", s.handleWebhooksCreate())
+	s.Router.With(s.authenticateRoute).Get("/api/v1/webhooks/{webhookID}", s.handleWebhooksGet())
+	s.Router.With(s.authenticateRoute, s.requireRecentAuth).Patch("/api/v1/webhooks/{webhookID}", s.handleWebhooksUpdate())
+	s.Router.With(s.authenticateRoute, s.requireRecentAuth).Delete("/api/v1/webhooks/{webhookID}", s.handleWebhooksDelete())
+	s.Router.With(s.authenticateRoute).Get("/api/v1/webhooks/{webhookID}/deliveries", s.handleWebhooksDeliveriesList())
+	s.Router.With(s.authenticateRoute).Get("/api/v1/webhooks/{webhookID}/deliveries/{deliveryID}", s.handleWebhooksDeliveriesGet())
 
 	s.Router.Post("/api/v1/auth/login", s.handleAuthLogin())
 	s.Router.Post("/api/v1/auth/logout", s.handleAuthLogout())
+	s.Router.With(s.requireSession).Post("/api/v1/auth/reauthenticate", s.handleAuthReauthenticate())
 	s.Router.With(s.authenticateRoute).Post("/api/v1/auth/introspect", s.handleAuthIntrospect())
+	s.Router.With(s.requireClient).Post("/api/v1/auth/token", s.handleAuthToken())
 }
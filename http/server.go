@@ -2,22 +2,28 @@ package http
 
 import (
 	"context"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/gofrs/uuid/v5"
-	"github.com/mattmeyers/level"
+	"github.com/ninth-realm/heimdall/audit"
 	"github.com/ninth-realm/heimdall/auth"
+	"github.com/ninth-realm/heimdall/health"
 	"github.com/ninth-realm/heimdall/store"
+	"github.com/ninth-realm/heimdall/webhook"
 )
 
 type Server struct {
-	Logger level.Logger
+	Logger *slog.Logger
 	Router chi.Router
 
 	// DisableAuth skips all configured auth checks on all routes. This setting
@@ -25,25 +31,58 @@ type Server struct {
 	// to be created.
 	DisableAuth bool
 
-	UserService   UserService
-	ClientService ClientService
-	AuthService   AuthService
+	// ReauthenticationWindow is how recently a user logged in with a remember
+	// me session must have entered their password to perform sensitive
+	// operations, such as changing their password.
+	ReauthenticationWindow time.Duration
+
+	// Listen sets the timeouts and limits of the server's connections.
+	Listen ListenConfig
+
+	// PublicURL is the scheme and host that clients reach the server at, when
+	// a proxy in front of it changes them. It's used to check the URLs in DPoP
+	// proofs, and to recognise same-origin browser requests.
+	PublicURL *url.URL
+
+	// AllowedOrigins are browser origins allowed to call the API cross-origin,
+	// on top of those allowed by clients.
+	AllowedOrigins []string
+
+	// SessionCookieSameSite is the SameSite attribute of the session cookie.
+	// It defaults to Lax, which stops the cookie from being sent by browser
+	// apps on other sites, even when their origin is allowed.
+	SessionCookieSameSite http.SameSite
+
+	// clientOrigins caches the origins allowed by clients.
+	clientOrigins originCache
+
+	UserService    UserService
+	ClientService  ClientService
+	AuthService    AuthService
+	AuditService   AuditService
+	WebhookService WebhookService
+	HealthService  HealthService
 }
 
 type UserService interface {
-	ListUsers(ctx context.Context) ([]store.User, error)
+	ListUsers(ctx context.Context, list store.UserListOptions) (store.Page[store.User], error)
+	SearchUsers(ctx context.Context, search store.UserSearchOptions) (store.Page[store.UserSearchResult], error)
 	GetUser(ctx context.Context, id uuid.UUID) (store.User, error)
 	CreateUser(ctx context.Context, user store.NewUser) (store.User, error)
-	UpdateUser(ctx context.Context, id uuid.UUID, patch store.UserPatch) (store.User, error)
+	UpdateUser(ctx context.Context, id uuid.UUID, version int64, patch store.UserPatch) (store.User, error)
 	DeleteUser(ctx context.Context, id uuid.UUID) error
+	RestoreUser(ctx context.Context, id uuid.UUID) (store.User, error)
+	ChangePassword(ctx context.Context, id uuid.UUID, current, new string) error
 }
 
 type ClientService interface {
-	ListClients(ctx context.Context) ([]store.Client, error)
+	ListClients(ctx context.Context, list store.ClientListOptions) (store.Page[store.Client], error)
 	GetClient(ctx context.Context, id uuid.UUID) (store.Client, error)
 	CreateClient(ctx context.Context, client store.NewClient) (store.Client, error)
-	UpdateClient(ctx context.Context, id uuid.UUID, patch store.ClientPatch) (store.Client, error)
+	UpdateClient(ctx context.Context, id uuid.UUID, version int64, patch store.ClientPatch) (store.Client, error)
 	DeleteClient(ctx context.Context, id uuid.UUID) error
+	RestoreClient(ctx context.Context, id uuid.UUID) (store.Client, error)
+	ListAllowedOrigins(ctx context.Context) ([]string, error)
 
 	ListClientAPIKeys(ctx context.Context, clientID uuid.UUID) ([]store.APIKey, error)
 	GenerateAPIKey(ctx context.Context, newKey store.NewAPIKey) (string, error)
@@ -51,10 +90,38 @@ type ClientService interface {
 }
 
 type AuthService interface {
-	Login(ctx context.Context, username, password string) (auth.Token, error)
+	Login(ctx context.Context, creds auth.Credentials) (auth.Token, error)
 	Logout(ctx context.Context, session string) error
 	IntrospectToken(ctx context.Context, token string) (auth.TokenInfo, error)
-	ValidateAPIKey(ctx context.Context, key string) error
+	ValidateAccessToken(ctx context.Context, token string, proof auth.TokenProof) (auth.TokenInfo, error)
+	IssueClientToken(ctx context.Context, clientID uuid.UUID, proof auth.TokenProof) (auth.Token, error)
+	Reauthenticate(ctx context.Context, userID, sessionID uuid.UUID, password string) error
+	ValidateAPIKey(ctx context.Context, key string) (uuid.UUID, error)
+	ValidateClientCertificate(ctx context.Context, cert *x509.Certificate) (uuid.UUID, error)
+
+	ListUserSessions(ctx context.Context, userID uuid.UUID) ([]store.Session, error)
+	RevokeUserSession(ctx context.Context, userID, sessionID uuid.UUID) error
+	RevokeUserSessions(ctx context.Context, userID, keepID uuid.UUID) error
+}
+
+type AuditService interface {
+	ListEvents(ctx context.Context, list store.AuditEventListOptions) (store.Page[store.AuditEvent], error)
+	Verify(ctx context.Context) (audit.Verification, error)
+}
+
+type WebhookService interface {
+	ListWebhooks(ctx context.Context) ([]store.Webhook, error)
+	GetWebhook(ctx context.Context, id uuid.UUID) (store.Webhook, error)
+	CreateWebhook(ctx context.Context, webhook store.NewWebhook) (store.Webhook, error)
+	UpdateWebhook(ctx context.Context, id uuid.UUID, version int64, patch store.WebhookPatch) (store.Webhook, error)
+	DeleteWebhook(ctx context.Context, id uuid.UUID) error
+
+	ListDeliveries(ctx context.Context, webhookID uuid.UUID, list store.WebhookDeliveryListOptions) (store.Page[store.WebhookDelivery], error)
+	GetDelivery(ctx context.Context, webhookID, deliveryID uuid.UUID) (webhook.DeliveryHistory, error)
+}
+
+type HealthService interface {
+	Ready(ctx context.Context) health.Report
 }
 
 // NewServer builds a new server object with the default middleware and router
@@ -62,11 +129,15 @@ type AuthService interface {
 // to ensure they have everything needed to function.
 func NewServer() *Server {
 	r := chi.NewRouter()
+	s := &Server{Router: r}
 
 	r.Use(middleware.RequestID)
-	r.Use(middleware.Logger)
+	r.Use(traceRequest)
+	r.Use(s.logRequest)
+	r.Use(observeRequest)
+	r.Use(auditRequest)
+	r.Use(s.handleCORS)
 
-	s := &Server{Router: r}
 	s.loadRoutes()
 
 	return s
@@ -76,19 +147,15 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	s.Router.ServeHTTP(w, r)
 }
 
-// ListenAndServe spins up the server to listen on the provided address. The
-// allowed addresses follow the same rules as `http.ListenAndServe`.
-func (s *Server) ListenAndServe(addr string) error {
-	if s.Logger == nil {
-		s.Logger, _ = level.NewBasicLogger(level.Info, nil)
-	}
-
+// ListenAndServe spins up the server to listen on the provided address until
+// ctx is done, and then shuts it down gracefully. The allowed addresses follow
+// the same rules as `http.ListenAndServe`.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
 	if s.DisableAuth {
-		s.Logger.Warn("Starting server in setup mode. All routes are unprotected.")
+		s.logger().Warn("Starting server in setup mode. All routes are unprotected.")
 	}
 
-	s.Logger.Info("Server listening on %s", addr)
-	return http.ListenAndServe(addr, s)
+	return Serve(ctx, addr, s, s.Listen, s.logger())
 }
 
 // The max size in bytes of a request body. 5 mB should be plenty.
@@ -122,10 +189,22 @@ func (s *Server) decode(r *http.Request, dst any) error {
 // process, the error is logged and an internal server error is returned to the
 // client.
 func (s *Server) respond(w http.ResponseWriter, r *http.Request, status int, data any) {
-	type envelope struct {
-		Response any `json:"response"`
-	}
+	s.respondWithEnvelope(w, r, status, envelope{Response: data})
+}
+
+// respondWithPage writes a single page of a list, linking to the next page when
+// there is one.
+func (s *Server) respondWithPage(w http.ResponseWriter, r *http.Request, status int, data any, next string) {
+	s.respondWithEnvelope(w, r, status, envelope{Response: data, Next: next})
+}
+
+type envelope struct {
+	Response any `json:"response"`
+	// Next links to the following page of a paginated response.
+	Next string `json:"next,omitempty"`
+}
 
+func (s *Server) respondWithEnvelope(w http.ResponseWriter, r *http.Request, status int, env envelope) {
 	w.Header().Set("content-type", "application/json")
 	w.WriteHeader(status)
 
@@ -133,7 +212,12 @@ func (s *Server) respond(w http.ResponseWriter, r *http.Request, status int, dat
 		return
 	}
 
-	err := json.NewEncoder(w).Encode(envelope{Response: data})
+	// Links such as the next page's contain query strings, so HTML characters
+	// are left alone to keep them readable.
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+
+	err := enc.Encode(env)
 	if err != nil {
 		s.respondWithError(w, r, http.StatusInternalServerError, err)
 	}
@@ -163,6 +247,41 @@ func (s *Server) respondWithError(w http.ResponseWriter, r *http.Request, status
 
 }
 
+// errorStatus picks the status code for an error returned by a service. Store
+// errors map onto their HTTP equivalents, and anything else is treated as an
+// internal error so that its details, such as SQL, are never sent to clients.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, store.NotFoundError{}):
+		return http.StatusNotFound
+	case errors.Is(err, store.ConflictError{}):
+		return http.StatusConflict
+	case errors.Is(err, store.ConstraintViolationError{}):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, store.ErrInvalidSort), errors.Is(err, store.ErrInvalidCursor):
+		return http.StatusBadRequest
+	case errors.Is(err, store.VersionMismatchError{}), errors.Is(err, errIfMatchInvalid):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, errIfMatchRequired):
+		return http.StatusPreconditionRequired
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 func (s *Server) logError(r *http.Request, err error) {
-	s.Logger.Error("%s: %v", r.Context().Value(middleware.RequestIDKey), err)
+	s.logger().LogAttrs(r.Context(), slog.LevelError, "Request failed",
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+		slog.String("route", routePattern(r)),
+		slog.Any("error", err),
+	)
+}
+
+// logger returns the server's logger, or the default logger if it has none.
+func (s *Server) logger() *slog.Logger {
+	if s.Logger != nil {
+		return s.Logger
+	}
+
+	return slog.Default()
 }
@@ -0,0 +1,67 @@
+package http
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/ninth-realm/heimdall/store"
+)
+
+func Test_errorStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{
+			name: "Not found",
+			err:  store.NotFoundError{ResourceType: "user", ResourceID: "1"},
+			want: http.StatusNotFound,
+		},
+		{
+			name: "Wrapped not found",
+			err:  fmt.Errorf("getting user: %w", store.NotFoundError{ResourceType: "user"}),
+			want: http.StatusNotFound,
+		},
+		{
+			name: "Conflict",
+			err:  store.ConflictError{ResourceType: "email"},
+			want: http.StatusConflict,
+		},
+		{
+			name: "Constraint violation",
+			err:  store.ConstraintViolationError{ResourceType: "API key"},
+			want: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "Version mismatch",
+			err:  store.VersionMismatchError{ResourceType: "client"},
+			want: http.StatusPreconditionFailed,
+		},
+		{
+			name: "Missing If-Match",
+			err:  errIfMatchRequired,
+			want: http.StatusPreconditionRequired,
+		},
+		{
+			name: "Unmapped driver error",
+			err:  sql.ErrConnDone,
+			want: http.StatusInternalServerError,
+		},
+		{
+			name: "Other error",
+			err:  errors.New("boom"),
+			want: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errorStatus(tt.err); got != tt.want {
+				t.Errorf("errorStatus() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
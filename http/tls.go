@@ -0,0 +1,165 @@
+package http
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// certificateCheckInterval is how often the certificate files are checked for
+// changes. They're only checked during handshakes, so an idle server doesn't
+// touch the disk.
+const certificateCheckInterval = 10 * time.Second
+
+// TLSConfig locates the files used to serve TLS.
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile holds the CAs that client certificates are verified
+	// against. When set, clients may authenticate with a certificate instead
+	// of an API key. Certificates are optional, so other clients are
+	// unaffected.
+	ClientCAFile string
+}
+
+// NewTLSConfig loads the certificate, and any client CAs, for serving TLS. The
+// certificate is reloaded whenever its files change.
+func NewTLSConfig(config TLSConfig, logger *slog.Logger) (*tls.Config, error) {
+	if config.CertFile == "" || config.KeyFile == "" {
+		return nil, errors.New("TLS requires both a certificate and a key")
+	}
+
+	certs, err := NewCertificateReloader(config.CertFile, config.KeyFile, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: certs.GetCertificate,
+	}
+
+	if config.ClientCAFile != "" {
+		pem, err := os.ReadFile(config.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", config.ClientCAFile)
+		}
+
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return tlsConfig, nil
+}
+
+// CertificateReloader serves a certificate and key from disk, and reloads them
+// when either file is modified, so that renewed certificates are picked up
+// without a restart. If a reload fails, such as when only one of the files has
+// been replaced so far, the previous certificate is kept and the reload is
+// tried again on the next check.
+type CertificateReloader struct {
+	certFile string
+	keyFile  string
+	logger   *slog.Logger
+	// checkInterval is how long to wait between checking the files.
+	checkInterval time.Duration
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	certMod   time.Time
+	keyMod    time.Time
+	checkedAt time.Time
+}
+
+// NewCertificateReloader loads the certificate and key. Unlike later reloads,
+// failing to load them here is an error.
+func NewCertificateReloader(certFile, keyFile string, logger *slog.Logger) (*CertificateReloader, error) {
+	c := &CertificateReloader{
+		certFile:      certFile,
+		keyFile:       keyFile,
+		logger:        logger,
+		checkInterval: certificateCheckInterval,
+	}
+
+	certMod, keyMod, err := c.modTimes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.load(certMod, keyMod); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// GetCertificate returns the current certificate, reloading it first if its
+// files have changed. It can be used as tls.Config.GetCertificate.
+func (c *CertificateReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.checkedAt) < c.checkInterval {
+		return c.cert, nil
+	}
+	c.checkedAt = time.Now()
+
+	certMod, keyMod, err := c.modTimes()
+	if err != nil {
+		c.logger.Error("Checking TLS certificate", "error", err)
+		return c.cert, nil
+	}
+
+	if certMod.Equal(c.certMod) && keyMod.Equal(c.keyMod) {
+		return c.cert, nil
+	}
+
+	if err := c.load(certMod, keyMod); err != nil {
+		c.logger.Error("Reloading TLS certificate", "error", err)
+		return c.cert, nil
+	}
+
+	c.logger.Info("Reloaded TLS certificate", "cert_file", c.certFile)
+
+	return c.cert, nil
+}
+
+// load reads the certificate and key, and records the modification times they
+// were read at.
+func (c *CertificateReloader) load(certMod, keyMod time.Time) error {
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return err
+	}
+
+	c.cert = &cert
+	c.certMod = certMod
+	c.keyMod = keyMod
+	c.checkedAt = time.Now()
+
+	return nil
+}
+
+func (c *CertificateReloader) modTimes() (time.Time, time.Time, error) {
+	cert, err := os.Stat(c.certFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	key, err := os.Stat(c.keyFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	return cert.ModTime(), key.ModTime(), nil
+}
@@ -0,0 +1,196 @@
+package http
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/ninth-realm/heimdall/store"
+)
+
+func TestCertificateReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	writeCertificate(t, certFile, keyFile, "first", time.Now().Add(-time.Hour))
+
+	c, err := NewCertificateReloader(certFile, keyFile, logger)
+	if err != nil {
+		t.Fatalf("NewCertificateReloader() error = %v", err)
+	}
+	c.checkInterval = 0
+
+	if got := commonName(t, c); got != "first" {
+		t.Errorf("certificate = %s, want first", got)
+	}
+
+	writeCertificate(t, certFile, keyFile, "second", time.Now())
+
+	if got := commonName(t, c); got != "second" {
+		t.Errorf("certificate after change = %s, want second", got)
+	}
+
+	// A half written key fails to load, and the working certificate is kept.
+	if err := os.WriteFile(keyFile, []byte("not a key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	setModTime(t, keyFile, time.Now().Add(time.Hour))
+
+	if got := commonName(t, c); got != "second" {
+		t.Errorf("certificate after bad key = %s, want second", got)
+	}
+}
+
+// certificateAuthService accepts certificates for a single client. Only the
+// methods used by authenticateRoute are implemented.
+type certificateAuthService struct {
+	AuthService
+	clientID uuid.UUID
+}
+
+func (s certificateAuthService) ValidateClientCertificate(ctx context.Context, cert *x509.Certificate) (uuid.UUID, error) {
+	if cert.Subject.CommonName != s.clientID.String() {
+		return uuid.Nil, store.NotFoundError{ResourceType: "client", ResourceID: cert.Subject.CommonName}
+	}
+
+	return s.clientID, nil
+}
+
+func Test_authenticateRoute_clientCertificate(t *testing.T) {
+	clientID := uuid.Must(uuid.NewV4())
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: clientID.String()}}
+	other := &x509.Certificate{Subject: pkix.Name{CommonName: uuid.Must(uuid.NewV4()).String()}}
+
+	tests := []struct {
+		name       string
+		tls        *tls.ConnectionState
+		wantStatus int
+		wantClient uuid.UUID
+	}{
+		{
+			name:       "Verified certificate",
+			tls:        &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}},
+			wantStatus: http.StatusOK,
+			wantClient: clientID,
+		},
+		{
+			name:       "Unknown client",
+			tls:        &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{other}}},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "Unverified certificate",
+			tls:        &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "Plain HTTP",
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{AuthService: certificateAuthService{clientID: clientID}}
+
+			var got principal
+			handler := s.authenticateRoute(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = principalFromContext(r.Context())
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+			r.TLS = tt.tls
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			if got.ClientID != tt.wantClient {
+				t.Errorf("client ID = %s, want %s", got.ClientID, tt.wantClient)
+			}
+		})
+	}
+}
+
+func commonName(t *testing.T, c *CertificateReloader) string {
+	t.Helper()
+
+	cert, err := c.GetCertificate(nil)
+	if err != nil {
+		t.Fatalf("GetCertificate() error = %v", err)
+	}
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("ParseCertificate() error = %v", err)
+	}
+
+	return leaf.Subject.CommonName
+}
+
+// writeCertificate writes a new self-signed certificate and key, marking both
+// files as modified at modTime.
+func writeCertificate(t *testing.T, certFile, keyFile, commonName string, modTime time.Time) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+	setModTime(t, certFile, modTime)
+	setModTime(t, keyFile, modTime)
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func setModTime(t *testing.T, path string, modTime time.Time) {
+	t.Helper()
+
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
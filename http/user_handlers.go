@@ -1,7 +1,9 @@
 package http
 
 import (
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/gofrs/uuid/v5"
@@ -10,13 +12,71 @@ import (
 
 func (s *Server) handleUsersList() http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		users, err := s.UserService.ListUsers(r.Context())
+		q := r.URL.Query()
+
+		page, err := parsePagination(q)
+		if err != nil {
+			s.respondWithError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		createdAfter, err := queryTime(q, "createdAfter")
+		if err != nil {
+			s.respondWithError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		createdBefore, err := queryTime(q, "createdBefore")
+		if err != nil {
+			s.respondWithError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		users, err := s.UserService.ListUsers(r.Context(), store.UserListOptions{
+			Filter: store.UserFilter{
+				Email:         queryString(q, "email"),
+				NamePrefix:    queryString(q, "namePrefix"),
+				CreatedAfter:  createdAfter,
+				CreatedBefore: createdBefore,
+			},
+			Sort:       parseSort(q),
+			Pagination: page,
+		})
 		if err != nil {
-			s.respondWithError(w, r, http.StatusInternalServerError, err)
+			s.respondWithError(w, r, errorStatus(err), err)
 			return
 		}
 
-		s.respond(w, r, http.StatusOK, users)
+		s.respondWithPage(w, r, http.StatusOK, users.Items, nextLink(r, users.Next))
+	})
+}
+
+func (s *Server) handleUsersSearch() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+
+		query := strings.TrimSpace(q.Get("q"))
+		if query == "" {
+			s.respondWithError(w, r, http.StatusBadRequest, errors.New("q is required"))
+			return
+		}
+
+		page, err := parsePagination(q)
+		if err != nil {
+			s.respondWithError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		results, err := s.UserService.SearchUsers(r.Context(), store.UserSearchOptions{
+			Query:      query,
+			Pagination: page,
+		})
+		if err != nil {
+			s.respondWithError(w, r, errorStatus(err), err)
+			return
+		}
+
+		s.respondWithPage(w, r, http.StatusOK, results.Items, nextLink(r, results.Next))
 	})
 }
 
@@ -43,10 +103,11 @@ func (s *Server) handleUsersCreate() http.HandlerFunc {
 			Password:  (*string)(&requestBody.Password.Value),
 		})
 		if err != nil {
-			s.respondWithError(w, r, http.StatusUnprocessableEntity, err)
+			s.respondWithError(w, r, errorStatus(err), err)
 			return
 		}
 
+		setETag(w, user.Version)
 		s.respond(w, r, http.StatusCreated, user)
 	})
 }
@@ -61,10 +122,11 @@ func (s *Server) handleUsersGet() http.HandlerFunc {
 
 		user, err := s.UserService.GetUser(r.Context(), id)
 		if err != nil {
-			s.respondWithError(w, r, http.StatusNotFound, err)
+			s.respondWithError(w, r, errorStatus(err), err)
 			return
 		}
 
+		setETag(w, user.Version)
 		s.respond(w, r, http.StatusOK, user)
 	})
 }
@@ -82,6 +144,12 @@ func (s *Server) handleUsersUpdate() http.HandlerFunc {
 			return
 		}
 
+		version, err := ifMatch(r)
+		if err != nil {
+			s.respondWithError(w, r, errorStatus(err), err)
+			return
+		}
+
 		var requestBody request
 		err = s.decode(r, &requestBody)
 		if err != nil {
@@ -89,15 +157,16 @@ func (s *Server) handleUsersUpdate() http.HandlerFunc {
 			return
 		}
 
-		user, err := s.UserService.UpdateUser(r.Context(), id, store.UserPatch{
+		user, err := s.UserService.UpdateUser(r.Context(), id, version, store.UserPatch{
 			FirstName: (*string)(requestBody.FirstName),
 			LastName:  (*string)(requestBody.LastName),
 		})
 		if err != nil {
-			s.respondWithError(w, r, http.StatusUnprocessableEntity, err)
+			s.respondWithError(w, r, errorStatus(err), err)
 			return
 		}
 
+		setETag(w, user.Version)
 		s.respond(w, r, http.StatusOK, user)
 	})
 }
@@ -112,7 +181,86 @@ func (s *Server) handleUsersDelete() http.HandlerFunc {
 
 		err = s.UserService.DeleteUser(r.Context(), id)
 		if err != nil {
-			s.respondWithError(w, r, http.StatusNotFound, err)
+			s.respondWithError(w, r, errorStatus(err), err)
+			return
+		}
+
+		s.respond(w, r, http.StatusNoContent, nil)
+	})
+}
+
+func (s *Server) handleUsersRestore() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := uuid.FromString(chi.URLParamFromCtx(r.Context(), "userID"))
+		if err != nil {
+			s.respondWithError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		user, err := s.UserService.RestoreUser(r.Context(), id)
+		if err != nil {
+			s.respondWithError(w, r, errorStatus(err), err)
+			return
+		}
+
+		setETag(w, user.Version)
+		s.respond(w, r, http.StatusOK, user)
+	})
+}
+
+func (s *Server) handleUsersSessionsList() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := uuid.FromString(chi.URLParamFromCtx(r.Context(), "userID"))
+		if err != nil {
+			s.respondWithError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		sessions, err := s.AuthService.ListUserSessions(r.Context(), id)
+		if err != nil {
+			s.respondWithError(w, r, errorStatus(err), err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, sessions)
+	})
+}
+
+func (s *Server) handleUsersSessionsDelete() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, err := uuid.FromString(chi.URLParamFromCtx(r.Context(), "userID"))
+		if err != nil {
+			s.respondWithError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		sessionID, err := uuid.FromString(chi.URLParamFromCtx(r.Context(), "sessionID"))
+		if err != nil {
+			s.respondWithError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		err = s.AuthService.RevokeUserSession(r.Context(), userID, sessionID)
+		if err != nil {
+			s.respondWithError(w, r, errorStatus(err), err)
+			return
+		}
+
+		s.respond(w, r, http.StatusNoContent, nil)
+	})
+}
+
+func (s *Server) handleUsersSessionsRevoke() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := uuid.FromString(chi.URLParamFromCtx(r.Context(), "userID"))
+		if err != nil {
+			s.respondWithError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		err = s.AuthService.RevokeUserSessions(r.Context(), id, uuid.Nil)
+		if err != nil {
+			s.respondWithError(w, r, errorStatus(err), err)
 			return
 		}
 
@@ -3,8 +3,11 @@ package http
 import (
 	"encoding/json"
 	"net/mail"
+	"net/url"
 	"reflect"
 	"strings"
+
+	"github.com/ninth-realm/heimdall/webhook"
 )
 
 type optional[T any] struct {
@@ -69,3 +72,81 @@ func (s *nonEmptyString) UnmarshalJSON(b []byte) error {
 
 	return nil
 }
+
+// webhookURL represents an absolute http or https URL.
+type webhookURL string
+
+func (s *webhookURL) UnmarshalJSON(b []byte) error {
+	var str string
+	err := json.Unmarshal(b, &str)
+
+	if err != nil {
+		return err
+	}
+
+	u, err := url.Parse(strings.TrimSpace(str))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return &json.UnmarshalTypeError{Value: string(b), Type: reflect.TypeOf(*s)}
+	}
+
+	*s = webhookURL(u.String())
+
+	return nil
+}
+
+// originList represents a list of browser origins, without duplicates.
+type originList []string
+
+func (l *originList) UnmarshalJSON(b []byte) error {
+	var origins []string
+	err := json.Unmarshal(b, &origins)
+
+	if err != nil {
+		return err
+	}
+
+	list := originList{}
+	seen := make(map[string]bool)
+	for _, o := range origins {
+		origin, err := ParseOrigin(o)
+		if err != nil {
+			return &json.UnmarshalTypeError{Value: o, Type: reflect.TypeOf(*l)}
+		}
+
+		if !seen[origin] {
+			seen[origin] = true
+			list = append(list, origin)
+		}
+	}
+
+	*l = list
+
+	return nil
+}
+
+// eventTypeList represents a non-empty list of event types that webhooks can
+// subscribe to.
+type eventTypeList []string
+
+func (l *eventTypeList) UnmarshalJSON(b []byte) error {
+	var types []string
+	err := json.Unmarshal(b, &types)
+
+	if err != nil {
+		return err
+	}
+
+	if len(types) == 0 {
+		return &json.UnmarshalTypeError{Value: string(b), Type: reflect.TypeOf(*l)}
+	}
+
+	for _, t := range types {
+		if !webhook.IsEventType(t) {
+			return &json.UnmarshalTypeError{Value: t, Type: reflect.TypeOf(*l)}
+		}
+	}
+
+	*l = types
+
+	return nil
+}
@@ -0,0 +1,210 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gofrs/uuid/v5"
+	"github.com/ninth-realm/heimdall/store"
+)
+
+func (s *Server) handleWebhooksList() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		webhooks, err := s.WebhookService.ListWebhooks(r.Context())
+		if err != nil {
+			s.respondWithError(w, r, errorStatus(err), err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, webhooks)
+	})
+}
+
+func (s *Server) handleWebhooksCreate() http.HandlerFunc {
+	type request struct {
+		URL        webhookURL      `json:"url"`
+		EventTypes eventTypeList   `json:"eventTypes"`
+		Secret     *nonEmptyString `json:"secret"`
+		Enabled    bool            `json:"enabled"`
+	}
+
+	// The secret is only ever returned here, so it has to be saved by the
+	// caller to verify deliveries.
+	type response struct {
+		store.Webhook
+		Secret string `json:"secret"`
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var requestBody request
+		err := s.decode(r, &requestBody)
+		if err != nil {
+			s.respondWithError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		if requestBody.URL == "" || requestBody.EventTypes == nil {
+			s.respondWithError(w, r, http.StatusBadRequest, errors.New("url and eventTypes are required"))
+			return
+		}
+
+		newWebhook := store.NewWebhook{
+			URL:        string(requestBody.URL),
+			EventTypes: requestBody.EventTypes,
+			Enabled:    requestBody.Enabled,
+		}
+		if requestBody.Secret != nil {
+			newWebhook.Secret = requestBody.Secret.toString()
+		}
+
+		webhook, err := s.WebhookService.CreateWebhook(r.Context(), newWebhook)
+		if err != nil {
+			s.respondWithError(w, r, errorStatus(err), err)
+			return
+		}
+
+		setETag(w, webhook.Version)
+		s.respond(w, r, http.StatusCreated, response{Webhook: webhook, Secret: webhook.Secret})
+	})
+}
+
+func (s *Server) handleWebhooksGet() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := uuid.FromString(chi.URLParamFromCtx(r.Context(), "webhookID"))
+		if err != nil {
+			s.respondWithError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		webhook, err := s.WebhookService.GetWebhook(r.Context(), id)
+		if err != nil {
+			s.respondWithError(w, r, errorStatus(err), err)
+			return
+		}
+
+		setETag(w, webhook.Version)
+		s.respond(w, r, http.StatusOK, webhook)
+	})
+}
+
+func (s *Server) handleWebhooksUpdate() http.HandlerFunc {
+	type request struct {
+		URL        *webhookURL     `json:"url"`
+		EventTypes *eventTypeList  `json:"eventTypes"`
+		Secret     *nonEmptyString `json:"secret"`
+		Enabled    *bool           `json:"enabled"`
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := uuid.FromString(chi.URLParamFromCtx(r.Context(), "webhookID"))
+		if err != nil {
+			s.respondWithError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		version, err := ifMatch(r)
+		if err != nil {
+			s.respondWithError(w, r, errorStatus(err), err)
+			return
+		}
+
+		var requestBody request
+		err = s.decode(r, &requestBody)
+		if err != nil {
+			s.respondWithError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		webhook, err := s.WebhookService.UpdateWebhook(r.Context(), id, version, store.WebhookPatch{
+			URL:        (*string)(requestBody.URL),
+			EventTypes: (*[]string)(requestBody.EventTypes),
+			Secret:     (*string)(requestBody.Secret),
+			Enabled:    requestBody.Enabled,
+		})
+		if err != nil {
+			s.respondWithError(w, r, errorStatus(err), err)
+			return
+		}
+
+		setETag(w, webhook.Version)
+		s.respond(w, r, http.StatusOK, webhook)
+	})
+}
+
+func (s *Server) handleWebhooksDelete() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := uuid.FromString(chi.URLParamFromCtx(r.Context(), "webhookID"))
+		if err != nil {
+			s.respondWithError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		err = s.WebhookService.DeleteWebhook(r.Context(), id)
+		if err != nil {
+			s.respondWithError(w, r, errorStatus(err), err)
+			return
+		}
+
+		s.respond(w, r, http.StatusNoContent, nil)
+	})
+}
+
+func (s *Server) handleWebhooksDeliveriesList() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := uuid.FromString(chi.URLParamFromCtx(r.Context(), "webhookID"))
+		if err != nil {
+			s.respondWithError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		q := r.URL.Query()
+
+		page, err := parsePagination(q)
+		if err != nil {
+			s.respondWithError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		// Recent deliveries come first unless asked otherwise.
+		sort := store.Sort{Field: store.SortByCreatedAt, Descending: true}
+		if q.Get("sort") != "" {
+			sort = parseSort(q)
+		}
+
+		deliveries, err := s.WebhookService.ListDeliveries(r.Context(), id, store.WebhookDeliveryListOptions{
+			Sort:       sort,
+			Pagination: page,
+		})
+		if err != nil {
+			s.respondWithError(w, r, errorStatus(err), err)
+			return
+		}
+
+		s.respondWithPage(w, r, http.StatusOK, deliveries.Items, nextLink(r, deliveries.Next))
+	})
+}
+
+func (s *Server) handleWebhooksDeliveriesGet() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		webhookID, err := uuid.FromString(chi.URLParamFromCtx(r.Context(), "webhookID"))
+		if err != nil {
+			s.respondWithError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		deliveryID, err := uuid.FromString(chi.URLParamFromCtx(r.Context(), "deliveryID"))
+		if err != nil {
+			s.respondWithError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		delivery, err := s.WebhookService.GetDelivery(r.Context(), webhookID, deliveryID)
+		if err != nil {
+			s.respondWithError(w, r, errorStatus(err), err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, delivery)
+	})
+}
@@ -0,0 +1,38 @@
+package job
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/ninth-realm/heimdall/store"
+)
+
+// Purger permanently removes users and clients that were soft deleted more than
+// retention ago, along with everything that belongs to them. Until then they
+// can still be restored. Events older than retention are removed from the
+// outbox once every one of the event subscribers has handled them.
+func Purger(repo store.Repository, retention time.Duration, subscribers []string, logger *slog.Logger) Func {
+	return func(ctx context.Context) error {
+		before := time.Now().UTC().Add(-retention)
+
+		users, err := repo.PurgeDeletedUsers(before, store.QueryOptions{Ctx: ctx})
+		if err != nil {
+			return err
+		}
+
+		clients, err := repo.PurgeDeletedClients(before, store.QueryOptions{Ctx: ctx})
+		if err != nil {
+			return err
+		}
+
+		events, err := repo.DeleteDispatchedOutboxEvents(subscribers, before, store.QueryOptions{Ctx: ctx})
+		if err != nil {
+			return err
+		}
+
+		logger.Info("Purged deleted resources", "users", users, "clients", clients, "events", events)
+
+		return nil
+	}
+}
@@ -0,0 +1,84 @@
+// Package job runs periodic maintenance tasks in the background for as long as
+// the server is up.
+package job
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Func is a single run of a job. Returned errors are logged, and the job will
+// still be run again on its next tick.
+type Func func(ctx context.Context) error
+
+type entry struct {
+	name     string
+	interval time.Duration
+	fn       Func
+}
+
+// Scheduler runs each of its jobs on a fixed interval. Jobs must be added before
+// the scheduler is started.
+type Scheduler struct {
+	Logger *slog.Logger
+
+	jobs   []entry
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Add registers a job to be run every interval. Jobs with a non-positive interval
+// are disabled and will never run.
+func (s *Scheduler) Add(name string, interval time.Duration, fn Func) {
+	s.jobs = append(s.jobs, entry{name: name, interval: interval, fn: fn})
+}
+
+// Start runs every job once and then again on each of their intervals until the
+// provided context is cancelled or Stop is called.
+func (s *Scheduler) Start(ctx context.Context) {
+	if s.Logger == nil {
+		s.Logger = slog.Default()
+	}
+
+	ctx, s.cancel = context.WithCancel(ctx)
+
+	for _, j := range s.jobs {
+		if j.interval <= 0 {
+			s.Logger.Info("Job is disabled", "job", j.name)
+			continue
+		}
+
+		s.wg.Add(1)
+		go s.run(ctx, j)
+	}
+}
+
+// Stop cancels all running jobs and waits for them to return.
+func (s *Scheduler) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+
+	s.wg.Wait()
+}
+
+func (s *Scheduler) run(ctx context.Context, j entry) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := j.fn(ctx); err != nil && ctx.Err() == nil {
+			s.Logger.Error("Job failed", "job", j.name, "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
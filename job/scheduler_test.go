@@ -0,0 +1,80 @@
+package job
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestScheduler_RunsJobsUntilStopped(t *testing.T) {
+	var runs atomic.Int32
+
+	s := &Scheduler{}
+	s.Add("counter", time.Millisecond, func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	})
+
+	s.Start(context.Background())
+
+	deadline := time.Now().Add(time.Second)
+	for runs.Load() < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	s.Stop()
+
+	if n := runs.Load(); n < 3 {
+		t.Fatalf("Expected job to run at least 3 times, ran %d times", n)
+	}
+
+	n := runs.Load()
+	time.Sleep(10 * time.Millisecond)
+	if runs.Load() != n {
+		t.Errorf("Job ran after the scheduler was stopped")
+	}
+}
+
+func TestScheduler_SkipsDisabledJobs(t *testing.T) {
+	var runs atomic.Int32
+
+	s := &Scheduler{}
+	s.Add("disabled", 0, func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	})
+
+	s.Start(context.Background())
+	time.Sleep(10 * time.Millisecond)
+	s.Stop()
+
+	if n := runs.Load(); n != 0 {
+		t.Errorf("Expected disabled job to never run, ran %d times", n)
+	}
+}
+
+func TestScheduler_StopsWhenContextIsCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s := &Scheduler{}
+	s.Add("blocking", time.Hour, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	s.Start(ctx)
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.Stop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Scheduler did not stop after its context was cancelled")
+	}
+}
@@ -0,0 +1,25 @@
+package job
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/ninth-realm/heimdall/store"
+)
+
+// SessionSweeper permanently removes sessions that have expired. Expired
+// sessions are already rejected when used, so this only keeps the session table
+// from growing forever.
+func SessionSweeper(repo store.SessionRepository, logger *slog.Logger) Func {
+	return func(ctx context.Context) error {
+		n, err := repo.DeleteExpiredSessions(time.Now().UTC(), store.QueryOptions{Ctx: ctx})
+		if err != nil {
+			return err
+		}
+
+		logger.Info("Removed expired sessions", "sessions", n)
+
+		return nil
+	}
+}
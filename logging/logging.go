@@ -0,0 +1,72 @@
+// Package logging builds the structured logger used throughout Heimdall.
+//
+// Logs are written as JSON by default so that they can be searched by field,
+// such as a request ID. Attributes whose keys suggest a secret, such as a
+// password, token or API key, are redacted wherever they appear.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Formats.
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// Redacted replaces the value of sensitive attributes.
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys are the substrings of attribute keys whose values are redacted.
+// Keys are compared in lowercase with dashes and underscores removed.
+var sensitiveKeys = []string{
+	"password",
+	"token",
+	"secret",
+	"apikey",
+	"authorization",
+	"cookie",
+}
+
+// New builds a logger that writes to w in the given format, dropping anything
+// below the given level. The level is one of debug, info, warn or error.
+func New(w io.Writer, format, level string) (*slog.Logger, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q", level)
+	}
+
+	opts := &slog.HandlerOptions{Level: l, ReplaceAttr: redact}
+
+	switch format {
+	case FormatJSON:
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	case FormatText:
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+}
+
+func redact(groups []string, a slog.Attr) slog.Attr {
+	if isSensitive(a.Key) {
+		return slog.String(a.Key, Redacted)
+	}
+
+	return a
+}
+
+func isSensitive(key string) bool {
+	key = strings.NewReplacer("-", "", "_", "").Replace(strings.ToLower(key))
+	for _, s := range sensitiveKeys {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+
+	return false
+}
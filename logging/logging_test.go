@@ -0,0 +1,71 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestNew_redacts(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, FormatJSON, "info")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	logger.Info("login",
+		"username", "john.doe@example.com",
+		"password", "hunter2",
+		"X-API-Key", "client:prefix.secret",
+		slog.Group("request", "session_token", "abc", "path", "/api/v1/me"),
+	)
+
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("decoding log line: %v", err)
+	}
+
+	if got["username"] != "john.doe@example.com" {
+		t.Errorf("username = %v, want it logged", got["username"])
+	}
+
+	for _, key := range []string{"password", "X-API-Key"} {
+		if got[key] != Redacted {
+			t.Errorf("%s = %v, want %s", key, got[key], Redacted)
+		}
+	}
+
+	request, _ := got["request"].(map[string]any)
+	if request["session_token"] != Redacted || request["path"] != "/api/v1/me" {
+		t.Errorf("request = %v, want only the token redacted", request)
+	}
+}
+
+func TestNew_level(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, FormatText, "warn")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	logger.Info("hidden")
+	if buf.Len() != 0 {
+		t.Errorf("info logged at warn level: %s", buf.String())
+	}
+
+	logger.Warn("shown")
+	if buf.Len() == 0 {
+		t.Error("warning not logged at warn level")
+	}
+}
+
+func TestNew_invalid(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, "xml", "info"); err == nil {
+		t.Error("New() with unknown format error = nil")
+	}
+
+	if _, err := New(&bytes.Buffer{}, FormatJSON, "loud"); err == nil {
+		t.Error("New() with unknown level error = nil")
+	}
+}
@@ -0,0 +1,190 @@
+// Package metrics exposes Prometheus metrics about Heimdall, such as request
+// latency, login attempts and database query latency.
+//
+// Metrics are recorded in Registry as the code they measure runs, and served in
+// the Prometheus text format by Handler. They describe a single process, so
+// each replica should be scraped.
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ninth-realm/heimdall/event"
+	"github.com/ninth-realm/heimdall/store"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "heimdall"
+
+// Login failure reasons. They're never shown to the user, who is only told that
+// their credentials are invalid.
+const (
+	ReasonUnknownUser   = "unknown_user"
+	ReasonNoPassword    = "no_password"
+	ReasonWrongPassword = "wrong_password"
+	// ReasonError is a login that couldn't be checked, such as when the
+	// database is unavailable.
+	ReasonError = "error"
+)
+
+// API key validation results.
+const (
+	ResultValid   = "valid"
+	ResultInvalid = "invalid"
+	// ResultError is a key that couldn't be checked, such as when the
+	// database is unavailable.
+	ResultError = "error"
+)
+
+// Registry holds every Heimdall metric, along with the Go runtime and process
+// metrics.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time taken to handle HTTP requests, by route pattern and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "Login attempts, by result and the reason for failures.",
+	}, []string{"result", "reason"})
+
+	apiKeyValidations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "api_key_validations_total",
+		Help:      "API key validations, by result.",
+	}, []string{"result"})
+
+	argon2Duration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "argon2_duration_seconds",
+		Help:      "Time taken to compute argon2 hashes of passwords and API keys.",
+		Buckets:   prometheus.ExponentialBuckets(0.005, 2, 10),
+	})
+
+	dbQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Time taken by database queries, by repository method.",
+		Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 14),
+	}, []string{"method"})
+
+	events = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_total",
+		Help:      "Domain events dispatched from the outbox, by type.",
+	}, []string{"type"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequestDuration,
+		logins,
+		apiKeyValidations,
+		argon2Duration,
+		dbQueryDuration,
+		events,
+	)
+}
+
+// Handler serves the metrics in Registry.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// ServeMux routes /metrics to Handler. It's meant to be served on its own
+// listener, so that the metrics can be kept off the public network.
+func ServeMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+
+	return mux
+}
+
+// ObserveHTTPRequest records a handled request. The route is the pattern that
+// matched it rather than its path, so that IDs don't create a series each.
+func ObserveHTTPRequest(method, route string, status int, duration time.Duration) {
+	httpRequestDuration.WithLabelValues(method, route, strconv.Itoa(status)).Observe(duration.Seconds())
+}
+
+// LoginSucceeded records a successful login.
+func LoginSucceeded() {
+	logins.WithLabelValues("success", "").Inc()
+}
+
+// LoginFailed records a failed login for one of the Reason constants.
+func LoginFailed(reason string) {
+	logins.WithLabelValues("failure", reason).Inc()
+}
+
+// APIKeyValidated records the validation of an API key with one of the Result
+// constants.
+func APIKeyValidated(result string) {
+	apiKeyValidations.WithLabelValues(result).Inc()
+}
+
+// ObserveArgon2 records how long an argon2 hash took to compute.
+func ObserveArgon2(duration time.Duration) {
+	argon2Duration.Observe(duration.Seconds())
+}
+
+// ObserveQuery is a store.Observer that records how long each repository
+// method takes.
+func ObserveQuery(ctx context.Context, method string) (context.Context, func(error)) {
+	start := time.Now()
+
+	return ctx, func(error) {
+		dbQueryDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	}
+}
+
+// Subscriber counts the events dispatched from the outbox. Each event is handed
+// to a single replica, so the count is only complete once summed across them.
+func Subscriber() event.Handler {
+	return func(e store.OutboxEvent, opts store.QueryOptions) error {
+		events.WithLabelValues(e.Type).Inc()
+		return nil
+	}
+}
+
+// RegisterSessions adds a gauge of the active sessions, counted from repo each
+// time the metrics are scraped.
+func RegisterSessions(repo store.Repository) {
+	Registry.MustRegister(sessionCollector{repo: repo})
+}
+
+var activeSessions = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "", "active_sessions"),
+	"Sessions that haven't expired.",
+	nil, nil,
+)
+
+type sessionCollector struct {
+	repo store.Repository
+}
+
+func (c sessionCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- activeSessions
+}
+
+func (c sessionCollector) Collect(ch chan<- prometheus.Metric) {
+	count, err := c.repo.CountActiveSessions(time.Now().UTC(), store.QueryOptions{})
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(activeSessions, err)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(activeSessions, prometheus.GaugeValue, float64(count))
+}
@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ninth-realm/heimdall/store"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// sessionRepo counts a fixed number of active sessions. Only the methods used
+// by the metrics are implemented.
+type sessionRepo struct {
+	store.Repository
+	count int64
+	err   error
+}
+
+func (r sessionRepo) CountActiveSessions(at time.Time, opts store.QueryOptions) (int64, error) {
+	return r.count, r.err
+}
+
+func (r sessionRepo) DeleteSession(tokenHash string, opts store.QueryOptions) error {
+	return r.err
+}
+
+func TestObserveQuery(t *testing.T) {
+	repo := store.ObservedRepository{Repo: sessionRepo{}, Observer: ObserveQuery}
+
+	before := testutil.CollectAndCount(dbQueryDuration)
+	for i := 0; i < 2; i++ {
+		if err := repo.DeleteSession("token", store.QueryOptions{}); err != nil {
+			t.Fatalf("DeleteSession() error = %v", err)
+		}
+	}
+
+	if got := testutil.CollectAndCount(dbQueryDuration); got != before+1 {
+		t.Errorf("db_query_duration_seconds has %d series, want %d", got, before+1)
+	}
+
+	count := histogramCount(t, dbQueryDuration.WithLabelValues("DeleteSession"))
+	if count != 2 {
+		t.Errorf("DeleteSession observations = %d, want 2", count)
+	}
+}
+
+func TestSessionCollector(t *testing.T) {
+	t.Run("Active sessions", func(t *testing.T) {
+		c := sessionCollector{repo: sessionRepo{count: 3}}
+
+		want := `
+			# HELP heimdall_active_sessions Sessions that haven't expired.
+			# TYPE heimdall_active_sessions gauge
+			heimdall_active_sessions 3
+		`
+		if err := testutil.CollectAndCompare(c, strings.NewReader(want)); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("Repository error", func(t *testing.T) {
+		reg := prometheus.NewRegistry()
+		reg.MustRegister(sessionCollector{repo: sessionRepo{err: errors.New("boom")}})
+
+		if _, err := reg.Gather(); err == nil {
+			t.Error("Gather() error = nil, want the repository's error")
+		}
+	})
+}
+
+func TestLogins(t *testing.T) {
+	before := testutil.ToFloat64(logins.WithLabelValues("failure", ReasonWrongPassword))
+
+	LoginFailed(ReasonWrongPassword)
+
+	after := testutil.ToFloat64(logins.WithLabelValues("failure", ReasonWrongPassword))
+	if after != before+1 {
+		t.Errorf("wrong password failures = %v, want %v", after, before+1)
+	}
+}
+
+func histogramCount(t *testing.T, o prometheus.Observer) uint64 {
+	t.Helper()
+
+	m, ok := o.(prometheus.Metric)
+	if !ok {
+		t.Fatalf("%T is not a metric", o)
+	}
+
+	var pb dto.Metric
+	if err := m.Write(&pb); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	return pb.GetHistogram().GetSampleCount()
+}
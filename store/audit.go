@@ -0,0 +1,136 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// GenesisHash is the previous hash of the first audit event.
+const GenesisHash = "0000000000000000000000000000000000000000000000000000000000000000"
+
+// AuditEvent is an entry in the append-only audit log. Each event includes the
+// hash of the one before it, so that changing or removing an event breaks the
+// chain for every event after it.
+type AuditEvent struct {
+	ID uuid.UUID `json:"id" db:"id"`
+	// Sequence is the event's position in the log, starting at 1.
+	Sequence   int64     `json:"sequence" db:"sequence"`
+	Type       string    `json:"type" db:"type"`
+	OccurredAt time.Time `json:"occurredAt" db:"occurred_at"`
+	ActorType  string    `json:"actorType" db:"actor_type"`
+	ActorID    *string   `json:"actorId" db:"actor_id"`
+	IPAddress  *string   `json:"ipAddress" db:"ip_address"`
+	RequestID  *string   `json:"requestId" db:"request_id"`
+	TargetType *string   `json:"targetType" db:"target_type"`
+	TargetID   *string   `json:"targetId" db:"target_id"`
+	PrevHash   string    `json:"prevHash" db:"prev_hash"`
+	Hash       string    `json:"hash" db:"hash"`
+}
+
+// ComputeHash returns the hash of the event's contents, including the hash of
+// the previous event. It doesn't include Hash itself, so it can be compared
+// with it to check that the event hasn't been changed.
+func (e AuditEvent) ComputeHash() string {
+	// Struct fields are always encoded in the same order, which keeps the hash
+	// stable. Times are converted to UTC since backends may return them in a
+	// different location than they were written in.
+	b, _ := json.Marshal(struct {
+		ID         uuid.UUID
+		Sequence   int64
+		Type       string
+		OccurredAt time.Time
+		ActorType  string
+		ActorID    *string
+		IPAddress  *string
+		RequestID  *string
+		TargetType *string
+		TargetID   *string
+		PrevHash   string
+	}{
+		e.ID,
+		e.Sequence,
+		e.Type,
+		e.OccurredAt.UTC(),
+		e.ActorType,
+		e.ActorID,
+		e.IPAddress,
+		e.RequestID,
+		e.TargetType,
+		e.TargetID,
+		e.PrevHash,
+	})
+
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+type NewAuditEvent struct {
+	Type string
+	// OccurredAt is stored with a precision of one second.
+	OccurredAt time.Time
+	ActorType  string
+	ActorID    *string
+	IPAddress  *string
+	RequestID  *string
+	TargetType *string
+	TargetID   *string
+}
+
+// AuditChainHead is the sequence and hash of the last event in the audit log.
+// Before the first event is appended, they are 0 and GenesisHash.
+type AuditChainHead struct {
+	Sequence int64  `db:"sequence"`
+	Hash     string `db:"hash"`
+}
+
+// AuditEventFilter narrows down the events returned by a list query. Unset
+// fields don't filter.
+type AuditEventFilter struct {
+	Type           *string
+	ActorID        *string
+	TargetType     *string
+	TargetID       *string
+	OccurredAfter  *time.Time
+	OccurredBefore *time.Time
+}
+
+type AuditEventListOptions struct {
+	Filter     AuditEventFilter
+	Sort       Sort
+	Pagination Pagination
+}
+
+// Validate checks that the events can be sorted as requested, and that the
+// cursor belongs to that sort.
+func (o AuditEventListOptions) Validate() error {
+	if err := o.Sort.validate(SortBySequence); err != nil {
+		return err
+	}
+
+	return o.Pagination.validate(o.Sort)
+}
+
+// AuditEventCursor returns the cursor that continues a list of events, sorted
+// by sort, after event.
+func AuditEventCursor(sort Sort, event AuditEvent) Cursor {
+	return Cursor{
+		Sort:  sort.String(),
+		Value: strconv.FormatInt(event.Sequence, 10),
+		ID:    event.ID,
+	}
+}
+
+type AuditRepository interface {
+	// AppendAuditEvent adds an event to the end of the audit log and returns
+	// it with its sequence and hashes. It must be called in a transaction,
+	// which holds a lock on the head of the log until it ends so that
+	// concurrent appends can't fork the chain.
+	AppendAuditEvent(event NewAuditEvent, opts QueryOptions) (AuditEvent, error)
+	GetAuditChainHead(opts QueryOptions) (AuditChainHead, error)
+	ListAuditEvents(list AuditEventListOptions, opts QueryOptions) ([]AuditEvent, error)
+}
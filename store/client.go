@@ -8,21 +8,31 @@ import (
 )
 
 type Client struct {
-	ID        uuid.UUID `json:"id" db:"id"`
-	Name      string    `json:"name" db:"name"`
-	Enabled   bool      `json:"enabled" db:"enabled"`
-	CreatedAt time.Time `json:"createdAt" db:"created_at"`
-	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
+	ID      uuid.UUID `json:"id" db:"id"`
+	Name    string    `json:"name" db:"name"`
+	Enabled bool      `json:"enabled" db:"enabled"`
+	// AllowedOrigins are browser origins, such as https://app.example.com, that
+	// can call the API cross-origin while the client is enabled. They aren't
+	// tied to the client: an allowed origin can call every route with any
+	// user's session, just like one in the server's config.
+	AllowedOrigins StringList `json:"allowedOrigins" db:"allowed_origins"`
+	CreatedAt      time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updatedAt" db:"updated_at"`
+	// Version is incremented each time the client is saved. Saving an outdated
+	// copy of a client fails with a VersionMismatchError.
+	Version int64 `json:"-" db:"version"`
 }
 
 type NewClient struct {
-	Name    string
-	Enabled bool
+	Name           string
+	Enabled        bool
+	AllowedOrigins []string
 }
 
 type ClientPatch struct {
-	Name    *string
-	Enabled *bool
+	Name           *string
+	Enabled        *bool
+	AllowedOrigins *[]string
 }
 
 func (p ClientPatch) ApplyTo(client Client) Client {
@@ -34,6 +44,10 @@ func (p ClientPatch) ApplyTo(client Client) Client {
 		client.Enabled = *p.Enabled
 	}
 
+	if p.AllowedOrigins != nil {
+		client.AllowedOrigins = *p.AllowedOrigins
+	}
+
 	return client
 }
 
@@ -54,12 +68,62 @@ type NewAPIKey struct {
 	Hash        string
 }
 
+// ClientFilter narrows down the clients returned by a list query. Unset fields
+// don't filter.
+type ClientFilter struct {
+	Name    *string
+	Enabled *bool
+}
+
+type ClientListOptions struct {
+	Filter     ClientFilter
+	Sort       Sort
+	Pagination Pagination
+}
+
+// Validate checks that the clients can be sorted as requested, and that the
+// cursor belongs to that sort.
+func (o ClientListOptions) Validate() error {
+	err := o.Sort.validate(SortByCreatedAt, SortByName)
+	if err != nil {
+		return err
+	}
+
+	return o.Pagination.validate(o.Sort)
+}
+
+// ClientCursor returns the cursor that continues a list of clients, sorted by
+// sort, after client.
+func ClientCursor(sort Sort, client Client) Cursor {
+	c := Cursor{Sort: sort.String(), ID: client.ID}
+
+	switch sort.Field {
+	case SortByCreatedAt:
+		c.Value = client.CreatedAt.UTC().Format(time.RFC3339Nano)
+	case SortByName:
+		c.Value = client.Name
+	}
+
+	return c
+}
+
 type ClientRepository interface {
-	ListClients(opts QueryOptions) ([]Client, error)
+	ListClients(list ClientListOptions, opts QueryOptions) ([]Client, error)
 	GetClientById(id uuid.UUID, opts QueryOptions) (Client, error)
 	InsertClient(user NewClient, opts QueryOptions) (uuid.UUID, error)
 	SaveClient(user Client, opts QueryOptions) error
+	// DeleteClient soft deletes a client. Deleted clients are treated as
+	// missing, and their API keys stop working, until they're purged.
 	DeleteClient(id uuid.UUID, opts QueryOptions) error
+	// RestoreClient undoes the soft deletion of a client that hasn't been
+	// purged.
+	RestoreClient(id uuid.UUID, opts QueryOptions) error
+	// PurgeDeletedClients permanently removes clients that were soft deleted
+	// before the given time, and returns how many were removed.
+	PurgeDeletedClients(before time.Time, opts QueryOptions) (int64, error)
+	// ListAllowedOrigins returns the allowed origins of every enabled client,
+	// without duplicates.
+	ListAllowedOrigins(opts QueryOptions) ([]string, error)
 
 	GetClientAPIKey(clientID uuid.UUID, prefix string, opts QueryOptions) (APIKey, error)
 	ListClientAPIKeys(clientID uuid.UUID, opts QueryOptions) ([]APIKey, error)
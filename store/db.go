@@ -14,6 +14,10 @@ type Repository interface {
 	AuthRepository
 	ClientRepository
 	SessionRepository
+	AuditRepository
+	WebhookRepository
+	EventRepository
+	HealthRepository
 }
 
 type TxBeginner interface {
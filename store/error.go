@@ -2,6 +2,8 @@ package store
 
 import "fmt"
 
+// NotFoundError is returned when the requested resource does not exist. Use
+// errors.Is(err, NotFoundError{}) to check for it regardless of the resource.
 type NotFoundError struct {
 	ResourceType string
 	ResourceID   string
@@ -10,3 +12,54 @@ type NotFoundError struct {
 func (e NotFoundError) Error() string {
 	return fmt.Sprintf("%s with ID %s not found", e.ResourceType, e.ResourceID)
 }
+
+func (e NotFoundError) Is(target error) bool {
+	_, ok := target.(NotFoundError)
+	return ok
+}
+
+// ConflictError is returned when a write conflicts with an existing resource,
+// e.g. by duplicating a value that must be unique.
+type ConflictError struct {
+	ResourceType string
+}
+
+func (e ConflictError) Error() string {
+	return fmt.Sprintf("%s already exists", e.ResourceType)
+}
+
+func (e ConflictError) Is(target error) bool {
+	_, ok := target.(ConflictError)
+	return ok
+}
+
+// ConstraintViolationError is returned when a write breaks a constraint other
+// than uniqueness, e.g. by referencing a resource that does not exist.
+type ConstraintViolationError struct {
+	ResourceType string
+}
+
+func (e ConstraintViolationError) Error() string {
+	return fmt.Sprintf("%s is invalid or references a resource that does not exist", e.ResourceType)
+}
+
+func (e ConstraintViolationError) Is(target error) bool {
+	_, ok := target.(ConstraintViolationError)
+	return ok
+}
+
+// VersionMismatchError is returned when saving a resource that has been changed
+// since it was read, so that the save doesn't silently overwrite the change.
+type VersionMismatchError struct {
+	ResourceType string
+	ResourceID   string
+}
+
+func (e VersionMismatchError) Error() string {
+	return fmt.Sprintf("%s with ID %s has been changed since it was read", e.ResourceType, e.ResourceID)
+}
+
+func (e VersionMismatchError) Is(target error) bool {
+	_, ok := target.(VersionMismatchError)
+	return ok
+}
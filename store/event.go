@@ -0,0 +1,64 @@
+package store
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// OutboxEvent is a domain event, such as a user being created, waiting in the
+// outbox to be handled by subscribers. Events are written in the same
+// transaction as the change that caused them, so they only exist if the change
+// was kept.
+type OutboxEvent struct {
+	ID uuid.UUID `db:"id"`
+	// Sequence orders the events. Sequences are assigned in the order that
+	// events are committed, so an event is never committed after one with a
+	// higher sequence has been read.
+	Sequence   int64     `db:"sequence"`
+	Type       string    `db:"type"`
+	OccurredAt time.Time `db:"occurred_at"`
+	ActorType  string    `db:"actor_type"`
+	ActorID    *string   `db:"actor_id"`
+	IPAddress  *string   `db:"ip_address"`
+	RequestID  *string   `db:"request_id"`
+	TargetType *string   `db:"target_type"`
+	TargetID   *string   `db:"target_id"`
+	// Data describes the event in more detail, e.g. the user that was
+	// created.
+	Data RawJSON `db:"data"`
+}
+
+type NewOutboxEvent struct {
+	Type       string
+	OccurredAt time.Time
+	ActorType  string
+	ActorID    *string
+	IPAddress  *string
+	RequestID  *string
+	TargetType *string
+	TargetID   *string
+	Data       RawJSON
+}
+
+type EventRepository interface {
+	// AppendOutboxEvent adds an event to the end of the outbox. opts.Txn must
+	// be set, and holds a lock on the end of the outbox until it ends so that
+	// events are committed in order.
+	AppendOutboxEvent(event NewOutboxEvent, opts QueryOptions) (OutboxEvent, error)
+	// ListOutboxEvents returns up to limit events with a sequence after the
+	// given one, in order.
+	ListOutboxEvents(after int64, limit int, opts QueryOptions) ([]OutboxEvent, error)
+	// GetEventSubscriberPosition returns the sequence of the last event
+	// handled by the named subscriber, or 0 if it hasn't handled any.
+	GetEventSubscriberPosition(name string, opts QueryOptions) (int64, error)
+	// SaveEventSubscriberPosition moves the named subscriber from one position
+	// to another. It fails with a VersionMismatchError if the subscriber is no
+	// longer at the from position, e.g. because another dispatcher moved it.
+	SaveEventSubscriberPosition(name string, from, to int64, opts QueryOptions) error
+	// DeleteDispatchedOutboxEvents removes events that occurred before the
+	// given time and have been handled by every one of the named subscribers,
+	// returning how many were removed. A subscriber that hasn't handled any
+	// events yet keeps them all.
+	DeleteDispatchedOutboxEvents(subscribers []string, before time.Time, opts QueryOptions) (int64, error)
+}
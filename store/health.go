@@ -0,0 +1,20 @@
+package store
+
+import "context"
+
+// MigrationVersion is the schema migration that a database has been migrated
+// to.
+type MigrationVersion struct {
+	Version uint `db:"version"`
+	// Dirty is set when the migration failed part way through, leaving the
+	// schema in an unknown state that must be fixed by hand.
+	Dirty bool `db:"dirty"`
+}
+
+type HealthRepository interface {
+	// Ping checks that the database can be reached.
+	Ping(ctx context.Context) error
+	// GetMigrationVersion returns the migration the database is at. A
+	// database that has never been migrated is at version 0.
+	GetMigrationVersion(opts QueryOptions) (MigrationVersion, error)
+}
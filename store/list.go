@@ -0,0 +1,125 @@
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// Fields that list queries can be sorted by.
+const (
+	SortByCreatedAt = "createdAt"
+	SortByName      = "name"
+	SortByFirstName = "firstName"
+	SortByLastName  = "lastName"
+	SortByScore     = "score"
+	SortBySequence  = "sequence"
+)
+
+var (
+	ErrInvalidSort   = errors.New("invalid sort")
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// Sort orders the results of a list query by a single field. Ties are always
+// broken by ID so that every item has a stable position to page from.
+type Sort struct {
+	Field      string
+	Descending bool
+}
+
+// ParseSort parses a sort such as "createdAt" or "-createdAt", where a leading
+// "-" sorts in descending order.
+func ParseSort(s string) Sort {
+	field, descending := strings.CutPrefix(s, "-")
+	return Sort{Field: field, Descending: descending}
+}
+
+func (s Sort) String() string {
+	if s.Descending {
+		return "-" + s.Field
+	}
+
+	return s.Field
+}
+
+func (s Sort) validate(fields ...string) error {
+	for _, field := range fields {
+		if s.Field == field {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s must be one of %s", ErrInvalidSort, s.Field, strings.Join(fields, ", "))
+}
+
+// Cursor is the position of the last item on a page. The next page starts with
+// the item immediately after it.
+type Cursor struct {
+	// Sort is the sort the cursor was created with. A cursor can't be used to
+	// page through results in a different order.
+	Sort string `json:"s"`
+	// Value is the last item's value for the sorted field.
+	Value string    `json:"v"`
+	ID    uuid.UUID `json:"id"`
+}
+
+// Encode returns the cursor in the opaque form handed out to clients.
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor parses a cursor previously returned by Encode.
+func DecodeCursor(s string) (Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	var c Cursor
+	if err = json.Unmarshal(b, &c); err != nil || c.ID == uuid.Nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	return c, nil
+}
+
+// Pagination selects a page of results. A limit of zero returns every result.
+type Pagination struct {
+	Limit int
+	After *Cursor
+}
+
+func (p Pagination) validate(sort Sort) error {
+	if p.After != nil && p.After.Sort != sort.String() {
+		return fmt.Errorf("%w: cursor was created for a different sort", ErrInvalidCursor)
+	}
+
+	return nil
+}
+
+// Page is a single page of results from a list query.
+type Page[T any] struct {
+	Items []T
+	// Next is where the following page starts. It's nil on the last page.
+	Next *Cursor
+}
+
+// NewPage builds a page from items fetched with a limit one higher than the
+// page size. The extra item, if present, shows that there is a next page and
+// is dropped from the results.
+func NewPage[T any](items []T, limit int, cursor func(T) Cursor) Page[T] {
+	if limit <= 0 || len(items) <= limit {
+		return Page[T]{Items: items}
+	}
+
+	items = items[:limit]
+	next := cursor(items[limit-1])
+
+	return Page[T]{Items: items, Next: &next}
+}
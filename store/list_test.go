@@ -0,0 +1,133 @@
+package store
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCursor_RoundTrip(t *testing.T) {
+	want := Cursor{Sort: "-createdAt", Value: "2023-01-01T00:00:00Z", ID: uuid.Must(uuid.NewV4())}
+
+	got, err := DecodeCursor(want.Encode())
+	if err != nil {
+		t.Fatalf("DecodeCursor() error = %v", err)
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("DecodeCursor() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDecodeCursor_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{name: "Not base64", cursor: "not a cursor!"},
+		{name: "Not JSON", cursor: "bm90IGpzb24"},
+		{name: "Missing ID", cursor: Cursor{Sort: "name", Value: "a"}.Encode()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeCursor(tt.cursor); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("DecodeCursor() error = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
+
+func TestParseSort(t *testing.T) {
+	tests := []struct {
+		sort string
+		want Sort
+	}{
+		{sort: "name", want: Sort{Field: "name"}},
+		{sort: "-name", want: Sort{Field: "name", Descending: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			got := ParseSort(tt.sort)
+			if got != tt.want {
+				t.Errorf("ParseSort() = %+v, want %+v", got, tt.want)
+			}
+
+			if got.String() != tt.sort {
+				t.Errorf("Sort.String() = %q, want %q", got.String(), tt.sort)
+			}
+		})
+	}
+}
+
+func TestNewPage(t *testing.T) {
+	cursor := func(i int) Cursor { return Cursor{Value: string(rune('a' + i))} }
+
+	tests := []struct {
+		name  string
+		items []int
+		limit int
+		want  Page[int]
+	}{
+		{
+			name:  "Extra item",
+			items: []int{0, 1, 2},
+			limit: 2,
+			want:  Page[int]{Items: []int{0, 1}, Next: &Cursor{Value: "b"}},
+		},
+		{
+			name:  "Last page",
+			items: []int{0, 1},
+			limit: 2,
+			want:  Page[int]{Items: []int{0, 1}},
+		},
+		{
+			name:  "No limit",
+			items: []int{0, 1, 2},
+			limit: 0,
+			want:  Page[int]{Items: []int{0, 1, 2}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewPage(tt.items, tt.limit, cursor)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("NewPage() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUserListOptions_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		options UserListOptions
+		wantErr error
+	}{
+		{
+			name:    "Valid",
+			options: UserListOptions{Sort: Sort{Field: SortByLastName, Descending: true}},
+		},
+		{
+			name:    "Unknown sort",
+			options: UserListOptions{Sort: Sort{Field: SortByName}},
+			wantErr: ErrInvalidSort,
+		},
+		{
+			name: "Cursor for another sort",
+			options: UserListOptions{
+				Sort:       Sort{Field: SortByCreatedAt},
+				Pagination: Pagination{After: &Cursor{Sort: "-createdAt"}},
+			},
+			wantErr: ErrInvalidCursor,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.options.Validate(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package mysql
+
+import (
+	"time"
+
+	"github.com/ninth-realm/heimdall/store"
+)
+
+func (db DB) AppendAuditEvent(event store.NewAuditEvent, opts store.QueryOptions) (store.AuditEvent, error) {
+	const lockQuery = `
+		UPDATE audit_chain
+		SET
+			sequence = sequence + 1
+		WHERE
+			id = 1
+	`
+
+	const insertQuery = `
+		INSERT INTO audit_event
+			(
+				id,
+				sequence,
+				type,
+				occurred_at,
+				actor_type,
+				actor_id,
+				ip_address,
+				request_id,
+				target_type,
+				target_id,
+				prev_hash,
+				hash
+			)
+		VALUES
+			(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	const headQuery = `
+		UPDATE audit_chain
+		SET
+			hash = ?
+		WHERE
+			id = 1
+	`
+
+	q := db.querier(opts.Txn)
+
+	// Taking the next sequence number first locks the head of the chain, so the
+	// previous hash read below can't change before this event is appended.
+	_, err := q.ExecContext(opts.Context(), lockQuery)
+	if err != nil {
+		return store.AuditEvent{}, err
+	}
+
+	head, err := db.GetAuditChainHead(opts)
+	if err != nil {
+		return store.AuditEvent{}, err
+	}
+
+	e := store.AuditEvent{
+		ID:         db.UUIDGenerator.GenerateUUID(),
+		Sequence:   head.Sequence,
+		Type:       event.Type,
+		OccurredAt: event.OccurredAt.UTC().Truncate(time.Second),
+		ActorType:  event.ActorType,
+		ActorID:    event.ActorID,
+		IPAddress:  event.IPAddress,
+		RequestID:  event.RequestID,
+		TargetType: event.TargetType,
+		TargetID:   event.TargetID,
+		PrevHash:   head.Hash,
+	}
+	e.Hash = e.ComputeHash()
+
+	_, err = q.ExecContext(
+		opts.Context(),
+		insertQuery,
+		e.ID,
+		e.Sequence,
+		e.Type,
+		e.OccurredAt,
+		e.ActorType,
+		e.ActorID,
+		e.IPAddress,
+		e.RequestID,
+		e.TargetType,
+		e.TargetID,
+		e.PrevHash,
+		e.Hash,
+	)
+	if err != nil {
+		return store.AuditEvent{}, mapError(err, "audit event", "")
+	}
+
+	_, err = q.ExecContext(opts.Context(), headQuery, e.Hash)
+	if err != nil {
+		return store.AuditEvent{}, err
+	}
+
+	return e, nil
+}
+
+func (db DB) GetAuditChainHead(opts store.QueryOptions) (store.AuditChainHead, error) {
+	const query = `
+		SELECT
+			sequence,
+			hash
+		FROM
+			audit_chain
+		WHERE
+			id = 1
+	`
+
+	var head store.AuditChainHead
+	err := db.querier(opts.Txn).GetContext(opts.Context(), &head, query)
+	if err != nil {
+		return store.AuditChainHead{}, mapError(err, "audit chain", "")
+	}
+
+	return head, nil
+}
+
+func (db DB) ListAuditEvents(list store.AuditEventListOptions, opts store.QueryOptions) ([]store.AuditEvent, error) {
+	const query = `
+		SELECT
+			id,
+			sequence,
+			type,
+			occurred_at,
+			actor_type,
+			actor_id,
+			ip_address,
+			request_id,
+			target_type,
+			target_id,
+			prev_hash,
+			hash
+		FROM
+			audit_event
+	`
+
+	var q listQuery
+	if list.Filter.Type != nil {
+		q.filter("type = ?", *list.Filter.Type)
+	}
+
+	if list.Filter.ActorID != nil {
+		q.filter("actor_id = ?", *list.Filter.ActorID)
+	}
+
+	if list.Filter.TargetType != nil {
+		q.filter("target_type = ?", *list.Filter.TargetType)
+	}
+
+	if list.Filter.TargetID != nil {
+		q.filter("target_id = ?", *list.Filter.TargetID)
+	}
+
+	if list.Filter.OccurredAfter != nil {
+		q.filter("occurred_at >= ?", *list.Filter.OccurredAfter)
+	}
+
+	if list.Filter.OccurredBefore != nil {
+		q.filter("occurred_at < ?", *list.Filter.OccurredBefore)
+	}
+
+	if err := q.paginate(list.Sort, list.Pagination); err != nil {
+		return nil, err
+	}
+
+	events := []store.AuditEvent{}
+	stmt, args := q.build(query)
+	err := db.querier(opts.Txn).SelectContext(opts.Context(), &events, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
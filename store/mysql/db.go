@@ -0,0 +1,114 @@
+// Package mysql implements store.Repository on top of a MySQL or MariaDB
+// database.
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/gofrs/uuid/v5"
+	"github.com/jmoiron/sqlx"
+	"github.com/ninth-realm/heimdall/store"
+)
+
+var _ store.Repository = (*DB)(nil)
+
+type DB struct {
+	Conn          *sqlx.DB
+	UUIDGenerator UUIDGenerator
+}
+
+type UUIDGenerator interface {
+	GenerateUUID() uuid.UUID
+}
+
+// NewDB connects to the database described by the provided DSN, e.g.
+//
+//	heimdall:secret@tcp(localhost:3306)/heimdall
+//
+// Timestamps are always parsed into UTC time.Time values. Updates report the
+// rows they matched rather than the rows they changed, the same as the other
+// backends, so that an update which leaves a row untouched isn't mistaken for
+// a missing row.
+func NewDB(dsn string) (DB, error) {
+	cfg, err := parseDSN(dsn)
+	if err != nil {
+		return DB{}, err
+	}
+
+	conn, err := sqlx.Open("mysql", cfg.FormatDSN())
+	if err != nil {
+		return DB{}, err
+	}
+
+	if err := conn.Ping(); err != nil {
+		return DB{}, err
+	}
+
+	return DB{Conn: conn}, nil
+}
+
+// OpenMigrationDB connects to the database described by the provided DSN for
+// running migrations, which hold several statements each. Unlike NewDB's
+// connections, these allow multiple statements per query. They must only be
+// used for migrations, since they'd let an injected query run extra
+// statements.
+func OpenMigrationDB(dsn string) (*sql.DB, error) {
+	cfg, err := parseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.MultiStatements = true
+
+	conn, err := sql.Open("mysql", cfg.FormatDSN())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.Ping(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+func parseDSN(dsn string) (*mysql.Config, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.ParseTime = true
+	cfg.Loc = time.UTC
+	cfg.ClientFoundRows = true
+
+	return cfg, nil
+}
+
+func (db DB) BeginTx(ctx context.Context) (*sqlx.Tx, error) {
+	return db.Conn.BeginTxx(ctx, nil)
+}
+
+// Close closes the database's connections once any queries in progress have
+// finished.
+func (db DB) Close() error {
+	return db.Conn.Close()
+}
+
+type Querier interface {
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func (db DB) querier(txn *sqlx.Tx) Querier {
+	if txn != nil {
+		return txn
+	}
+
+	return db.Conn
+}
@@ -0,0 +1,79 @@
+package mysql_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4"
+	migratemysql "github.com/golang-migrate/migrate/v4/database/mysql"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/ninth-realm/heimdall/store"
+	"github.com/ninth-realm/heimdall/store/mysql"
+	"github.com/ninth-realm/heimdall/store/storetest"
+)
+
+// dsnEnv names the environment variable holding the DSN of the database to
+// run the tests against. Every table in it is dropped before each test, so it
+// should never point at a database with data you care about.
+const dsnEnv = "HEIMDALL_TEST_MYSQL_DSN"
+
+func TestRepository(t *testing.T) {
+	dsn := os.Getenv(dsnEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", dsnEnv)
+	}
+
+	storetest.Run(t, func(t *testing.T) store.Repository {
+		return newTestDB(t, dsn)
+	})
+}
+
+// newTestDB drops every table in the database and migrates it from scratch.
+func newTestDB(t *testing.T, dsn string) store.Repository {
+	t.Helper()
+
+	db, err := mysql.NewDB(dsn)
+	if err != nil {
+		t.Fatalf("NewDB() error = %v", err)
+	}
+	t.Cleanup(func() { db.Conn.Close() })
+
+	db.UUIDGenerator = storetest.UUIDGenerator{}
+
+	m := newMigrate(t, dsn)
+	if err = m.Drop(); err != nil {
+		t.Fatalf("Drop() error = %v", err)
+	}
+
+	// Dropping also removes the migrations table, so a new instance is needed
+	// to recreate it.
+	m = newMigrate(t, dsn)
+	if err = m.Up(); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+
+	return db
+}
+
+func newMigrate(t *testing.T, dsn string) *migrate.Migrate {
+	t.Helper()
+
+	conn, err := mysql.OpenMigrationDB(dsn)
+	if err != nil {
+		t.Fatalf("OpenMigrationDB() error = %v", err)
+	}
+
+	driver, err := migratemysql.WithInstance(conn, &migratemysql.Config{})
+	if err != nil {
+		conn.Close()
+		t.Fatalf("mysql.WithInstance() error = %v", err)
+	}
+	t.Cleanup(func() { driver.Close() })
+
+	m, err := migrate.NewWithDatabaseInstance("file://../../db/migrations/mysql", "mysql", driver)
+	if err != nil {
+		t.Fatalf("migrate.NewWithDatabaseInstance() error = %v", err)
+	}
+
+	return m
+}
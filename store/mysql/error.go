@@ -0,0 +1,64 @@
+package mysql
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/gofrs/uuid/v5"
+	"github.com/ninth-realm/heimdall/store"
+)
+
+// MySQL error numbers for constraint violations.
+const (
+	errBadNull            = 1048
+	errDuplicateEntry     = 1062
+	errRowIsReferenced    = 1451
+	errNoReferencedRow    = 1452
+	errCheckConstraint    = 3819
+	errNoReferencedRowOld = 1216
+	errRowIsReferencedOld = 1217
+)
+
+// mapError converts driver errors into the typed errors defined by the store
+// package, so that callers never see MySQL specific errors or SQL. The
+// resource ID is only used to describe a missing resource. Unrecognized errors
+// are returned unchanged.
+func mapError(err error, resourceType, resourceID string) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return store.NotFoundError{ResourceType: resourceType, ResourceID: resourceID}
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return err
+	}
+
+	switch mysqlErr.Number {
+	case errDuplicateEntry:
+		return store.ConflictError{ResourceType: resourceType}
+	case errBadNull, errRowIsReferenced, errNoReferencedRow, errCheckConstraint,
+		errNoReferencedRowOld, errRowIsReferencedOld:
+		return store.ConstraintViolationError{ResourceType: resourceType}
+	}
+
+	return err
+}
+
+// staleOrMissing explains why saving a resource matched no rows. Saves only
+// match the version that was read, so either the resource has been changed
+// since then, or it no longer exists.
+func (db DB) staleOrMissing(table, resourceType string, id uuid.UUID, opts store.QueryOptions) error {
+	query := "SELECT COUNT(*) FROM " + table + " WHERE id = ? AND deleted_at IS NULL"
+
+	var n int
+	if err := db.querier(opts.Txn).GetContext(opts.Context(), &n, query, id); err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return store.NotFoundError{ResourceType: resourceType, ResourceID: id.String()}
+	}
+
+	return store.VersionMismatchError{ResourceType: resourceType, ResourceID: id.String()}
+}
@@ -0,0 +1,111 @@
+package mysql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ninth-realm/heimdall/store"
+)
+
+// sortColumns maps the fields that lists can be sorted by onto their columns.
+var sortColumns = map[string]string{
+	store.SortByCreatedAt: "created_at",
+	store.SortByName:      "name",
+	store.SortByFirstName: "first_name",
+	store.SortByLastName:  "last_name",
+	store.SortByScore:     "score",
+	store.SortBySequence:  "sequence",
+}
+
+// listQuery collects the filters, order and limit of a list query.
+type listQuery struct {
+	where   []string
+	args    []any
+	orderBy string
+	limit   int
+}
+
+func (q *listQuery) filter(condition string, args ...any) {
+	q.where = append(q.where, condition)
+	q.args = append(q.args, args...)
+}
+
+// paginate orders the results and skips past the cursor, if there is one. Rows
+// are also ordered by ID so that every row has a unique position, even when
+// several share the same value for the sorted column.
+func (q *listQuery) paginate(sort store.Sort, page store.Pagination) error {
+	column, ok := sortColumns[sort.Field]
+	if !ok {
+		return fmt.Errorf("%w: %s", store.ErrInvalidSort, sort.Field)
+	}
+
+	direction, comparison := "ASC", ">"
+	if sort.Descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	if page.After != nil {
+		var value any = page.After.Value
+		switch sort.Field {
+		case store.SortByCreatedAt:
+			t, err := time.Parse(time.RFC3339Nano, page.After.Value)
+			if err != nil {
+				return store.ErrInvalidCursor
+			}
+			value = t
+		case store.SortByScore:
+			score, err := strconv.ParseFloat(page.After.Value, 64)
+			if err != nil {
+				return store.ErrInvalidCursor
+			}
+			value = score
+		case store.SortBySequence:
+			sequence, err := strconv.ParseInt(page.After.Value, 10, 64)
+			if err != nil {
+				return store.ErrInvalidCursor
+			}
+			value = sequence
+		}
+
+		q.filter(
+			fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", column, comparison),
+			value, value, page.After.ID,
+		)
+	}
+
+	q.orderBy = fmt.Sprintf("%[1]s %[2]s, id %[2]s", column, direction)
+	q.limit = page.Limit
+
+	return nil
+}
+
+// build appends the collected clauses to the base SELECT query. Any arguments
+// for placeholders in the base query come before those of the clauses.
+func (q listQuery) build(base string, args ...any) (string, []any) {
+	query := base
+	args = append(args, q.args...)
+
+	if len(q.where) > 0 {
+		query += "\nWHERE\n\t" + strings.Join(q.where, "\n\tAND ")
+	}
+
+	if q.orderBy != "" {
+		query += "\nORDER BY " + q.orderBy
+	}
+
+	if q.limit > 0 {
+		query += "\nLIMIT ?"
+		args = append(args, q.limit)
+	}
+
+	return query, args
+}
+
+// likePrefix returns a case insensitive LIKE pattern, for use with ESCAPE '\\',
+// that matches strings starting with prefix.
+func likePrefix(prefix string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(strings.ToLower(prefix)) + "%"
+}
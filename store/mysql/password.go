@@ -0,0 +1,53 @@
+package mysql
+
+import (
+	"github.com/gofrs/uuid/v5"
+	"github.com/ninth-realm/heimdall/store"
+)
+
+func (db DB) InsertPassword(password store.NewPassword, opts store.QueryOptions) (uuid.UUID, error) {
+	const query = `
+		INSERT INTO password
+			(id, user_id, hash)
+		VALUES
+			(?, ?, ?)
+	`
+
+	id := db.UUIDGenerator.GenerateUUID()
+	_, err := db.querier(opts.Txn).ExecContext(
+		opts.Context(),
+		query,
+		id,
+		password.UserID,
+		password.Hash,
+	)
+	if err != nil {
+		return uuid.Nil, mapError(err, "password", "")
+	}
+
+	return id, nil
+}
+
+func (db DB) SavePassword(password store.NewPassword, opts store.QueryOptions) error {
+	const query = `
+		INSERT INTO password
+			(id, user_id, hash)
+		VALUES
+			(?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			hash = VALUES(hash)
+	`
+
+	_, err := db.querier(opts.Txn).ExecContext(
+		opts.Context(),
+		query,
+		db.UUIDGenerator.GenerateUUID(),
+		password.UserID,
+		password.Hash,
+	)
+	if err != nil {
+		return mapError(err, "password", "")
+	}
+
+	return nil
+}
@@ -0,0 +1,496 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/jmoiron/sqlx"
+)
+
+// Observer is told about each call made through an ObservedRepository. It is
+// called with the method's name before the method runs, and may return a new
+// context for the method to run with. The returned function is called with the
+// method's error once it has finished.
+type Observer func(ctx context.Context, method string) (context.Context, func(err error))
+
+// ObservedRepository wraps a Repository so that every call to it is reported to
+// an Observer, such as for measuring query latency. Calls are passed through to
+// Repo unchanged.
+type ObservedRepository struct {
+	Repo     Repository
+	Observer Observer
+}
+
+var _ Repository = ObservedRepository{}
+
+func (r ObservedRepository) observe(opts *QueryOptions, method string) func(error) {
+	ctx, done := r.Observer(opts.Context(), method)
+	opts.Ctx = ctx
+
+	return done
+}
+
+func (r ObservedRepository) BeginTx(ctx context.Context) (*sqlx.Tx, error) {
+	ctx, done := r.Observer(ctx, "BeginTx")
+	txn, err := r.Repo.BeginTx(ctx)
+	done(err)
+
+	return txn, err
+}
+
+func (r ObservedRepository) ListUsers(list UserListOptions, opts QueryOptions) ([]User, error) {
+	done := r.observe(&opts, "ListUsers")
+	v, err := r.Repo.ListUsers(list, opts)
+	done(err)
+
+	return v, err
+}
+
+func (r ObservedRepository) SearchUsers(search UserSearchOptions, opts QueryOptions) ([]UserSearchResult, error) {
+	done := r.observe(&opts, "SearchUsers")
+	v, err := r.Repo.SearchUsers(search, opts)
+	done(err)
+
+	return v, err
+}
+
+func (r ObservedRepository) GetUserById(id uuid.UUID, opts QueryOptions) (User, error) {
+	done := r.observe(&opts, "GetUserById")
+	v, err := r.Repo.GetUserById(id, opts)
+	done(err)
+
+	return v, err
+}
+
+func (r ObservedRepository) GetUserByEmail(email string, opts QueryOptions) (User, error) {
+	done := r.observe(&opts, "GetUserByEmail")
+	v, err := r.Repo.GetUserByEmail(email, opts)
+	done(err)
+
+	return v, err
+}
+
+func (r ObservedRepository) InsertUser(user NewUser, opts QueryOptions) (uuid.UUID, error) {
+	done := r.observe(&opts, "InsertUser")
+	v, err := r.Repo.InsertUser(user, opts)
+	done(err)
+
+	return v, err
+}
+
+func (r ObservedRepository) SaveUser(user User, opts QueryOptions) error {
+	done := r.observe(&opts, "SaveUser")
+	err := r.Repo.SaveUser(user, opts)
+	done(err)
+
+	return err
+}
+
+func (r ObservedRepository) DeleteUser(id uuid.UUID, opts QueryOptions) error {
+	done := r.observe(&opts, "DeleteUser")
+	err := r.Repo.DeleteUser(id, opts)
+	done(err)
+
+	return err
+}
+
+func (r ObservedRepository) RestoreUser(id uuid.UUID, opts QueryOptions) error {
+	done := r.observe(&opts, "RestoreUser")
+	err := r.Repo.RestoreUser(id, opts)
+	done(err)
+
+	return err
+}
+
+func (r ObservedRepository) PurgeDeletedUsers(before time.Time, opts QueryOptions) (int64, error) {
+	done := r.observe(&opts, "PurgeDeletedUsers")
+	v, err := r.Repo.PurgeDeletedUsers(before, opts)
+	done(err)
+
+	return v, err
+}
+
+func (r ObservedRepository) InsertPassword(password NewPassword, opts QueryOptions) (uuid.UUID, error) {
+	done := r.observe(&opts, "InsertPassword")
+	v, err := r.Repo.InsertPassword(password, opts)
+	done(err)
+
+	return v, err
+}
+
+func (r ObservedRepository) SavePassword(password NewPassword, opts QueryOptions) error {
+	done := r.observe(&opts, "SavePassword")
+	err := r.Repo.SavePassword(password, opts)
+	done(err)
+
+	return err
+}
+
+func (r ObservedRepository) InsertEmail(email NewEmail, opts QueryOptions) (uuid.UUID, error) {
+	done := r.observe(&opts, "InsertEmail")
+	v, err := r.Repo.InsertEmail(email, opts)
+	done(err)
+
+	return v, err
+}
+
+func (r ObservedRepository) GetUserPasswordHash(userID uuid.UUID, opts QueryOptions) (string, error) {
+	done := r.observe(&opts, "GetUserPasswordHash")
+	v, err := r.Repo.GetUserPasswordHash(userID, opts)
+	done(err)
+
+	return v, err
+}
+
+func (r ObservedRepository) ListClients(list ClientListOptions, opts QueryOptions) ([]Client, error) {
+	done := r.observe(&opts, "ListClients")
+	v, err := r.Repo.ListClients(list, opts)
+	done(err)
+
+	return v, err
+}
+
+func (r ObservedRepository) GetClientById(id uuid.UUID, opts QueryOptions) (Client, error) {
+	done := r.observe(&opts, "GetClientById")
+	v, err := r.Repo.GetClientById(id, opts)
+	done(err)
+
+	return v, err
+}
+
+func (r ObservedRepository) InsertClient(user NewClient, opts QueryOptions) (uuid.UUID, error) {
+	done := r.observe(&opts, "InsertClient")
+	v, err := r.Repo.InsertClient(user, opts)
+	done(err)
+
+	return v, err
+}
+
+func (r ObservedRepository) SaveClient(user Client, opts QueryOptions) error {
+	done := r.observe(&opts, "SaveClient")
+	err := r.Repo.SaveClient(user, opts)
+	done(err)
+
+	return err
+}
+
+func (r ObservedRepository) DeleteClient(id uuid.UUID, opts QueryOptions) error {
+	done := r.observe(&opts, "DeleteClient")
+	err := r.Repo.DeleteClient(id, opts)
+	done(err)
+
+	return err
+}
+
+func (r ObservedRepository) RestoreClient(id uuid.UUID, opts QueryOptions) error {
+	done := r.observe(&opts, "RestoreClient")
+	err := r.Repo.RestoreClient(id, opts)
+	done(err)
+
+	return err
+}
+
+func (r ObservedRepository) PurgeDeletedClients(before time.Time, opts QueryOptions) (int64, error) {
+	done := r.observe(&opts, "PurgeDeletedClients")
+	v, err := r.Repo.PurgeDeletedClients(before, opts)
+	done(err)
+
+	return v, err
+}
+
+func (r ObservedRepository) ListAllowedOrigins(opts QueryOptions) ([]string, error) {
+	done := r.observe(&opts, "ListAllowedOrigins")
+	v, err := r.Repo.ListAllowedOrigins(opts)
+	done(err)
+
+	return v, err
+}
+
+func (r ObservedRepository) GetClientAPIKey(clientID uuid.UUID, prefix string, opts QueryOptions) (APIKey, error) {
+	done := r.observe(&opts, "GetClientAPIKey")
+	v, err := r.Repo.GetClientAPIKey(clientID, prefix, opts)
+	done(err)
+
+	return v, err
+}
+
+func (r ObservedRepository) ListClientAPIKeys(clientID uuid.UUID, opts QueryOptions) ([]APIKey, error) {
+	done := r.observe(&opts, "ListClientAPIKeys")
+	v, err := r.Repo.ListClientAPIKeys(clientID, opts)
+	done(err)
+
+	return v, err
+}
+
+func (r ObservedRepository) InsertAPIKey(key NewAPIKey, opts QueryOptions) (uuid.UUID, error) {
+	done := r.observe(&opts, "InsertAPIKey")
+	v, err := r.Repo.InsertAPIKey(key, opts)
+	done(err)
+
+	return v, err
+}
+
+func (r ObservedRepository) DeleteClientAPIKey(clientID, keyID uuid.UUID, opts QueryOptions) error {
+	done := r.observe(&opts, "DeleteClientAPIKey")
+	err := r.Repo.DeleteClientAPIKey(clientID, keyID, opts)
+	done(err)
+
+	return err
+}
+
+func (r ObservedRepository) GetSession(tokenHash string, opts QueryOptions) (Session, error) {
+	done := r.observe(&opts, "GetSession")
+	v, err := r.Repo.GetSession(tokenHash, opts)
+	done(err)
+
+	return v, err
+}
+
+func (r ObservedRepository) InsertSession(session NewSession, opts QueryOptions) (uuid.UUID, error) {
+	done := r.observe(&opts, "InsertSession")
+	v, err := r.Repo.InsertSession(session, opts)
+	done(err)
+
+	return v, err
+}
+
+func (r ObservedRepository) TouchSession(id uuid.UUID, lastSeenAt, expiresAt time.Time, opts QueryOptions) error {
+	done := r.observe(&opts, "TouchSession")
+	err := r.Repo.TouchSession(id, lastSeenAt, expiresAt, opts)
+	done(err)
+
+	return err
+}
+
+func (r ObservedRepository) ReauthenticateSession(id uuid.UUID, authenticatedAt time.Time, opts QueryOptions) error {
+	done := r.observe(&opts, "ReauthenticateSession")
+	err := r.Repo.ReauthenticateSession(id, authenticatedAt, opts)
+	done(err)
+
+	return err
+}
+
+func (r ObservedRepository) DeleteSession(tokenHash string, opts QueryOptions) error {
+	done := r.observe(&opts, "DeleteSession")
+	err := r.Repo.DeleteSession(tokenHash, opts)
+	done(err)
+
+	return err
+}
+
+func (r ObservedRepository) ListUserSessions(userID uuid.UUID, opts QueryOptions) ([]Session, error) {
+	done := r.observe(&opts, "ListUserSessions")
+	v, err := r.Repo.ListUserSessions(userID, opts)
+	done(err)
+
+	return v, err
+}
+
+func (r ObservedRepository) DeleteUserSession(userID, sessionID uuid.UUID, opts QueryOptions) error {
+	done := r.observe(&opts, "DeleteUserSession")
+	err := r.Repo.DeleteUserSession(userID, sessionID, opts)
+	done(err)
+
+	return err
+}
+
+func (r ObservedRepository) DeleteUserSessions(userID, keepID uuid.UUID, opts QueryOptions) error {
+	done := r.observe(&opts, "DeleteUserSessions")
+	err := r.Repo.DeleteUserSessions(userID, keepID, opts)
+	done(err)
+
+	return err
+}
+
+func (r ObservedRepository) DeleteExpiredSessions(before time.Time, opts QueryOptions) (int64, error) {
+	done := r.observe(&opts, "DeleteExpiredSessions")
+	v, err := r.Repo.DeleteExpiredSessions(before, opts)
+	done(err)
+
+	return v, err
+}
+
+func (r ObservedRepository) CountActiveSessions(at time.Time, opts QueryOptions) (int64, error) {
+	done := r.observe(&opts, "CountActiveSessions")
+	v, err := r.Repo.CountActiveSessions(at, opts)
+	done(err)
+
+	return v, err
+}
+
+func (r ObservedRepository) AppendAuditEvent(event NewAuditEvent, opts QueryOptions) (AuditEvent, error) {
+	done := r.observe(&opts, "AppendAuditEvent")
+	v, err := r.Repo.AppendAuditEvent(event, opts)
+	done(err)
+
+	return v, err
+}
+
+func (r ObservedRepository) GetAuditChainHead(opts QueryOptions) (AuditChainHead, error) {
+	done := r.observe(&opts, "GetAuditChainHead")
+	v, err := r.Repo.GetAuditChainHead(opts)
+	done(err)
+
+	return v, err
+}
+
+func (r ObservedRepository) ListAuditEvents(list AuditEventListOptions, opts QueryOptions) ([]AuditEvent, error) {
+	done := r.observe(&opts, "ListAuditEvents")
+	v, err := r.Repo.ListAuditEvents(list, opts)
+	done(err)
+
+	return v, err
+}
+
+func (r ObservedRepository) ListWebhooks(opts QueryOptions) ([]Webhook, error) {
+	done := r.observe(&opts, "ListWebhooks")
+	v, err := r.Repo.ListWebhooks(opts)
+	done(err)
+
+	return v, err
+}
+
+func (r ObservedRepository) GetWebhook(id uuid.UUID, opts QueryOptions) (Webhook, error) {
+	done := r.observe(&opts, "GetWebhook")
+	v, err := r.Repo.GetWebhook(id, opts)
+	done(err)
+
+	return v, err
+}
+
+func (r ObservedRepository) InsertWebhook(webhook NewWebhook, opts QueryOptions) (uuid.UUID, error) {
+	done := r.observe(&opts, "InsertWebhook")
+	v, err := r.Repo.InsertWebhook(webhook, opts)
+	done(err)
+
+	return v, err
+}
+
+func (r ObservedRepository) SaveWebhook(webhook Webhook, opts QueryOptions) error {
+	done := r.observe(&opts, "SaveWebhook")
+	err := r.Repo.SaveWebhook(webhook, opts)
+	done(err)
+
+	return err
+}
+
+func (r ObservedRepository) DeleteWebhook(id uuid.UUID, opts QueryOptions) error {
+	done := r.observe(&opts, "DeleteWebhook")
+	err := r.Repo.DeleteWebhook(id, opts)
+	done(err)
+
+	return err
+}
+
+func (r ObservedRepository) InsertWebhookDelivery(delivery NewWebhookDelivery, opts QueryOptions) (uuid.UUID, error) {
+	done := r.observe(&opts, "InsertWebhookDelivery")
+	v, err := r.Repo.InsertWebhookDelivery(delivery, opts)
+	done(err)
+
+	return v, err
+}
+
+func (r ObservedRepository) GetWebhookDelivery(webhookID, deliveryID uuid.UUID, opts QueryOptions) (WebhookDelivery, error) {
+	done := r.observe(&opts, "GetWebhookDelivery")
+	v, err := r.Repo.GetWebhookDelivery(webhookID, deliveryID, opts)
+	done(err)
+
+	return v, err
+}
+
+func (r ObservedRepository) ListWebhookDeliveries(webhookID uuid.UUID, list WebhookDeliveryListOptions, opts QueryOptions) ([]WebhookDelivery, error) {
+	done := r.observe(&opts, "ListWebhookDeliveries")
+	v, err := r.Repo.ListWebhookDeliveries(webhookID, list, opts)
+	done(err)
+
+	return v, err
+}
+
+func (r ObservedRepository) ListDueWebhookDeliveries(now time.Time, limit int, opts QueryOptions) ([]WebhookDelivery, error) {
+	done := r.observe(&opts, "ListDueWebhookDeliveries")
+	v, err := r.Repo.ListDueWebhookDeliveries(now, limit, opts)
+	done(err)
+
+	return v, err
+}
+
+func (r ObservedRepository) ClaimWebhookDelivery(id uuid.UUID, attempts int, until time.Time, opts QueryOptions) error {
+	done := r.observe(&opts, "ClaimWebhookDelivery")
+	err := r.Repo.ClaimWebhookDelivery(id, attempts, until, opts)
+	done(err)
+
+	return err
+}
+
+func (r ObservedRepository) FinishWebhookDeliveryAttempt(attempt NewWebhookDeliveryAttempt, status string, nextAttemptAt time.Time, opts QueryOptions) error {
+	done := r.observe(&opts, "FinishWebhookDeliveryAttempt")
+	err := r.Repo.FinishWebhookDeliveryAttempt(attempt, status, nextAttemptAt, opts)
+	done(err)
+
+	return err
+}
+
+func (r ObservedRepository) ListWebhookDeliveryAttempts(deliveryID uuid.UUID, opts QueryOptions) ([]WebhookDeliveryAttempt, error) {
+	done := r.observe(&opts, "ListWebhookDeliveryAttempts")
+	v, err := r.Repo.ListWebhookDeliveryAttempts(deliveryID, opts)
+	done(err)
+
+	return v, err
+}
+
+func (r ObservedRepository) AppendOutboxEvent(event NewOutboxEvent, opts QueryOptions) (OutboxEvent, error) {
+	done := r.observe(&opts, "AppendOutboxEvent")
+	v, err := r.Repo.AppendOutboxEvent(event, opts)
+	done(err)
+
+	return v, err
+}
+
+func (r ObservedRepository) ListOutboxEvents(after int64, limit int, opts QueryOptions) ([]OutboxEvent, error) {
+	done := r.observe(&opts, "ListOutboxEvents")
+	v, err := r.Repo.ListOutboxEvents(after, limit, opts)
+	done(err)
+
+	return v, err
+}
+
+func (r ObservedRepository) GetEventSubscriberPosition(name string, opts QueryOptions) (int64, error) {
+	done := r.observe(&opts, "GetEventSubscriberPosition")
+	v, err := r.Repo.GetEventSubscriberPosition(name, opts)
+	done(err)
+
+	return v, err
+}
+
+func (r ObservedRepository) SaveEventSubscriberPosition(name string, from, to int64, opts QueryOptions) error {
+	done := r.observe(&opts, "SaveEventSubscriberPosition")
+	err := r.Repo.SaveEventSubscriberPosition(name, from, to, opts)
+	done(err)
+
+	return err
+}
+
+func (r ObservedRepository) DeleteDispatchedOutboxEvents(subscribers []string, before time.Time, opts QueryOptions) (int64, error) {
+	done := r.observe(&opts, "DeleteDispatchedOutboxEvents")
+	v, err := r.Repo.DeleteDispatchedOutboxEvents(subscribers, before, opts)
+	done(err)
+
+	return v, err
+}
+
+func (r ObservedRepository) Ping(ctx context.Context) error {
+	ctx, done := r.Observer(ctx, "Ping")
+	err := r.Repo.Ping(ctx)
+	done(err)
+
+	return err
+}
+
+func (r ObservedRepository) GetMigrationVersion(opts QueryOptions) (MigrationVersion, error) {
+	done := r.observe(&opts, "GetMigrationVersion")
+	v, err := r.Repo.GetMigrationVersion(opts)
+	done(err)
+
+	return v, err
+}
@@ -21,4 +21,6 @@ type NewPassword struct {
 
 type PasswordRepository interface {
 	InsertPassword(password NewPassword, opts QueryOptions) (uuid.UUID, error)
+	// SavePassword sets the password hash for a user, replacing any existing hash.
+	SavePassword(password NewPassword, opts QueryOptions) error
 }
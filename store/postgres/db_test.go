@@ -0,0 +1,63 @@
+package postgres_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4"
+	migratepg "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/ninth-realm/heimdall/store"
+	"github.com/ninth-realm/heimdall/store/postgres"
+	"github.com/ninth-realm/heimdall/store/storetest"
+)
+
+// dsnEnv names the environment variable holding the DSN of the database to
+// run the tests against. Everything in its public schema is dropped before
+// each test, so it should never point at a database with data you care about.
+const dsnEnv = "HEIMDALL_TEST_POSTGRES_DSN"
+
+func TestRepository(t *testing.T) {
+	dsn := os.Getenv(dsnEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", dsnEnv)
+	}
+
+	storetest.Run(t, func(t *testing.T) store.Repository {
+		return newTestDB(t, dsn)
+	})
+}
+
+// newTestDB resets the database's public schema and migrates it from scratch.
+func newTestDB(t *testing.T, dsn string) store.Repository {
+	t.Helper()
+
+	db, err := postgres.NewDB(dsn)
+	if err != nil {
+		t.Fatalf("NewDB() error = %v", err)
+	}
+	t.Cleanup(func() { db.Conn.Close() })
+
+	db.UUIDGenerator = storetest.UUIDGenerator{}
+
+	_, err = db.Conn.Exec(`DROP SCHEMA public CASCADE; CREATE SCHEMA public;`)
+	if err != nil {
+		t.Fatalf("resetting schema: %v", err)
+	}
+
+	driver, err := migratepg.WithInstance(db.Conn.DB, &migratepg.Config{})
+	if err != nil {
+		t.Fatalf("postgres.WithInstance() error = %v", err)
+	}
+
+	m, err := migrate.NewWithDatabaseInstance("file://../../db/migrations/postgres", "postgres", driver)
+	if err != nil {
+		t.Fatalf("migrate.NewWithDatabaseInstance() error = %v", err)
+	}
+
+	if err = m.Up(); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+
+	return db
+}
@@ -0,0 +1,57 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/lib/pq"
+	"github.com/ninth-realm/heimdall/store"
+)
+
+// uniqueViolation is the SQLSTATE for a unique key violation. Every other code
+// in its integrity_constraint_violation class is treated as a generic
+// constraint violation.
+const uniqueViolation = "23505"
+
+// mapError converts driver errors into the typed errors defined by the store
+// package, so that callers never see Postgres specific errors or SQL. The
+// resource ID is only used to describe a missing resource. Unrecognized errors
+// are returned unchanged.
+func mapError(err error, resourceType, resourceID string) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return store.NotFoundError{ResourceType: resourceType, ResourceID: resourceID}
+	}
+
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+
+	switch {
+	case pqErr.Code == uniqueViolation:
+		return store.ConflictError{ResourceType: resourceType}
+	case pqErr.Code.Class() == "23":
+		return store.ConstraintViolationError{ResourceType: resourceType}
+	}
+
+	return err
+}
+
+// staleOrMissing explains why saving a resource matched no rows. Saves only
+// match the version that was read, so either the resource has been changed
+// since then, or it no longer exists.
+func (db DB) staleOrMissing(table, resourceType string, id uuid.UUID, opts store.QueryOptions) error {
+	query := "SELECT COUNT(*) FROM " + table + " WHERE id = $1 AND deleted_at IS NULL"
+
+	var n int
+	if err := db.querier(opts.Txn).GetContext(opts.Context(), &n, query, id); err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return store.NotFoundError{ResourceType: resourceType, ResourceID: id.String()}
+	}
+
+	return store.VersionMismatchError{ResourceType: resourceType, ResourceID: id.String()}
+}
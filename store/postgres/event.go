@@ -0,0 +1,224 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/ninth-realm/heimdall/store"
+)
+
+func (db DB) AppendOutboxEvent(event store.NewOutboxEvent, opts store.QueryOptions) (store.OutboxEvent, error) {
+	const lockQuery = `
+		UPDATE event_outbox_head
+		SET
+			sequence = sequence + 1
+		WHERE
+			id = 1
+	`
+
+	const sequenceQuery = `
+		SELECT
+			sequence
+		FROM
+			event_outbox_head
+		WHERE
+			id = 1
+	`
+
+	const insertQuery = `
+		INSERT INTO event_outbox
+			(
+				id,
+				sequence,
+				type,
+				occurred_at,
+				actor_type,
+				actor_id,
+				ip_address,
+				request_id,
+				target_type,
+				target_id,
+				data
+			)
+		VALUES
+			($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+
+	q := db.querier(opts.Txn)
+
+	// Taking the next sequence number locks the head of the outbox until the
+	// transaction ends, so events are committed in sequence order.
+	_, err := q.ExecContext(opts.Context(), lockQuery)
+	if err != nil {
+		return store.OutboxEvent{}, err
+	}
+
+	e := store.OutboxEvent{
+		ID:         db.UUIDGenerator.GenerateUUID(),
+		Type:       event.Type,
+		OccurredAt: event.OccurredAt.UTC().Truncate(time.Second),
+		ActorType:  event.ActorType,
+		ActorID:    event.ActorID,
+		IPAddress:  event.IPAddress,
+		RequestID:  event.RequestID,
+		TargetType: event.TargetType,
+		TargetID:   event.TargetID,
+		Data:       event.Data,
+	}
+
+	err = q.GetContext(opts.Context(), &e.Sequence, sequenceQuery)
+	if err != nil {
+		return store.OutboxEvent{}, err
+	}
+
+	_, err = q.ExecContext(
+		opts.Context(),
+		insertQuery,
+		e.ID,
+		e.Sequence,
+		e.Type,
+		e.OccurredAt,
+		e.ActorType,
+		e.ActorID,
+		e.IPAddress,
+		e.RequestID,
+		e.TargetType,
+		e.TargetID,
+		e.Data,
+	)
+	if err != nil {
+		return store.OutboxEvent{}, mapError(err, "outbox event", "")
+	}
+
+	return e, nil
+}
+
+func (db DB) ListOutboxEvents(after int64, limit int, opts store.QueryOptions) ([]store.OutboxEvent, error) {
+	const query = `
+		SELECT
+			id,
+			sequence,
+			type,
+			occurred_at,
+			actor_type,
+			actor_id,
+			ip_address,
+			request_id,
+			target_type,
+			target_id,
+			data
+		FROM
+			event_outbox
+		WHERE
+			sequence > $1
+		ORDER BY
+			sequence
+		LIMIT $2
+	`
+
+	events := []store.OutboxEvent{}
+	err := db.querier(opts.Txn).SelectContext(opts.Context(), &events, query, after, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (db DB) GetEventSubscriberPosition(name string, opts store.QueryOptions) (int64, error) {
+	const query = `
+		SELECT
+			position
+		FROM
+			event_subscriber
+		WHERE
+			name = $1
+	`
+
+	var position int64
+	err := db.querier(opts.Txn).GetContext(opts.Context(), &position, query, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	return position, nil
+}
+
+func (db DB) SaveEventSubscriberPosition(name string, from, to int64, opts store.QueryOptions) error {
+	const insertQuery = `
+		INSERT INTO event_subscriber
+			(name, position)
+		VALUES
+			($1, $2)
+	`
+
+	const updateQuery = `
+		UPDATE event_subscriber
+		SET
+			position = $1
+		WHERE
+			name = $2
+			AND position = $3
+	`
+
+	mismatch := store.VersionMismatchError{ResourceType: "event subscriber", ResourceID: name}
+
+	// Subscribers start at 0 without a row, so their first move creates one.
+	if from == 0 {
+		_, err := db.querier(opts.Txn).ExecContext(opts.Context(), insertQuery, name, to)
+		if err = mapError(err, "event subscriber", name); errors.Is(err, store.ConflictError{}) {
+			return mismatch
+		}
+
+		return err
+	}
+
+	res, err := db.querier(opts.Txn).ExecContext(opts.Context(), updateQuery, to, name, from)
+	if err != nil {
+		return mapError(err, "event subscriber", name)
+	}
+
+	if n, err := res.RowsAffected(); n == 0 {
+		return mismatch
+	} else if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (db DB) DeleteDispatchedOutboxEvents(subscribers []string, before time.Time, opts store.QueryOptions) (int64, error) {
+	const query = `
+		DELETE FROM event_outbox
+		WHERE
+			occurred_at < $1
+			AND sequence <= $2
+	`
+
+	if len(subscribers) == 0 {
+		return 0, nil
+	}
+
+	// Subscribers that haven't handled any events yet have no position saved,
+	// and hold up every event.
+	handled := int64(math.MaxInt64)
+	for _, name := range subscribers {
+		position, err := db.GetEventSubscriberPosition(name, opts)
+		if err != nil {
+			return 0, err
+		}
+
+		handled = min(handled, position)
+	}
+
+	res, err := db.querier(opts.Txn).ExecContext(opts.Context(), query, before, handled)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
@@ -0,0 +1,292 @@
+package postgres
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/ninth-realm/heimdall/store"
+)
+
+func (db DB) ListUsers(list store.UserListOptions, opts store.QueryOptions) ([]store.User, error) {
+	const query = `
+		SELECT
+			id,
+			first_name,
+			last_name,
+			created_at,
+			updated_at,
+			version
+		FROM
+			"user"
+	`
+
+	var q listQuery
+	q.filter("deleted_at IS NULL")
+	if list.Filter.Email != nil {
+		q.filter("id IN (SELECT user_id FROM email WHERE email = ?)", *list.Filter.Email)
+	}
+
+	if list.Filter.NamePrefix != nil {
+		pattern := likePrefix(*list.Filter.NamePrefix)
+		q.filter(`(LOWER(first_name) LIKE ? ESCAPE '\' OR LOWER(last_name) LIKE ? ESCAPE '\')`, pattern, pattern)
+	}
+
+	if list.Filter.CreatedAfter != nil {
+		q.filter("created_at >= ?", *list.Filter.CreatedAfter)
+	}
+
+	if list.Filter.CreatedBefore != nil {
+		q.filter("created_at < ?", *list.Filter.CreatedBefore)
+	}
+
+	if err := q.paginate(list.Sort, list.Pagination); err != nil {
+		return nil, err
+	}
+
+	users := []store.User{}
+	stmt, args := q.build(query)
+	err := db.querier(opts.Txn).SelectContext(opts.Context(), &users, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// SearchUsers uses the user_search table's tsvector documents. Users with
+// several matching email addresses are returned once, with the best match.
+func (db DB) SearchUsers(search store.UserSearchOptions, opts store.QueryOptions) ([]store.UserSearchResult, error) {
+	const query = `
+		SELECT
+			id,
+			first_name,
+			last_name,
+			created_at,
+			updated_at,
+			version,
+			email,
+			score
+		FROM (
+			SELECT DISTINCT ON (u.id)
+				u.id,
+				u.first_name,
+				u.last_name,
+				u.created_at,
+				u.updated_at,
+				u.version,
+				e.email,
+				ts_rank(s.document, q.query)::FLOAT8 AS score
+			FROM
+				user_search s
+				INNER JOIN email e ON e.id = s.email_id
+				INNER JOIN "user" u ON u.id = s.user_id
+				CROSS JOIN to_tsquery('simple', ?) q(query)
+			WHERE
+				s.document @@ q.query
+				AND u.deleted_at IS NULL
+			ORDER BY
+				u.id, score DESC, e.email
+		) results`
+
+	terms := store.SearchTerms(search.Query)
+	if len(terms) == 0 {
+		return []store.UserSearchResult{}, nil
+	}
+
+	// Every term must match the start of a word in the document.
+	prefixes := make([]string, len(terms))
+	for i, term := range terms {
+		prefixes[i] = term + ":*"
+	}
+
+	var q listQuery
+	if err := q.paginate(store.UserSearchSort, search.Pagination); err != nil {
+		return nil, err
+	}
+
+	results := []store.UserSearchResult{}
+	stmt, args := q.build(query, strings.Join(prefixes, " & "))
+	err := db.querier(opts.Txn).SelectContext(opts.Context(), &results, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (db DB) GetUserById(id uuid.UUID, opts store.QueryOptions) (store.User, error) {
+	const query = `
+		SELECT
+			id,
+			first_name,
+			last_name,
+			created_at,
+			updated_at,
+			version
+		FROM
+			"user"
+		WHERE
+			id = $1
+			AND deleted_at IS NULL
+	`
+
+	var user store.User
+	err := db.querier(opts.Txn).GetContext(opts.Context(), &user, query, id)
+
+	if err != nil {
+		return store.User{}, mapError(err, "user", id.String())
+	}
+
+	return user, nil
+}
+
+func (db DB) GetUserByEmail(email string, opts store.QueryOptions) (store.User, error) {
+	const query = `
+		SELECT
+			u.id,
+			u.first_name,
+			u.last_name,
+			u.created_at,
+			u.updated_at,
+			u.version
+		FROM
+			"user" u
+		INNER JOIN email e ON
+			u.id = e.user_id
+		WHERE
+			e.email = $1
+			AND u.deleted_at IS NULL
+	`
+
+	var user store.User
+	err := db.querier(opts.Txn).GetContext(opts.Context(), &user, query, email)
+
+	if err != nil {
+		return store.User{}, mapError(err, "user", email)
+	}
+
+	return user, nil
+}
+
+func (db DB) InsertUser(user store.NewUser, opts store.QueryOptions) (uuid.UUID, error) {
+	const query = `
+		INSERT INTO "user"
+			(id, first_name, last_name)
+		VALUES
+			($1, $2, $3)
+	`
+
+	id := db.UUIDGenerator.GenerateUUID()
+	_, err := db.querier(opts.Txn).ExecContext(
+		opts.Context(),
+		query,
+		id,
+		user.FirstName,
+		user.LastName,
+	)
+
+	if err != nil {
+		return uuid.Nil, mapError(err, "user", "")
+	}
+
+	return id, nil
+}
+
+func (db DB) SaveUser(user store.User, opts store.QueryOptions) error {
+	const query = `
+		UPDATE "user"
+		SET
+			first_name = $1,
+			last_name = $2,
+			version = version + 1
+		WHERE
+			id = $3
+			AND version = $4
+			AND deleted_at IS NULL
+	`
+
+	res, err := db.querier(opts.Txn).ExecContext(
+		opts.Context(),
+		query,
+		user.FirstName,
+		user.LastName,
+		user.ID,
+		user.Version,
+	)
+
+	if err != nil {
+		return mapError(err, "user", user.ID.String())
+	}
+
+	if n, err := res.RowsAffected(); n == 0 {
+		return db.staleOrMissing(`"user"`, "user", user.ID, opts)
+	} else if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (db DB) DeleteUser(id uuid.UUID, opts store.QueryOptions) error {
+	const query = `
+		UPDATE "user"
+		SET
+			deleted_at = CURRENT_TIMESTAMP
+		WHERE
+			id = $1
+			AND deleted_at IS NULL
+	`
+
+	res, err := db.querier(opts.Txn).ExecContext(opts.Context(), query, id)
+	if err != nil {
+		return mapError(err, "user", id.String())
+	}
+
+	if n, err := res.RowsAffected(); n == 0 {
+		return store.NotFoundError{ResourceType: "user", ResourceID: id.String()}
+	} else if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (db DB) RestoreUser(id uuid.UUID, opts store.QueryOptions) error {
+	const query = `
+		UPDATE "user"
+		SET
+			deleted_at = NULL
+		WHERE
+			id = $1
+			AND deleted_at IS NOT NULL
+	`
+
+	res, err := db.querier(opts.Txn).ExecContext(opts.Context(), query, id)
+	if err != nil {
+		return mapError(err, "user", id.String())
+	}
+
+	if n, err := res.RowsAffected(); n == 0 {
+		return store.NotFoundError{ResourceType: "deleted user", ResourceID: id.String()}
+	} else if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (db DB) PurgeDeletedUsers(before time.Time, opts store.QueryOptions) (int64, error) {
+	const query = `
+		DELETE FROM "user"
+		WHERE
+			deleted_at < $1
+	`
+
+	res, err := db.querier(opts.Txn).ExecContext(opts.Context(), query, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
@@ -6,15 +6,54 @@ import (
 	"github.com/gofrs/uuid/v5"
 )
 
+// Session is a logged in user's session. The session token itself is never
+// stored, only a hash of it, so that a leaked database cannot be used to
+// hijack live sessions.
 type Session struct {
-	Token     string    `json:"token" db:"token"`
-	UserId    uuid.UUID `json:"userId" db:"user_id"`
-	CreatedAt time.Time `json:"createdAt" db:"created_at"`
-	ExpiresAt time.Time `json:"expiresAt" db:"expires_at"`
+	ID         uuid.UUID `json:"id" db:"id"`
+	TokenHash  string    `json:"-" db:"token_hash"`
+	UserId     uuid.UUID `json:"userId" db:"user_id"`
+	UserAgent  *string   `json:"userAgent" db:"user_agent"`
+	IPAddress  *string   `json:"ipAddress" db:"ip_address"`
+	RememberMe bool      `json:"rememberMe" db:"remember_me"`
+	CreatedAt  time.Time `json:"createdAt" db:"created_at"`
+	LastSeenAt time.Time `json:"lastSeenAt" db:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expiresAt" db:"expires_at"`
+	// AuthenticatedAt is the last time the user entered their password for this
+	// session, either when logging in or when re-authenticating.
+	AuthenticatedAt time.Time `json:"authenticatedAt" db:"authenticated_at"`
+}
+
+type NewSession struct {
+	TokenHash  string
+	UserID     uuid.UUID
+	UserAgent  *string
+	IPAddress  *string
+	RememberMe bool
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
 }
 
 type SessionRepository interface {
-	GetSession(token string, opts QueryOptions) (Session, error)
-	SaveSession(session Session, opts QueryOptions) error
-	DeleteSession(token string, opts QueryOptions) error
+	GetSession(tokenHash string, opts QueryOptions) (Session, error)
+	InsertSession(session NewSession, opts QueryOptions) (uuid.UUID, error)
+	// TouchSession records that a session was used and extends its expiration.
+	TouchSession(id uuid.UUID, lastSeenAt, expiresAt time.Time, opts QueryOptions) error
+	// ReauthenticateSession records that the user confirmed their password for
+	// an existing session.
+	ReauthenticateSession(id uuid.UUID, authenticatedAt time.Time, opts QueryOptions) error
+	DeleteSession(tokenHash string, opts QueryOptions) error
+
+	ListUserSessions(userID uuid.UUID, opts QueryOptions) ([]Session, error)
+	DeleteUserSession(userID, sessionID uuid.UUID, opts QueryOptions) error
+	// DeleteUserSessions removes all of a user's sessions except for the one
+	// identified by keepID. Passing uuid.Nil removes every session.
+	DeleteUserSessions(userID, keepID uuid.UUID, opts QueryOptions) error
+
+	// DeleteExpiredSessions removes all sessions that expired before the given
+	// time and returns the number of sessions removed.
+	DeleteExpiredSessions(before time.Time, opts QueryOptions) (int64, error)
+	// CountActiveSessions returns the number of sessions that haven't expired
+	// by the given time.
+	CountActiveSessions(at time.Time, opts QueryOptions) (int64, error)
 }
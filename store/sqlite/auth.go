@@ -18,7 +18,7 @@ func (db DB) GetUserPasswordHash(userID uuid.UUID, opts store.QueryOptions) (str
 	var hash string
 	err := db.querier(opts.Txn).GetContext(opts.Context(), &hash, query, userID)
 	if err != nil {
-		return "", err
+		return "", mapError(err, "password", userID.String())
 	}
 
 	return hash, nil
@@ -1,26 +1,43 @@
 package sqlite
 
 import (
-	"errors"
+	"time"
 
 	"github.com/gofrs/uuid/v5"
 	"github.com/ninth-realm/heimdall/store"
 )
 
-func (db DB) ListClients(opts store.QueryOptions) ([]store.Client, error) {
+func (db DB) ListClients(list store.ClientListOptions, opts store.QueryOptions) ([]store.Client, error) {
 	const query = `
 		SELECT
 			id,
 			name,
 			enabled,
+			allowed_origins,
 			created_at,
-			updated_at
+			updated_at,
+			version
 		FROM
 			client
 	`
 
-	var clients []store.Client
-	err := db.querier(opts.Txn).SelectContext(opts.Context(), &clients, query)
+	var q listQuery
+	q.filter("deleted_at IS NULL")
+	if list.Filter.Name != nil {
+		q.filter("name = ?", *list.Filter.Name)
+	}
+
+	if list.Filter.Enabled != nil {
+		q.filter("enabled = ?", *list.Filter.Enabled)
+	}
+
+	if err := q.paginate(list.Sort, list.Pagination); err != nil {
+		return nil, err
+	}
+
+	clients := []store.Client{}
+	stmt, args := q.build(query)
+	err := db.querier(opts.Txn).SelectContext(opts.Context(), &clients, stmt, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -34,19 +51,22 @@ func (db DB) GetClientById(id uuid.UUID, opts store.QueryOptions) (store.Client,
 			id,
 			name,
 			enabled,
+			allowed_origins,
 			created_at,
-			updated_at
+			updated_at,
+			version
 		FROM
 			client
 		WHERE
 			id = ?
+			AND deleted_at IS NULL
 	`
 
 	var client store.Client
 	err := db.querier(opts.Txn).GetContext(opts.Context(), &client, query, id)
 
 	if err != nil {
-		return store.Client{}, err
+		return store.Client{}, mapError(err, "client", id.String())
 	}
 
 	return client, nil
@@ -55,9 +75,9 @@ func (db DB) GetClientById(id uuid.UUID, opts store.QueryOptions) (store.Client,
 func (db DB) InsertClient(client store.NewClient, opts store.QueryOptions) (uuid.UUID, error) {
 	const query = `
 		INSERT INTO client
-			(id, name, enabled)
+			(id, name, enabled, allowed_origins)
 		VALUES
-			(?, ?, ?)
+			(?, ?, ?, ?)
 	`
 
 	id := db.UUIDGenerator.GenerateUUID()
@@ -67,10 +87,11 @@ func (db DB) InsertClient(client store.NewClient, opts store.QueryOptions) (uuid
 		id,
 		client.Name,
 		client.Enabled,
+		store.StringList(client.AllowedOrigins),
 	)
 
 	if err != nil {
-		return uuid.Nil, err
+		return uuid.Nil, mapError(err, "client", "")
 	}
 
 	return id, nil
@@ -81,20 +102,32 @@ func (db DB) SaveClient(client store.Client, opts store.QueryOptions) error {
 		UPDATE client
 		SET
 			name = ?,
-			enabled = ?
+			enabled = ?,
+			allowed_origins = ?,
+			version = version + 1
 		WHERE
 			id = ?
+			AND version = ?
+			AND deleted_at IS NULL
 	`
 
-	_, err := db.querier(opts.Txn).ExecContext(
+	res, err := db.querier(opts.Txn).ExecContext(
 		opts.Context(),
 		query,
 		client.Name,
 		client.Enabled,
+		client.AllowedOrigins,
 		client.ID,
+		client.Version,
 	)
 
 	if err != nil {
+		return mapError(err, "client", client.ID.String())
+	}
+
+	if n, err := res.RowsAffected(); n == 0 {
+		return db.staleOrMissing("client", "client", client.ID, opts)
+	} else if err != nil {
 		return err
 	}
 
@@ -103,24 +136,97 @@ func (db DB) SaveClient(client store.Client, opts store.QueryOptions) error {
 
 func (db DB) DeleteClient(id uuid.UUID, opts store.QueryOptions) error {
 	const query = `
-		DELETE FROM client
+		UPDATE client
+		SET
+			deleted_at = CURRENT_TIMESTAMP
 		WHERE
 			id = ?
+			AND deleted_at IS NULL
 	`
 
-	res, err := db.querier(opts.Txn).ExecContext(opts.Ctx, query, id)
+	res, err := db.querier(opts.Txn).ExecContext(opts.Context(), query, id)
 	if err != nil {
+		return mapError(err, "client", id.String())
+	}
+
+	if n, err := res.RowsAffected(); n == 0 {
+		return store.NotFoundError{ResourceType: "client", ResourceID: id.String()}
+	} else if err != nil {
 		return err
 	}
 
+	return nil
+}
+
+func (db DB) RestoreClient(id uuid.UUID, opts store.QueryOptions) error {
+	const query = `
+		UPDATE client
+		SET
+			deleted_at = NULL
+		WHERE
+			id = ?
+			AND deleted_at IS NOT NULL
+	`
+
+	res, err := db.querier(opts.Txn).ExecContext(opts.Context(), query, id)
+	if err != nil {
+		return mapError(err, "client", id.String())
+	}
+
 	if n, err := res.RowsAffected(); n == 0 {
-		return errors.New("client not found")
+		return store.NotFoundError{ResourceType: "deleted client", ResourceID: id.String()}
 	} else if err != nil {
 		return err
 	}
 
 	return nil
+}
+
+func (db DB) PurgeDeletedClients(before time.Time, opts store.QueryOptions) (int64, error) {
+	const query = `
+		DELETE FROM client
+		WHERE
+			deleted_at < ?
+	`
+
+	res, err := db.querier(opts.Txn).ExecContext(opts.Context(), query, formatTime(before))
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
 
+func (db DB) ListAllowedOrigins(opts store.QueryOptions) ([]string, error) {
+	const query = `
+		SELECT
+			allowed_origins
+		FROM
+			client
+		WHERE
+			enabled = ?
+			AND deleted_at IS NULL
+			AND allowed_origins <> ''
+	`
+
+	var lists []store.StringList
+	err := db.querier(opts.Txn).SelectContext(opts.Context(), &lists, query, true)
+	if err != nil {
+		return nil, err
+	}
+
+	origins := []string{}
+	seen := make(map[string]bool)
+	for _, list := range lists {
+		for _, origin := range list {
+			if !seen[origin] {
+				seen[origin] = true
+				origins = append(origins, origin)
+			}
+		}
+	}
+
+	return origins, nil
 }
 
 func (db DB) GetClientAPIKey(clientID uuid.UUID, prefix string, opts store.QueryOptions) (store.APIKey, error) {
@@ -138,12 +244,13 @@ func (db DB) GetClientAPIKey(clientID uuid.UUID, prefix string, opts store.Query
 		WHERE
 			client_id = ?
 			AND prefix = ?
+			AND client_id IN (SELECT id FROM client WHERE deleted_at IS NULL)
 	`
 
 	var key store.APIKey
 	err := db.querier(opts.Txn).GetContext(opts.Context(), &key, query, clientID, prefix)
 	if err != nil {
-		return store.APIKey{}, err
+		return store.APIKey{}, mapError(err, "API key", prefix)
 	}
 
 	return key, nil
@@ -194,7 +301,7 @@ func (db DB) InsertAPIKey(key store.NewAPIKey, opts store.QueryOptions) (uuid.UU
 	)
 
 	if err != nil {
-		return uuid.Nil, err
+		return uuid.Nil, mapError(err, "API key", "")
 	}
 
 	return id, nil
@@ -208,13 +315,13 @@ func (db DB) DeleteClientAPIKey(clientID, keyID uuid.UUID, opts store.QueryOptio
 			AND client_id = ?
 	`
 
-	res, err := db.querier(opts.Txn).ExecContext(opts.Ctx, query, keyID, clientID)
+	res, err := db.querier(opts.Txn).ExecContext(opts.Context(), query, keyID, clientID)
 	if err != nil {
-		return err
+		return mapError(err, "API key", keyID.String())
 	}
 
 	if n, err := res.RowsAffected(); n == 0 {
-		return errors.New("API key not found")
+		return store.NotFoundError{ResourceType: "API key", ResourceID: keyID.String()}
 	} else if err != nil {
 		return err
 	}
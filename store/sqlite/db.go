@@ -3,6 +3,7 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"strings"
 
 	"github.com/gofrs/uuid/v5"
 	"github.com/jmoiron/sqlx"
@@ -22,11 +23,23 @@ type UUIDGenerator interface {
 }
 
 func NewDB(dsn string) (DB, error) {
-	conn, err := sqlx.Open("sqlite", dsn)
+	// SQLite ignores foreign keys unless they are enabled on each connection,
+	// which would leave orphaned rows behind instead of cascading deletes.
+	conn, err := sqlx.Open("sqlite", withPragma(dsn, "foreign_keys(1)"))
 	if err != nil {
 		return DB{}, err
 	}
 
+	// Each connection to an in-memory database gets its own, empty database,
+	// so the pool is kept to one connection that's never closed. Requests and
+	// background jobs then wait their turn for it.
+	if isMemoryDSN(dsn) {
+		conn.SetMaxOpenConns(1)
+		conn.SetMaxIdleConns(1)
+		conn.SetConnMaxLifetime(0)
+		conn.SetConnMaxIdleTime(0)
+	}
+
 	if err := conn.Ping(); err != nil {
 		return DB{}, err
 	}
@@ -34,10 +47,32 @@ func NewDB(dsn string) (DB, error) {
 	return DB{Conn: conn}, nil
 }
 
+// isMemoryDSN reports whether the DSN opens an in-memory database.
+func isMemoryDSN(dsn string) bool {
+	return strings.Contains(dsn, ":memory:") || strings.Contains(dsn, "mode=memory")
+}
+
+// withPragma adds a pragma to the DSN so that it's applied to every connection
+// opened by the driver.
+func withPragma(dsn, pragma string) string {
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+
+	return dsn + sep + "_pragma=" + pragma
+}
+
 func (db DB) BeginTx(ctx context.Context) (*sqlx.Tx, error) {
 	return db.Conn.BeginTxx(ctx, nil)
 }
 
+// Close closes the database's connections once any queries in progress have
+// finished.
+func (db DB) Close() error {
+	return db.Conn.Close()
+}
+
 type Querier interface {
 	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
 	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
@@ -0,0 +1,12 @@
+package sqlite_test
+
+import (
+	"testing"
+
+	"github.com/ninth-realm/heimdall/store/sqlite/sqlitetest"
+	"github.com/ninth-realm/heimdall/store/storetest"
+)
+
+func TestRepository(t *testing.T) {
+	storetest.Run(t, sqlitetest.NewDB)
+}
@@ -22,7 +22,7 @@ func (db DB) InsertEmail(email store.NewEmail, opts store.QueryOptions) (uuid.UU
 		email.Email,
 	)
 	if err != nil {
-		return uuid.Nil, err
+		return uuid.Nil, mapError(err, "email", "")
 	}
 
 	return id, nil
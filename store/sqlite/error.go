@@ -0,0 +1,54 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/ninth-realm/heimdall/store"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// mapError converts driver errors into the typed errors defined by the store
+// package, so that callers never see SQLite specific errors or SQL. The
+// resource ID is only used to describe a missing resource. Unrecognized errors
+// are returned unchanged.
+func mapError(err error, resourceType, resourceID string) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return store.NotFoundError{ResourceType: resourceType, ResourceID: resourceID}
+	}
+
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return err
+	}
+
+	switch code := sqliteErr.Code(); {
+	case code == sqlite3.SQLITE_CONSTRAINT_UNIQUE, code == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+		return store.ConflictError{ResourceType: resourceType}
+	// Extended result codes keep the primary result code in their lowest byte.
+	case code&0xff == sqlite3.SQLITE_CONSTRAINT:
+		return store.ConstraintViolationError{ResourceType: resourceType}
+	}
+
+	return err
+}
+
+// staleOrMissing explains why saving a resource matched no rows. Saves only
+// match the version that was read, so either the resource has been changed
+// since then, or it no longer exists.
+func (db DB) staleOrMissing(table, resourceType string, id uuid.UUID, opts store.QueryOptions) error {
+	query := "SELECT COUNT(*) FROM " + table + " WHERE id = ? AND deleted_at IS NULL"
+
+	var n int
+	if err := db.querier(opts.Txn).GetContext(opts.Context(), &n, query, id); err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return store.NotFoundError{ResourceType: resourceType, ResourceID: id.String()}
+	}
+
+	return store.VersionMismatchError{ResourceType: resourceType, ResourceID: id.String()}
+}
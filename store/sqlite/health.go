@@ -0,0 +1,34 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/ninth-realm/heimdall/store"
+)
+
+func (db DB) Ping(ctx context.Context) error {
+	return db.Conn.PingContext(ctx)
+}
+
+func (db DB) GetMigrationVersion(opts store.QueryOptions) (store.MigrationVersion, error) {
+	const query = `
+		SELECT
+			version,
+			dirty
+		FROM
+			schema_migrations
+		LIMIT 1
+	`
+
+	var v store.MigrationVersion
+	err := db.querier(opts.Txn).GetContext(opts.Context(), &v, query)
+	if errors.Is(err, sql.ErrNoRows) {
+		return store.MigrationVersion{}, nil
+	} else if err != nil {
+		return store.MigrationVersion{}, err
+	}
+
+	return v, nil
+}
@@ -22,8 +22,32 @@ func (db DB) InsertPassword(password store.NewPassword, opts store.QueryOptions)
 		password.Hash,
 	)
 	if err != nil {
-		return uuid.Nil, err
+		return uuid.Nil, mapError(err, "password", "")
 	}
 
 	return id, nil
 }
+
+func (db DB) SavePassword(password store.NewPassword, opts store.QueryOptions) error {
+	const query = `
+		INSERT INTO password
+			(id, user_id, hash)
+		VALUES
+			(?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET
+			hash = excluded.hash
+	`
+
+	_, err := db.querier(opts.Txn).ExecContext(
+		opts.Context(),
+		query,
+		db.UUIDGenerator.GenerateUUID(),
+		password.UserID,
+		password.Hash,
+	)
+	if err != nil {
+		return mapError(err, "password", "")
+	}
+
+	return nil
+}
@@ -1,77 +1,252 @@
 package sqlite
 
 import (
-	"errors"
 	"time"
 
+	"github.com/gofrs/uuid/v5"
 	"github.com/ninth-realm/heimdall/store"
 )
 
-func (db DB) GetSession(token string, opts store.QueryOptions) (store.Session, error) {
+func (db DB) GetSession(tokenHash string, opts store.QueryOptions) (store.Session, error) {
 	const query = `
 		SELECT
-			token,
+			id,
+			token_hash,
 			user_id,
+			user_agent,
+			ip_address,
+			remember_me,
 			created_at,
-			expires_at
+			last_seen_at,
+			expires_at,
+			authenticated_at
 		FROM
 			session
-        WHERE
-            token = ?
+		WHERE
+			token_hash = ?
 	`
 
 	var session store.Session
-	err := db.querier(opts.Txn).GetContext(opts.Context(), &session, query, token)
+	err := db.querier(opts.Txn).GetContext(opts.Context(), &session, query, tokenHash)
 	if err != nil {
-		return store.Session{}, err
+		return store.Session{}, mapError(err, "session", tokenHash)
 	}
 
 	if session.ExpiresAt.Before(time.Now()) {
-		return store.Session{}, errors.New("session expired")
+		return store.Session{}, store.NotFoundError{ResourceType: "session", ResourceID: tokenHash}
 	}
 
 	return session, nil
 }
-func (db DB) SaveSession(session store.Session, opts store.QueryOptions) error {
+
+func (db DB) InsertSession(session store.NewSession, opts store.QueryOptions) (uuid.UUID, error) {
 	const query = `
-        INSERT INTO session
-            (token, user_id, created_at, expires_at)
-        VALUES
-            (?, ?, ?, ?)
-    `
+		INSERT INTO session
+			(
+				id,
+				token_hash,
+				user_id,
+				user_agent,
+				ip_address,
+				remember_me,
+				created_at,
+				last_seen_at,
+				expires_at,
+				authenticated_at
+			)
+		VALUES
+			(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
 
+	id := db.UUIDGenerator.GenerateUUID()
 	_, err := db.querier(opts.Txn).ExecContext(
 		opts.Context(),
 		query,
-		session.Token,
-		session.UserId,
+		id,
+		session.TokenHash,
+		session.UserID,
+		session.UserAgent,
+		session.IPAddress,
+		session.RememberMe,
+		session.CreatedAt,
 		session.CreatedAt,
 		session.ExpiresAt,
+		session.CreatedAt,
 	)
 	if err != nil {
-		return err
+		return uuid.Nil, mapError(err, "session", "")
+	}
+
+	return id, nil
+}
+
+func (db DB) TouchSession(id uuid.UUID, lastSeenAt, expiresAt time.Time, opts store.QueryOptions) error {
+	const query = `
+		UPDATE session
+		SET
+			last_seen_at = ?,
+			expires_at = ?
+		WHERE
+			id = ?
+	`
+
+	res, err := db.querier(opts.Txn).ExecContext(opts.Context(), query, lastSeenAt, expiresAt, id)
+	if err != nil {
+		return mapError(err, "session", id.String())
+	}
+
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return store.NotFoundError{ResourceType: "session", ResourceID: id.String()}
 	}
 
 	return nil
 }
 
-func (db DB) DeleteSession(token string, opts store.QueryOptions) error {
+func (db DB) ReauthenticateSession(id uuid.UUID, authenticatedAt time.Time, opts store.QueryOptions) error {
 	const query = `
-        DELETE FROM
-            session
-        WHERE
-            token = ?
-    `
+		UPDATE session
+		SET
+			authenticated_at = ?
+		WHERE
+			id = ?
+	`
 
-	res, err := db.querier(opts.Txn).ExecContext(opts.Context(), query, token)
+	res, err := db.querier(opts.Txn).ExecContext(opts.Context(), query, authenticatedAt, id)
 	if err != nil {
-		return err
+		return mapError(err, "session", id.String())
+	}
+
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return store.NotFoundError{ResourceType: "session", ResourceID: id.String()}
+	}
+
+	return nil
+}
+
+func (db DB) DeleteSession(tokenHash string, opts store.QueryOptions) error {
+	const query = `
+		DELETE FROM
+			session
+		WHERE
+			token_hash = ?
+	`
+
+	res, err := db.querier(opts.Txn).ExecContext(opts.Context(), query, tokenHash)
+	if err != nil {
+		return mapError(err, "session", tokenHash)
 	}
 
 	rows, _ := res.RowsAffected()
 	if rows == 0 {
-		return store.NotFoundError{ResourceType: "session", ResourceID: token}
+		return store.NotFoundError{ResourceType: "session", ResourceID: tokenHash}
 	}
 
 	return nil
 }
+
+func (db DB) ListUserSessions(userID uuid.UUID, opts store.QueryOptions) ([]store.Session, error) {
+	const query = `
+		SELECT
+			id,
+			token_hash,
+			user_id,
+			user_agent,
+			ip_address,
+			remember_me,
+			created_at,
+			last_seen_at,
+			expires_at,
+			authenticated_at
+		FROM
+			session
+		WHERE
+			user_id = ?
+			AND expires_at > ?
+		ORDER BY
+			created_at
+	`
+
+	sessions := []store.Session{}
+	err := db.querier(opts.Txn).SelectContext(opts.Context(), &sessions, query, userID, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+func (db DB) DeleteUserSession(userID, sessionID uuid.UUID, opts store.QueryOptions) error {
+	const query = `
+		DELETE FROM
+			session
+		WHERE
+			id = ?
+			AND user_id = ?
+	`
+
+	res, err := db.querier(opts.Txn).ExecContext(opts.Context(), query, sessionID, userID)
+	if err != nil {
+		return mapError(err, "session", sessionID.String())
+	}
+
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return store.NotFoundError{ResourceType: "session", ResourceID: sessionID.String()}
+	}
+
+	return nil
+}
+
+func (db DB) DeleteUserSessions(userID, keepID uuid.UUID, opts store.QueryOptions) error {
+	const query = `
+		DELETE FROM
+			session
+		WHERE
+			user_id = ?
+			AND id != ?
+	`
+
+	_, err := db.querier(opts.Txn).ExecContext(opts.Context(), query, userID, keepID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (db DB) DeleteExpiredSessions(before time.Time, opts store.QueryOptions) (int64, error) {
+	const query = `
+		DELETE FROM
+			session
+		WHERE
+			expires_at < ?
+	`
+
+	res, err := db.querier(opts.Txn).ExecContext(opts.Context(), query, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+func (db DB) CountActiveSessions(at time.Time, opts store.QueryOptions) (int64, error) {
+	const query = `
+		SELECT
+			COUNT(*)
+		FROM
+			session
+		WHERE
+			expires_at > ?
+	`
+
+	var count int64
+	err := db.querier(opts.Txn).GetContext(opts.Context(), &count, query, at)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
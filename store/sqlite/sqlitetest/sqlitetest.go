@@ -0,0 +1,55 @@
+// Package sqlitetest creates migrated SQLite databases for tests, both for the
+// SQLite backend itself and for packages that need a real repository to test
+// against.
+package sqlitetest
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/sqlite3"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/ninth-realm/heimdall/store"
+	"github.com/ninth-realm/heimdall/store/sqlite"
+	"github.com/ninth-realm/heimdall/store/storetest"
+)
+
+// NewDB creates a fully migrated database in a temporary directory that is
+// removed once the test completes.
+func NewDB(t *testing.T) store.Repository {
+	t.Helper()
+
+	db, err := sqlite.NewDB(filepath.Join(t.TempDir(), "heimdall.db"))
+	if err != nil {
+		t.Fatalf("NewDB() error = %v", err)
+	}
+	t.Cleanup(func() { db.Conn.Close() })
+
+	db.UUIDGenerator = storetest.UUIDGenerator{}
+
+	driver, err := sqlite3.WithInstance(db.Conn.DB, &sqlite3.Config{})
+	if err != nil {
+		t.Fatalf("sqlite3.WithInstance() error = %v", err)
+	}
+
+	m, err := migrate.NewWithDatabaseInstance("file://"+migrationsDir(), "sqlite", driver)
+	if err != nil {
+		t.Fatalf("migrate.NewWithDatabaseInstance() error = %v", err)
+	}
+
+	if err = m.Up(); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+
+	return db
+}
+
+// migrationsDir finds the SQLite migrations relative to this file, since tests
+// run from their own package's directory.
+func migrationsDir() string {
+	_, file, _, _ := runtime.Caller(0)
+
+	return filepath.Join(filepath.Dir(file), "..", "..", "..", "db", "migrations", "sqlite")
+}
@@ -1,25 +1,51 @@
 package sqlite
 
 import (
-	"errors"
+	"strings"
+	"time"
 
 	"github.com/gofrs/uuid/v5"
 	"github.com/ninth-realm/heimdall/store"
 )
 
-func (db DB) ListUsers(opts store.QueryOptions) ([]store.User, error) {
+func (db DB) ListUsers(list store.UserListOptions, opts store.QueryOptions) ([]store.User, error) {
 	const query = `
 		SELECT
 			id,
 			first_name,
 			last_name,
 			created_at,
-			updated_at
+			updated_at,
+			version
 		FROM
 			` + "`user`"
 
-	var users []store.User
-	err := db.querier(opts.Txn).SelectContext(opts.Context(), &users, query)
+	var q listQuery
+	q.filter("deleted_at IS NULL")
+	if list.Filter.Email != nil {
+		q.filter("id IN (SELECT user_id FROM email WHERE email = ?)", *list.Filter.Email)
+	}
+
+	if list.Filter.NamePrefix != nil {
+		pattern := likePrefix(*list.Filter.NamePrefix)
+		q.filter(`(LOWER(first_name) LIKE ? ESCAPE '\' OR LOWER(last_name) LIKE ? ESCAPE '\')`, pattern, pattern)
+	}
+
+	if list.Filter.CreatedAfter != nil {
+		q.filter("created_at >= ?", formatTime(*list.Filter.CreatedAfter))
+	}
+
+	if list.Filter.CreatedBefore != nil {
+		q.filter("created_at < ?", formatTime(*list.Filter.CreatedBefore))
+	}
+
+	if err := q.paginate(list.Sort, list.Pagination); err != nil {
+		return nil, err
+	}
+
+	users := []store.User{}
+	stmt, args := q.build(query)
+	err := db.querier(opts.Txn).SelectContext(opts.Context(), &users, stmt, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -27,6 +53,74 @@ func (db DB) ListUsers(opts store.QueryOptions) ([]store.User, error) {
 	return users, nil
 }
 
+// SearchUsers uses the user_search FTS5 table. bm25 gives better matches a lower
+// score, so it's negated to rank them highest. Users with several matching
+// email addresses are returned once, with the best match.
+func (db DB) SearchUsers(search store.UserSearchOptions, opts store.QueryOptions) ([]store.UserSearchResult, error) {
+	const query = `
+		SELECT
+			id,
+			first_name,
+			last_name,
+			created_at,
+			updated_at,
+			version,
+			email,
+			score
+		FROM (
+			SELECT
+				u.id,
+				u.first_name,
+				u.last_name,
+				u.created_at,
+				u.updated_at,
+				u.version,
+				m.email,
+				m.score,
+				ROW_NUMBER() OVER (PARTITION BY u.id ORDER BY m.score DESC, m.email) AS n
+			FROM (
+				SELECT
+					user_id,
+					email,
+					-bm25(user_search) AS score
+				FROM
+					user_search
+				WHERE
+					user_search MATCH ?
+			) m
+			INNER JOIN ` + "`user`" + ` u ON u.id = m.user_id
+			WHERE
+				u.deleted_at IS NULL
+		) results`
+
+	terms := store.SearchTerms(search.Query)
+	if len(terms) == 0 {
+		return []store.UserSearchResult{}, nil
+	}
+
+	// Each term is quoted so it's matched as a word rather than parsed as FTS5
+	// syntax, and made a prefix query so it matches the start of words.
+	phrases := make([]string, len(terms))
+	for i, term := range terms {
+		phrases[i] = `"` + term + `"*`
+	}
+
+	var q listQuery
+	q.filter("n = 1")
+	if err := q.paginate(store.UserSearchSort, search.Pagination); err != nil {
+		return nil, err
+	}
+
+	results := []store.UserSearchResult{}
+	stmt, args := q.build(query, strings.Join(phrases, " AND "))
+	err := db.querier(opts.Txn).SelectContext(opts.Context(), &results, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
 func (db DB) GetUserById(id uuid.UUID, opts store.QueryOptions) (store.User, error) {
 	const query = `
 		SELECT
@@ -34,18 +128,20 @@ func (db DB) GetUserById(id uuid.UUID, opts store.QueryOptions) (store.User, err
 			first_name,
 			last_name,
 			created_at,
-			updated_at
+			updated_at,
+			version
 		FROM
 			` + "`user`" + `
 		WHERE
 			id = ?
+			AND deleted_at IS NULL
 	`
 
 	var user store.User
 	err := db.querier(opts.Txn).GetContext(opts.Context(), &user, query, id)
 
 	if err != nil {
-		return store.User{}, err
+		return store.User{}, mapError(err, "user", id.String())
 	}
 
 	return user, nil
@@ -58,20 +154,22 @@ func (db DB) GetUserByEmail(email string, opts store.QueryOptions) (store.User,
 			u.first_name,
 			u.last_name,
 			u.created_at,
-			u.updated_at
+			u.updated_at,
+			u.version
 		FROM
 			` + "`user` u" + `
 		INNER JOIN email e ON
 			u.id = e.user_id
 		WHERE
 			e.email = ?
+			AND u.deleted_at IS NULL
 	`
 
 	var user store.User
 	err := db.querier(opts.Txn).GetContext(opts.Context(), &user, query, email)
 
 	if err != nil {
-		return store.User{}, err
+		return store.User{}, mapError(err, "user", email)
 	}
 
 	return user, nil
@@ -95,7 +193,7 @@ func (db DB) InsertUser(user store.NewUser, opts store.QueryOptions) (uuid.UUID,
 	)
 
 	if err != nil {
-		return uuid.Nil, err
+		return uuid.Nil, mapError(err, "user", "")
 	}
 
 	return id, nil
@@ -106,20 +204,30 @@ func (db DB) SaveUser(user store.User, opts store.QueryOptions) error {
 		UPDATE` + "`user`" + `
 		SET
 			first_name = ?,
-			last_name = ?
+			last_name = ?,
+			version = version + 1
 		WHERE
 			id = ?
+			AND version = ?
+			AND deleted_at IS NULL
 	`
 
-	_, err := db.querier(opts.Txn).ExecContext(
+	res, err := db.querier(opts.Txn).ExecContext(
 		opts.Context(),
 		query,
 		user.FirstName,
 		user.LastName,
 		user.ID,
+		user.Version,
 	)
 
 	if err != nil {
+		return mapError(err, "user", user.ID.String())
+	}
+
+	if n, err := res.RowsAffected(); n == 0 {
+		return db.staleOrMissing("`user`", "user", user.ID, opts)
+	} else if err != nil {
 		return err
 	}
 
@@ -128,22 +236,63 @@ func (db DB) SaveUser(user store.User, opts store.QueryOptions) error {
 
 func (db DB) DeleteUser(id uuid.UUID, opts store.QueryOptions) error {
 	const query = `
-		DELETE FROM` + "`user`" + `
+		UPDATE ` + "`user`" + `
+		SET
+			deleted_at = CURRENT_TIMESTAMP
 		WHERE
 			id = ?
+			AND deleted_at IS NULL
 	`
 
-	res, err := db.querier(opts.Txn).ExecContext(opts.Ctx, query, id)
+	res, err := db.querier(opts.Txn).ExecContext(opts.Context(), query, id)
 	if err != nil {
+		return mapError(err, "user", id.String())
+	}
+
+	if n, err := res.RowsAffected(); n == 0 {
+		return store.NotFoundError{ResourceType: "user", ResourceID: id.String()}
+	} else if err != nil {
 		return err
 	}
 
+	return nil
+}
+
+func (db DB) RestoreUser(id uuid.UUID, opts store.QueryOptions) error {
+	const query = `
+		UPDATE ` + "`user`" + `
+		SET
+			deleted_at = NULL
+		WHERE
+			id = ?
+			AND deleted_at IS NOT NULL
+	`
+
+	res, err := db.querier(opts.Txn).ExecContext(opts.Context(), query, id)
+	if err != nil {
+		return mapError(err, "user", id.String())
+	}
+
 	if n, err := res.RowsAffected(); n == 0 {
-		return errors.New("user not found")
+		return store.NotFoundError{ResourceType: "deleted user", ResourceID: id.String()}
 	} else if err != nil {
 		return err
 	}
 
 	return nil
+}
+
+func (db DB) PurgeDeletedUsers(before time.Time, opts store.QueryOptions) (int64, error) {
+	const query = `
+		DELETE FROM ` + "`user`" + `
+		WHERE
+			deleted_at < ?
+	`
+
+	res, err := db.querier(opts.Txn).ExecContext(opts.Context(), query, formatTime(before))
+	if err != nil {
+		return 0, err
+	}
 
+	return res.RowsAffected()
 }
@@ -0,0 +1,390 @@
+package sqlite
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/ninth-realm/heimdall/store"
+)
+
+func (db DB) ListWebhooks(opts store.QueryOptions) ([]store.Webhook, error) {
+	const query = `
+		SELECT
+			id,
+			url,
+			event_types,
+			secret,
+			enabled,
+			created_at,
+			updated_at,
+			version
+		FROM
+			webhook
+		ORDER BY
+			created_at, id
+	`
+
+	webhooks := []store.Webhook{}
+	err := db.querier(opts.Txn).SelectContext(opts.Context(), &webhooks, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+func (db DB) GetWebhook(id uuid.UUID, opts store.QueryOptions) (store.Webhook, error) {
+	const query = `
+		SELECT
+			id,
+			url,
+			event_types,
+			secret,
+			enabled,
+			created_at,
+			updated_at,
+			version
+		FROM
+			webhook
+		WHERE
+			id = ?
+	`
+
+	var webhook store.Webhook
+	err := db.querier(opts.Txn).GetContext(opts.Context(), &webhook, query, id)
+	if err != nil {
+		return store.Webhook{}, mapError(err, "webhook", id.String())
+	}
+
+	return webhook, nil
+}
+
+func (db DB) InsertWebhook(webhook store.NewWebhook, opts store.QueryOptions) (uuid.UUID, error) {
+	const query = `
+		INSERT INTO webhook
+			(id, url, event_types, secret, enabled)
+		VALUES
+			(?, ?, ?, ?, ?)
+	`
+
+	id := db.UUIDGenerator.GenerateUUID()
+	_, err := db.querier(opts.Txn).ExecContext(
+		opts.Context(),
+		query,
+		id,
+		webhook.URL,
+		store.StringList(webhook.EventTypes),
+		webhook.Secret,
+		webhook.Enabled,
+	)
+
+	if err != nil {
+		return uuid.Nil, mapError(err, "webhook", "")
+	}
+
+	return id, nil
+}
+
+func (db DB) SaveWebhook(webhook store.Webhook, opts store.QueryOptions) error {
+	const query = `
+		UPDATE webhook
+		SET
+			url = ?,
+			event_types = ?,
+			secret = ?,
+			enabled = ?,
+			version = version + 1
+		WHERE
+			id = ?
+			AND version = ?
+	`
+
+	res, err := db.querier(opts.Txn).ExecContext(
+		opts.Context(),
+		query,
+		webhook.URL,
+		webhook.EventTypes,
+		webhook.Secret,
+		webhook.Enabled,
+		webhook.ID,
+		webhook.Version,
+	)
+
+	if err != nil {
+		return mapError(err, "webhook", webhook.ID.String())
+	}
+
+	if n, err := res.RowsAffected(); n == 0 {
+		if _, err := db.GetWebhook(webhook.ID, opts); err != nil {
+			return err
+		}
+
+		return store.VersionMismatchError{ResourceType: "webhook", ResourceID: webhook.ID.String()}
+	} else if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (db DB) DeleteWebhook(id uuid.UUID, opts store.QueryOptions) error {
+	const query = `
+		DELETE FROM webhook
+		WHERE
+			id = ?
+	`
+
+	res, err := db.querier(opts.Txn).ExecContext(opts.Context(), query, id)
+	if err != nil {
+		return mapError(err, "webhook", id.String())
+	}
+
+	if n, err := res.RowsAffected(); n == 0 {
+		return store.NotFoundError{ResourceType: "webhook", ResourceID: id.String()}
+	} else if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (db DB) InsertWebhookDelivery(delivery store.NewWebhookDelivery, opts store.QueryOptions) (uuid.UUID, error) {
+	const query = `
+		INSERT INTO webhook_delivery
+			(id, webhook_id, event_id, event_type, payload)
+		VALUES
+			(?, ?, ?, ?, ?)
+	`
+
+	id := db.UUIDGenerator.GenerateUUID()
+	_, err := db.querier(opts.Txn).ExecContext(
+		opts.Context(),
+		query,
+		id,
+		delivery.WebhookID,
+		delivery.EventID,
+		delivery.EventType,
+		delivery.Payload,
+	)
+
+	if err != nil {
+		return uuid.Nil, mapError(err, "webhook delivery", "")
+	}
+
+	return id, nil
+}
+
+func (db DB) GetWebhookDelivery(webhookID, deliveryID uuid.UUID, opts store.QueryOptions) (store.WebhookDelivery, error) {
+	const query = `
+		SELECT
+			id,
+			webhook_id,
+			event_id,
+			event_type,
+			payload,
+			status,
+			attempts,
+			next_attempt_at,
+			last_attempt_at,
+			created_at
+		FROM
+			webhook_delivery
+		WHERE
+			id = ?
+			AND webhook_id = ?
+	`
+
+	var delivery store.WebhookDelivery
+	err := db.querier(opts.Txn).GetContext(opts.Context(), &delivery, query, deliveryID, webhookID)
+	if err != nil {
+		return store.WebhookDelivery{}, mapError(err, "webhook delivery", deliveryID.String())
+	}
+
+	return delivery, nil
+}
+
+func (db DB) ListWebhookDeliveries(webhookID uuid.UUID, list store.WebhookDeliveryListOptions, opts store.QueryOptions) ([]store.WebhookDelivery, error) {
+	const query = `
+		SELECT
+			id,
+			webhook_id,
+			event_id,
+			event_type,
+			payload,
+			status,
+			attempts,
+			next_attempt_at,
+			last_attempt_at,
+			created_at
+		FROM
+			webhook_delivery
+	`
+
+	var q listQuery
+	q.filter("webhook_id = ?", webhookID)
+	if err := q.paginate(list.Sort, list.Pagination); err != nil {
+		return nil, err
+	}
+
+	deliveries := []store.WebhookDelivery{}
+	stmt, args := q.build(query)
+	err := db.querier(opts.Txn).SelectContext(opts.Context(), &deliveries, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+func (db DB) ListDueWebhookDeliveries(now time.Time, limit int, opts store.QueryOptions) ([]store.WebhookDelivery, error) {
+	const query = `
+		SELECT
+			id,
+			webhook_id,
+			event_id,
+			event_type,
+			payload,
+			status,
+			attempts,
+			next_attempt_at,
+			last_attempt_at,
+			created_at
+		FROM
+			webhook_delivery
+		WHERE
+			status = ?
+			AND next_attempt_at <= ?
+		ORDER BY
+			next_attempt_at, id
+		LIMIT ?
+	`
+
+	deliveries := []store.WebhookDelivery{}
+	err := db.querier(opts.Txn).SelectContext(
+		opts.Context(),
+		&deliveries,
+		query,
+		store.DeliveryPending,
+		formatTime(now),
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+func (db DB) ClaimWebhookDelivery(id uuid.UUID, attempts int, until time.Time, opts store.QueryOptions) error {
+	const query = `
+		UPDATE webhook_delivery
+		SET
+			attempts = attempts + 1,
+			next_attempt_at = ?
+		WHERE
+			id = ?
+			AND attempts = ?
+			AND status = ?
+	`
+
+	res, err := db.querier(opts.Txn).ExecContext(
+		opts.Context(),
+		query,
+		formatTime(until),
+		id,
+		attempts,
+		store.DeliveryPending,
+	)
+	if err != nil {
+		return mapError(err, "webhook delivery", id.String())
+	}
+
+	if n, err := res.RowsAffected(); n == 0 {
+		return store.VersionMismatchError{ResourceType: "webhook delivery", ResourceID: id.String()}
+	} else if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (db DB) FinishWebhookDeliveryAttempt(attempt store.NewWebhookDeliveryAttempt, status string, nextAttemptAt time.Time, opts store.QueryOptions) error {
+	const insertQuery = `
+		INSERT INTO webhook_delivery_attempt
+			(id, delivery_id, number, attempted_at, status_code, error)
+		VALUES
+			(?, ?, ?, ?, ?, ?)
+	`
+
+	const updateQuery = `
+		UPDATE webhook_delivery
+		SET
+			status = ?,
+			next_attempt_at = ?,
+			last_attempt_at = ?
+		WHERE
+			id = ?
+	`
+
+	q := db.querier(opts.Txn)
+
+	_, err := q.ExecContext(
+		opts.Context(),
+		insertQuery,
+		db.UUIDGenerator.GenerateUUID(),
+		attempt.DeliveryID,
+		attempt.Number,
+		formatTime(attempt.AttemptedAt),
+		attempt.StatusCode,
+		attempt.Error,
+	)
+	if err != nil {
+		return mapError(err, "webhook delivery attempt", "")
+	}
+
+	res, err := q.ExecContext(
+		opts.Context(),
+		updateQuery,
+		status,
+		formatTime(nextAttemptAt),
+		formatTime(attempt.AttemptedAt),
+		attempt.DeliveryID,
+	)
+	if err != nil {
+		return mapError(err, "webhook delivery", attempt.DeliveryID.String())
+	}
+
+	if n, err := res.RowsAffected(); n == 0 {
+		return store.NotFoundError{ResourceType: "webhook delivery", ResourceID: attempt.DeliveryID.String()}
+	} else if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (db DB) ListWebhookDeliveryAttempts(deliveryID uuid.UUID, opts store.QueryOptions) ([]store.WebhookDeliveryAttempt, error) {
+	const query = `
+		SELECT
+			id,
+			delivery_id,
+			number,
+			attempted_at,
+			status_code,
+			error
+		FROM
+			webhook_delivery_attempt
+		WHERE
+			delivery_id = ?
+		ORDER BY
+			number
+	`
+
+	attempts := []store.WebhookDeliveryAttempt{}
+	err := db.querier(opts.Txn).SelectContext(opts.Context(), &attempts, query, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+
+	return attempts, nil
+}
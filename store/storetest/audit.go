@@ -0,0 +1,216 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jmoiron/sqlx"
+	"github.com/ninth-realm/heimdall/store"
+)
+
+func testAuditEvents(t *testing.T, newRepo Factory) {
+	bySequence := store.Sort{Field: store.SortBySequence}
+
+	t.Run("Append chains events", func(t *testing.T) {
+		repo := newRepo(t)
+
+		head, err := repo.GetAuditChainHead(opts())
+		if err != nil {
+			t.Fatalf("GetAuditChainHead() error = %v", err)
+		}
+
+		want := store.AuditChainHead{Sequence: 0, Hash: store.GenesisHash}
+		if diff := cmp.Diff(want, head); diff != "" {
+			t.Errorf("GetAuditChainHead() of empty log mismatch (-want +got):\n%s", diff)
+		}
+
+		first := appendAuditEvent(t, repo, newAuditEvent("user.created", "user", "1"))
+		second := appendAuditEvent(t, repo, newAuditEvent("user.updated", "user", "1"))
+
+		if first.Sequence != 1 || second.Sequence != 2 {
+			t.Errorf("AppendAuditEvent() sequences = %d, %d, want 1, 2", first.Sequence, second.Sequence)
+		}
+
+		if first.PrevHash != store.GenesisHash {
+			t.Errorf("AppendAuditEvent() first PrevHash = %s, want genesis hash", first.PrevHash)
+		}
+
+		if second.PrevHash != first.Hash {
+			t.Errorf("AppendAuditEvent() second PrevHash = %s, want %s", second.PrevHash, first.Hash)
+		}
+
+		head, err = repo.GetAuditChainHead(opts())
+		if err != nil {
+			t.Fatalf("GetAuditChainHead() error = %v", err)
+		}
+
+		want = store.AuditChainHead{Sequence: 2, Hash: second.Hash}
+		if diff := cmp.Diff(want, head); diff != "" {
+			t.Errorf("GetAuditChainHead() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("Stored events match their hash", func(t *testing.T) {
+		repo := newRepo(t)
+
+		event := newAuditEvent("login.succeeded", "session", "1")
+		ip, requestID := "127.0.0.1", "host/abc-000001"
+		event.IPAddress, event.RequestID = &ip, &requestID
+		appended := appendAuditEvent(t, repo, event)
+		appendAuditEvent(t, repo, store.NewAuditEvent{Type: "setup_mode.started", ActorType: "system", OccurredAt: time.Now()})
+
+		events, err := repo.ListAuditEvents(store.AuditEventListOptions{Sort: bySequence}, opts())
+		if err != nil {
+			t.Fatalf("ListAuditEvents() error = %v", err)
+		}
+
+		if len(events) != 2 {
+			t.Fatalf("ListAuditEvents() returned %d events, want 2", len(events))
+		}
+
+		for _, e := range events {
+			if got := e.ComputeHash(); got != e.Hash {
+				t.Errorf("ComputeHash() of stored event %d = %s, want %s", e.Sequence, got, e.Hash)
+			}
+		}
+
+		if !events[0].OccurredAt.Equal(appended.OccurredAt) {
+			t.Errorf("ListAuditEvents() OccurredAt = %v, want %v", events[0].OccurredAt, appended.OccurredAt)
+		}
+	})
+
+	t.Run("Rolled back events are not chained", func(t *testing.T) {
+		repo := newRepo(t)
+
+		errAbort := errors.New("abort")
+		_, err := store.RunUnitOfWork(context.Background(), repo, func(txn *sqlx.Tx) (store.AuditEvent, error) {
+			opts := store.QueryOptions{Ctx: context.Background(), Txn: txn}
+			if _, err := repo.AppendAuditEvent(newAuditEvent("user.created", "user", "1"), opts); err != nil {
+				return store.AuditEvent{}, err
+			}
+
+			return store.AuditEvent{}, errAbort
+		})
+		if !errors.Is(err, errAbort) {
+			t.Fatalf("RunUnitOfWork() error = %v, want %v", err, errAbort)
+		}
+
+		event := appendAuditEvent(t, repo, newAuditEvent("user.created", "user", "2"))
+		if event.Sequence != 1 || event.PrevHash != store.GenesisHash {
+			t.Errorf("AppendAuditEvent() after rollback = %d after %s, want 1 after genesis hash", event.Sequence, event.PrevHash)
+		}
+	})
+
+	t.Run("Filter", func(t *testing.T) {
+		repo := newRepo(t)
+
+		created := appendAuditEvent(t, repo, newAuditEvent("user.created", "user", "1"))
+		updated := appendAuditEvent(t, repo, newAuditEvent("user.updated", "user", "1"))
+		client := appendAuditEvent(t, repo, newAuditEvent("client.created", "client", "1"))
+
+		eventType, targetType, targetID := "user.updated", "client", "1"
+		hourAgo, inAnHour := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+		tests := []struct {
+			name   string
+			filter store.AuditEventFilter
+			want   []int64
+		}{
+			{name: "Type", filter: store.AuditEventFilter{Type: &eventType}, want: []int64{updated.Sequence}},
+			{name: "Target type", filter: store.AuditEventFilter{TargetType: &targetType}, want: []int64{client.Sequence}},
+			{name: "Target ID", filter: store.AuditEventFilter{TargetID: &targetID}, want: []int64{created.Sequence, updated.Sequence, client.Sequence}},
+			{name: "Occurred after", filter: store.AuditEventFilter{OccurredAfter: &hourAgo}, want: []int64{created.Sequence, updated.Sequence, client.Sequence}},
+			{name: "Occurred before", filter: store.AuditEventFilter{OccurredBefore: &hourAgo}, want: []int64{}},
+			{name: "Occurred between", filter: store.AuditEventFilter{OccurredAfter: &hourAgo, OccurredBefore: &inAnHour}, want: []int64{created.Sequence, updated.Sequence, client.Sequence}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				got := listAuditSequences(t, repo, store.AuditEventListOptions{Filter: tt.filter, Sort: bySequence})
+				if diff := cmp.Diff(tt.want, got); diff != "" {
+					t.Errorf("ListAuditEvents() mismatch (-want +got):\n%s", diff)
+				}
+			})
+		}
+	})
+
+	t.Run("Paginate", func(t *testing.T) {
+		repo := newRepo(t)
+
+		for i := 0; i < 5; i++ {
+			appendAuditEvent(t, repo, newAuditEvent("user.created", "user", "1"))
+		}
+
+		for _, sort := range []store.Sort{bySequence, {Field: store.SortBySequence, Descending: true}} {
+			t.Run(sort.String(), func(t *testing.T) {
+				all := listAuditSequences(t, repo, store.AuditEventListOptions{Sort: sort})
+
+				var paged []int64
+				page := store.Pagination{Limit: 2}
+				for {
+					events, err := repo.ListAuditEvents(store.AuditEventListOptions{Sort: sort, Pagination: page}, opts())
+					if err != nil {
+						t.Fatalf("ListAuditEvents() error = %v", err)
+					}
+
+					for _, e := range events {
+						paged = append(paged, e.Sequence)
+					}
+
+					if len(events) < page.Limit {
+						break
+					}
+
+					cursor := store.AuditEventCursor(sort, events[len(events)-1])
+					page.After = &cursor
+				}
+
+				if diff := cmp.Diff(all, paged); diff != "" {
+					t.Errorf("paged ListAuditEvents() mismatch (-want +got):\n%s", diff)
+				}
+			})
+		}
+	})
+}
+
+func newAuditEvent(eventType, targetType, targetID string) store.NewAuditEvent {
+	actorID := "00000000-0000-0000-0000-000000000001"
+	return store.NewAuditEvent{
+		Type:       eventType,
+		OccurredAt: time.Now(),
+		ActorType:  "user",
+		ActorID:    &actorID,
+		TargetType: &targetType,
+		TargetID:   &targetID,
+	}
+}
+
+func appendAuditEvent(t *testing.T, repo store.Repository, event store.NewAuditEvent) store.AuditEvent {
+	t.Helper()
+
+	e, err := store.RunUnitOfWork(context.Background(), repo, func(txn *sqlx.Tx) (store.AuditEvent, error) {
+		return repo.AppendAuditEvent(event, store.QueryOptions{Ctx: context.Background(), Txn: txn})
+	})
+	if err != nil {
+		t.Fatalf("AppendAuditEvent() error = %v", err)
+	}
+
+	return e
+}
+
+func listAuditSequences(t *testing.T, repo store.Repository, list store.AuditEventListOptions) []int64 {
+	t.Helper()
+
+	events, err := repo.ListAuditEvents(list, opts())
+	if err != nil {
+		t.Fatalf("ListAuditEvents() error = %v", err)
+	}
+
+	sequences := []int64{}
+	for _, e := range events {
+		sequences = append(sequences, e.Sequence)
+	}
+
+	return sequences
+}
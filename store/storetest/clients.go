@@ -0,0 +1,388 @@
+package storetest
+
+import (
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/google/go-cmp/cmp"
+	"github.com/ninth-realm/heimdall/store"
+)
+
+func testClients(t *testing.T, newRepo Factory) {
+	t.Run("Insert and get", func(t *testing.T) {
+		repo := newRepo(t)
+
+		id := insertClient(t, repo, "Bifrost")
+
+		client, err := repo.GetClientById(id, opts())
+		if err != nil {
+			t.Fatalf("GetClientById() error = %v", err)
+		}
+
+		if client.ID != id || client.Name != "Bifrost" || !client.Enabled {
+			t.Errorf("GetClientById() = %+v, want enabled client %s named Bifrost", client, id)
+		}
+	})
+
+	t.Run("List", func(t *testing.T) {
+		repo := newRepo(t)
+
+		insertClient(t, repo, "Bifrost")
+		insertClient(t, repo, "Gjallarhorn")
+
+		clients, err := repo.ListClients(store.ClientListOptions{Sort: store.Sort{Field: store.SortByCreatedAt}}, opts())
+		if err != nil {
+			t.Fatalf("ListClients() error = %v", err)
+		}
+
+		if len(clients) != 2 {
+			t.Errorf("ListClients() returned %d clients, want 2", len(clients))
+		}
+	})
+
+	t.Run("Save", func(t *testing.T) {
+		repo := newRepo(t)
+
+		id := insertClient(t, repo, "Bifrost")
+
+		client, err := repo.GetClientById(id, opts())
+		if err != nil {
+			t.Fatalf("GetClientById() error = %v", err)
+		}
+
+		client.Name = "Gjallarhorn"
+		client.Enabled = false
+		if err = repo.SaveClient(client, opts()); err != nil {
+			t.Fatalf("SaveClient() error = %v", err)
+		}
+
+		saved, err := repo.GetClientById(id, opts())
+		if err != nil {
+			t.Fatalf("GetClientById() error = %v", err)
+		}
+
+		if saved.Name != "Gjallarhorn" || saved.Enabled {
+			t.Errorf("GetClientById() after save = %+v, want disabled client named Gjallarhorn", saved)
+		}
+	})
+
+	t.Run("Allowed origins", func(t *testing.T) {
+		repo := newRepo(t)
+
+		id, err := repo.InsertClient(store.NewClient{
+			Name:           "Bifrost",
+			Enabled:        true,
+			AllowedOrigins: []string{"https://asgard.example", "https://midgard.example"},
+		}, opts())
+		if err != nil {
+			t.Fatalf("InsertClient() error = %v", err)
+		}
+
+		_, err = repo.InsertClient(store.NewClient{
+			Name:           "Gjallarhorn",
+			Enabled:        true,
+			AllowedOrigins: []string{"https://midgard.example"},
+		}, opts())
+		if err != nil {
+			t.Fatalf("InsertClient() error = %v", err)
+		}
+
+		_, err = repo.InsertClient(store.NewClient{
+			Name:           "Mjolnir",
+			AllowedOrigins: []string{"https://jotunheim.example"},
+		}, opts())
+		if err != nil {
+			t.Fatalf("InsertClient() error = %v", err)
+		}
+
+		insertClient(t, repo, "Gungnir")
+
+		origins, err := repo.ListAllowedOrigins(opts())
+		if err != nil {
+			t.Fatalf("ListAllowedOrigins() error = %v", err)
+		}
+
+		sort.Strings(origins)
+		want := []string{"https://asgard.example", "https://midgard.example"}
+		if diff := cmp.Diff(want, origins); diff != "" {
+			t.Errorf("ListAllowedOrigins() mismatch (-want +got):\n%s", diff)
+		}
+
+		client, err := repo.GetClientById(id, opts())
+		if err != nil {
+			t.Fatalf("GetClientById() error = %v", err)
+		}
+
+		client.AllowedOrigins = nil
+		if err = repo.SaveClient(client, opts()); err != nil {
+			t.Fatalf("SaveClient() error = %v", err)
+		}
+
+		saved, err := repo.GetClientById(id, opts())
+		if err != nil {
+			t.Fatalf("GetClientById() error = %v", err)
+		}
+
+		if len(saved.AllowedOrigins) != 0 {
+			t.Errorf("GetClientById() after save allowed origins = %v, want none", saved.AllowedOrigins)
+		}
+	})
+
+	t.Run("Save outdated version", func(t *testing.T) {
+		repo := newRepo(t)
+
+		id := insertClient(t, repo, "Bifrost")
+
+		client, err := repo.GetClientById(id, opts())
+		if err != nil {
+			t.Fatalf("GetClientById() error = %v", err)
+		}
+
+		stale := client
+		client.Enabled = false
+		if err = repo.SaveClient(client, opts()); err != nil {
+			t.Fatalf("SaveClient() error = %v", err)
+		}
+
+		stale.Name = "Gjallarhorn"
+		if err = repo.SaveClient(stale, opts()); !errors.Is(err, store.VersionMismatchError{}) {
+			t.Errorf("SaveClient() of outdated version error = %v, want VersionMismatchError", err)
+		}
+
+		saved, err := repo.GetClientById(id, opts())
+		if err != nil {
+			t.Fatalf("GetClientById() error = %v", err)
+		}
+
+		if saved.Name != "Bifrost" || saved.Version != client.Version+1 {
+			t.Errorf("GetClientById() after saves = %+v, want only the first save at version %d", saved, client.Version+1)
+		}
+	})
+
+	t.Run("Missing client", func(t *testing.T) {
+		repo := newRepo(t)
+
+		if _, err := repo.GetClientById(uuid.Must(uuid.NewV4()), opts()); !errors.Is(err, store.NotFoundError{}) {
+			t.Errorf("GetClientById() of missing client error = %v, want NotFoundError", err)
+		}
+
+		if err := repo.SaveClient(store.Client{ID: uuid.Must(uuid.NewV4())}, opts()); !errors.Is(err, store.NotFoundError{}) {
+			t.Errorf("SaveClient() of missing client error = %v, want NotFoundError", err)
+		}
+
+		if err := repo.DeleteClient(uuid.Must(uuid.NewV4()), opts()); !errors.Is(err, store.NotFoundError{}) {
+			t.Errorf("DeleteClient() of missing client error = %v, want NotFoundError", err)
+		}
+	})
+
+	t.Run("Duplicate name", func(t *testing.T) {
+		repo := newRepo(t)
+
+		insertClient(t, repo, "Bifrost")
+
+		_, err := repo.InsertClient(store.NewClient{Name: "Bifrost"}, opts())
+		if !errors.Is(err, store.ConflictError{}) {
+			t.Errorf("InsertClient() of duplicate name error = %v, want ConflictError", err)
+		}
+	})
+
+	t.Run("Soft delete and restore", func(t *testing.T) {
+		repo := newRepo(t)
+
+		id := insertClient(t, repo, "Bifrost")
+		insertAPIKey(t, repo, id, "abc123")
+
+		if err := repo.DeleteClient(id, opts()); err != nil {
+			t.Fatalf("DeleteClient() error = %v", err)
+		}
+
+		if _, err := repo.GetClientById(id, opts()); !errors.Is(err, store.NotFoundError{}) {
+			t.Errorf("GetClientById() of deleted client error = %v, want NotFoundError", err)
+		}
+
+		if _, err := repo.GetClientAPIKey(id, "abc123", opts()); !errors.Is(err, store.NotFoundError{}) {
+			t.Errorf("GetClientAPIKey() of deleted client error = %v, want NotFoundError", err)
+		}
+
+		if err := repo.SaveClient(store.Client{ID: id, Name: "Bifrost"}, opts()); !errors.Is(err, store.NotFoundError{}) {
+			t.Errorf("SaveClient() of deleted client error = %v, want NotFoundError", err)
+		}
+
+		if got := listClientIDs(t, repo, store.ClientListOptions{Sort: store.Sort{Field: store.SortByName}}); len(got) != 0 {
+			t.Errorf("ListClients() = %v, want deleted client to be excluded", got)
+		}
+
+		if err := repo.RestoreClient(id, opts()); err != nil {
+			t.Fatalf("RestoreClient() error = %v", err)
+		}
+
+		if _, err := repo.GetClientAPIKey(id, "abc123", opts()); err != nil {
+			t.Errorf("GetClientAPIKey() of restored client error = %v", err)
+		}
+
+		if err := repo.RestoreClient(id, opts()); !errors.Is(err, store.NotFoundError{}) {
+			t.Errorf("RestoreClient() of client that isn't deleted error = %v, want NotFoundError", err)
+		}
+	})
+
+	t.Run("Purge cascades", func(t *testing.T) {
+		repo := newRepo(t)
+
+		id := insertClient(t, repo, "Bifrost")
+		insertAPIKey(t, repo, id, "abc123")
+		liveID := insertClient(t, repo, "Gjallarhorn")
+
+		if err := repo.DeleteClient(id, opts()); err != nil {
+			t.Fatalf("DeleteClient() error = %v", err)
+		}
+
+		n, err := repo.PurgeDeletedClients(time.Now().UTC().Add(-time.Hour), opts())
+		if err != nil {
+			t.Fatalf("PurgeDeletedClients() error = %v", err)
+		}
+
+		if n != 0 {
+			t.Errorf("PurgeDeletedClients() of recently deleted client = %d, want 0", n)
+		}
+
+		n, err = repo.PurgeDeletedClients(time.Now().UTC().Add(time.Hour), opts())
+		if err != nil {
+			t.Fatalf("PurgeDeletedClients() error = %v", err)
+		}
+
+		if n != 1 {
+			t.Errorf("PurgeDeletedClients() = %d, want 1", n)
+		}
+
+		if err = repo.RestoreClient(id, opts()); !errors.Is(err, store.NotFoundError{}) {
+			t.Errorf("RestoreClient() of purged client error = %v, want NotFoundError", err)
+		}
+
+		if _, err = repo.GetClientById(liveID, opts()); err != nil {
+			t.Errorf("GetClientById() of client that wasn't deleted error = %v", err)
+		}
+
+		// The name and API key prefix must have been freed as well.
+		insertAPIKey(t, repo, insertClient(t, repo, "Bifrost"), "abc123")
+	})
+}
+
+func testAPIKeys(t *testing.T, newRepo Factory) {
+	t.Run("Insert and get", func(t *testing.T) {
+		repo := newRepo(t)
+
+		clientID := insertClient(t, repo, "Bifrost")
+		description := "Bifrost key"
+		id, err := repo.InsertAPIKey(store.NewAPIKey{
+			ClientID:    clientID,
+			Description: &description,
+			Prefix:      "abc123",
+			Hash:        "hash",
+		}, opts())
+		if err != nil {
+			t.Fatalf("InsertAPIKey() error = %v", err)
+		}
+
+		key, err := repo.GetClientAPIKey(clientID, "abc123", opts())
+		if err != nil {
+			t.Fatalf("GetClientAPIKey() error = %v", err)
+		}
+
+		if key.ID != id || key.ClientID != clientID || key.Hash != "hash" {
+			t.Errorf("GetClientAPIKey() = %+v, want key %s", key, id)
+		}
+
+		if key.Description == nil || *key.Description != description {
+			t.Errorf("GetClientAPIKey() description = %v, want %q", key.Description, description)
+		}
+	})
+
+	t.Run("List", func(t *testing.T) {
+		repo := newRepo(t)
+
+		clientID := insertClient(t, repo, "Bifrost")
+		otherID := insertClient(t, repo, "Gjallarhorn")
+		insertAPIKey(t, repo, clientID, "abc123")
+		insertAPIKey(t, repo, clientID, "def456")
+		insertAPIKey(t, repo, otherID, "fed654")
+
+		keys, err := repo.ListClientAPIKeys(clientID, opts())
+		if err != nil {
+			t.Fatalf("ListClientAPIKeys() error = %v", err)
+		}
+
+		if len(keys) != 2 {
+			t.Errorf("ListClientAPIKeys() returned %d keys, want 2", len(keys))
+		}
+	})
+
+	t.Run("Key belongs to client", func(t *testing.T) {
+		repo := newRepo(t)
+
+		clientID := insertClient(t, repo, "Bifrost")
+		otherID := insertClient(t, repo, "Gjallarhorn")
+		keyID := insertAPIKey(t, repo, clientID, "abc123")
+
+		if _, err := repo.GetClientAPIKey(otherID, "abc123", opts()); !errors.Is(err, store.NotFoundError{}) {
+			t.Errorf("GetClientAPIKey() of another client's key error = %v, want NotFoundError", err)
+		}
+
+		if err := repo.DeleteClientAPIKey(otherID, keyID, opts()); !errors.Is(err, store.NotFoundError{}) {
+			t.Errorf("DeleteClientAPIKey() of another client's key error = %v, want NotFoundError", err)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		repo := newRepo(t)
+
+		clientID := insertClient(t, repo, "Bifrost")
+		keyID := insertAPIKey(t, repo, clientID, "abc123")
+
+		if err := repo.DeleteClientAPIKey(clientID, keyID, opts()); err != nil {
+			t.Fatalf("DeleteClientAPIKey() error = %v", err)
+		}
+
+		if _, err := repo.GetClientAPIKey(clientID, "abc123", opts()); !errors.Is(err, store.NotFoundError{}) {
+			t.Errorf("GetClientAPIKey() of deleted key error = %v, want NotFoundError", err)
+		}
+
+		if err := repo.DeleteClientAPIKey(clientID, keyID, opts()); !errors.Is(err, store.NotFoundError{}) {
+			t.Errorf("DeleteClientAPIKey() of missing key error = %v, want NotFoundError", err)
+		}
+	})
+
+	t.Run("Key for missing client", func(t *testing.T) {
+		repo := newRepo(t)
+
+		_, err := repo.InsertAPIKey(store.NewAPIKey{ClientID: uuid.Must(uuid.NewV4()), Prefix: "abc123", Hash: "hash"}, opts())
+		if !errors.Is(err, store.ConstraintViolationError{}) {
+			t.Errorf("InsertAPIKey() for missing client error = %v, want ConstraintViolationError", err)
+		}
+	})
+
+	t.Run("Duplicate prefix", func(t *testing.T) {
+		repo := newRepo(t)
+
+		clientID := insertClient(t, repo, "Bifrost")
+		insertAPIKey(t, repo, clientID, "abc123")
+
+		_, err := repo.InsertAPIKey(store.NewAPIKey{ClientID: clientID, Prefix: "abc123", Hash: "hash"}, opts())
+		if !errors.Is(err, store.ConflictError{}) {
+			t.Errorf("InsertAPIKey() of duplicate prefix error = %v, want ConflictError", err)
+		}
+	})
+}
+
+func insertAPIKey(t *testing.T, repo store.Repository, clientID uuid.UUID, prefix string) uuid.UUID {
+	t.Helper()
+
+	id, err := repo.InsertAPIKey(store.NewAPIKey{ClientID: clientID, Prefix: prefix, Hash: "hash"}, opts())
+	if err != nil {
+		t.Fatalf("InsertAPIKey() error = %v", err)
+	}
+
+	return id
+}
@@ -0,0 +1,197 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/ninth-realm/heimdall/store"
+)
+
+func testOutboxEvents(t *testing.T, newRepo Factory) {
+	t.Run("Append and list", func(t *testing.T) {
+		repo := newRepo(t)
+
+		actorID := "1"
+		first := appendOutboxEvent(t, repo, store.NewOutboxEvent{
+			Type:       "user.created",
+			OccurredAt: time.Now(),
+			ActorType:  "user",
+			ActorID:    &actorID,
+			Data:       store.RawJSON(`{"user":{"id":"1"}}`),
+		})
+		second := appendOutboxEvent(t, repo, newOutboxEvent("user.updated"))
+		third := appendOutboxEvent(t, repo, newOutboxEvent("user.deleted"))
+
+		if first.Sequence != 1 || second.Sequence != 2 || third.Sequence != 3 {
+			t.Errorf("AppendOutboxEvent() sequences = %d, %d, %d, want 1, 2, 3", first.Sequence, second.Sequence, third.Sequence)
+		}
+
+		events := listOutboxEvents(t, repo, 0, 10)
+		if len(events) != 3 {
+			t.Fatalf("ListOutboxEvents() returned %d events, want 3", len(events))
+		}
+
+		got := events[0]
+		if got.ID != first.ID || got.Type != "user.created" || got.ActorID == nil || *got.ActorID != actorID {
+			t.Errorf("ListOutboxEvents() first = %+v, want %+v", got, first)
+		}
+
+		if string(got.Data) != `{"user":{"id":"1"}}` {
+			t.Errorf("ListOutboxEvents() data = %s, want %s", got.Data, first.Data)
+		}
+
+		if !got.OccurredAt.Equal(first.OccurredAt) {
+			t.Errorf("ListOutboxEvents() OccurredAt = %v, want %v", got.OccurredAt, first.OccurredAt)
+		}
+
+		events = listOutboxEvents(t, repo, 1, 1)
+		if len(events) != 1 || events[0].Sequence != 2 {
+			t.Errorf("ListOutboxEvents() after 1 = %v, want only event 2", events)
+		}
+	})
+
+	t.Run("Rolled back events are not kept", func(t *testing.T) {
+		repo := newRepo(t)
+
+		errAbort := errors.New("abort")
+		_, err := store.RunUnitOfWork(context.Background(), repo, func(txn *sqlx.Tx) (store.OutboxEvent, error) {
+			opts := store.QueryOptions{Ctx: context.Background(), Txn: txn}
+			if _, err := repo.AppendOutboxEvent(newOutboxEvent("user.created"), opts); err != nil {
+				return store.OutboxEvent{}, err
+			}
+
+			return store.OutboxEvent{}, errAbort
+		})
+		if !errors.Is(err, errAbort) {
+			t.Fatalf("RunUnitOfWork() error = %v, want %v", err, errAbort)
+		}
+
+		if events := listOutboxEvents(t, repo, 0, 10); len(events) != 0 {
+			t.Errorf("ListOutboxEvents() after rollback = %v, want none", events)
+		}
+
+		if event := appendOutboxEvent(t, repo, newOutboxEvent("user.created")); event.Sequence != 1 {
+			t.Errorf("AppendOutboxEvent() after rollback sequence = %d, want 1", event.Sequence)
+		}
+	})
+
+	t.Run("Subscriber positions", func(t *testing.T) {
+		repo := newRepo(t)
+
+		position, err := repo.GetEventSubscriberPosition("audit", opts())
+		if err != nil || position != 0 {
+			t.Fatalf("GetEventSubscriberPosition() of new subscriber = %d, %v, want 0", position, err)
+		}
+
+		if err := repo.SaveEventSubscriberPosition("audit", 0, 2, opts()); err != nil {
+			t.Fatalf("SaveEventSubscriberPosition() error = %v", err)
+		}
+
+		if err := repo.SaveEventSubscriberPosition("audit", 2, 3, opts()); err != nil {
+			t.Fatalf("SaveEventSubscriberPosition() error = %v", err)
+		}
+
+		position, err = repo.GetEventSubscriberPosition("audit", opts())
+		if err != nil || position != 3 {
+			t.Errorf("GetEventSubscriberPosition() = %d, %v, want 3", position, err)
+		}
+
+		for _, from := range []int64{0, 2} {
+			err = repo.SaveEventSubscriberPosition("audit", from, 4, opts())
+			if !errors.Is(err, store.VersionMismatchError{}) {
+				t.Errorf("SaveEventSubscriberPosition() from stale %d error = %v, want VersionMismatchError", from, err)
+			}
+		}
+
+		position, err = repo.GetEventSubscriberPosition("webhooks", opts())
+		if err != nil || position != 0 {
+			t.Errorf("GetEventSubscriberPosition() of other subscriber = %d, %v, want 0", position, err)
+		}
+	})
+
+	t.Run("Delete dispatched events", func(t *testing.T) {
+		repo := newRepo(t)
+
+		for i := 0; i < 3; i++ {
+			appendOutboxEvent(t, repo, newOutboxEvent("user.created"))
+		}
+
+		later := time.Now().Add(time.Hour)
+		subscribers := []string{"audit", "webhooks"}
+
+		n, err := repo.DeleteDispatchedOutboxEvents(nil, later, opts())
+		if err != nil || n != 0 {
+			t.Errorf("DeleteDispatchedOutboxEvents() without subscribers = %d, %v, want 0", n, err)
+		}
+
+		n, err = repo.DeleteDispatchedOutboxEvents(subscribers, later, opts())
+		if err != nil || n != 0 {
+			t.Errorf("DeleteDispatchedOutboxEvents() before any are handled = %d, %v, want 0", n, err)
+		}
+
+		if err := repo.SaveEventSubscriberPosition("audit", 0, 3, opts()); err != nil {
+			t.Fatalf("SaveEventSubscriberPosition() error = %v", err)
+		}
+
+		// webhooks hasn't handled any events, so none can be deleted.
+		n, err = repo.DeleteDispatchedOutboxEvents(subscribers, later, opts())
+		if err != nil || n != 0 {
+			t.Errorf("DeleteDispatchedOutboxEvents() with a subscriber that hasn't moved = %d, %v, want 0", n, err)
+		}
+
+		if err := repo.SaveEventSubscriberPosition("webhooks", 0, 2, opts()); err != nil {
+			t.Fatalf("SaveEventSubscriberPosition() error = %v", err)
+		}
+
+		n, err = repo.DeleteDispatchedOutboxEvents(subscribers, time.Now().Add(-time.Hour), opts())
+		if err != nil || n != 0 {
+			t.Errorf("DeleteDispatchedOutboxEvents() of recent events = %d, %v, want 0", n, err)
+		}
+
+		n, err = repo.DeleteDispatchedOutboxEvents(subscribers, later, opts())
+		if err != nil || n != 2 {
+			t.Errorf("DeleteDispatchedOutboxEvents() = %d, %v, want 2", n, err)
+		}
+
+		events := listOutboxEvents(t, repo, 0, 10)
+		if len(events) != 1 || events[0].Sequence != 3 {
+			t.Errorf("ListOutboxEvents() after delete = %v, want only event 3", events)
+		}
+	})
+}
+
+func newOutboxEvent(eventType string) store.NewOutboxEvent {
+	return store.NewOutboxEvent{
+		Type:       eventType,
+		OccurredAt: time.Now(),
+		ActorType:  "system",
+		Data:       store.RawJSON("null"),
+	}
+}
+
+func appendOutboxEvent(t *testing.T, repo store.Repository, event store.NewOutboxEvent) store.OutboxEvent {
+	t.Helper()
+
+	e, err := store.RunUnitOfWork(context.Background(), repo, func(txn *sqlx.Tx) (store.OutboxEvent, error) {
+		return repo.AppendOutboxEvent(event, store.QueryOptions{Ctx: context.Background(), Txn: txn})
+	})
+	if err != nil {
+		t.Fatalf("AppendOutboxEvent() error = %v", err)
+	}
+
+	return e
+}
+
+func listOutboxEvents(t *testing.T, repo store.Repository, after int64, limit int) []store.OutboxEvent {
+	t.Helper()
+
+	events, err := repo.ListOutboxEvents(after, limit, opts())
+	if err != nil {
+		t.Fatalf("ListOutboxEvents() error = %v", err)
+	}
+
+	return events
+}
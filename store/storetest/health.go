@@ -0,0 +1,33 @@
+package storetest
+
+import (
+	"context"
+	"testing"
+)
+
+func testHealth(t *testing.T, newRepo Factory) {
+	t.Run("Ping", func(t *testing.T) {
+		repo := newRepo(t)
+
+		if err := repo.Ping(context.Background()); err != nil {
+			t.Errorf("Ping() error = %v", err)
+		}
+	})
+
+	t.Run("Migrated database", func(t *testing.T) {
+		repo := newRepo(t)
+
+		v, err := repo.GetMigrationVersion(opts())
+		if err != nil {
+			t.Fatalf("GetMigrationVersion() error = %v", err)
+		}
+
+		if v.Version == 0 {
+			t.Error("GetMigrationVersion() version = 0, want the latest migration")
+		}
+
+		if v.Dirty {
+			t.Error("GetMigrationVersion() dirty = true, want false")
+		}
+	})
+}
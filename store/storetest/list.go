@@ -0,0 +1,282 @@
+package storetest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/google/go-cmp/cmp"
+	"github.com/ninth-realm/heimdall/store"
+)
+
+func testListUsers(t *testing.T, newRepo Factory) {
+	byCreatedAt := store.Sort{Field: store.SortByCreatedAt}
+
+	t.Run("Filter by email", func(t *testing.T) {
+		repo := newRepo(t)
+
+		id := insertUser(t, repo, "john.doe@example.com")
+		insertUser(t, repo, "jane.doe@example.com")
+
+		email := "john.doe@example.com"
+		got := listUserIDs(t, repo, store.UserListOptions{Filter: store.UserFilter{Email: &email}, Sort: byCreatedAt})
+		if diff := cmp.Diff([]uuid.UUID{id}, got); diff != "" {
+			t.Errorf("ListUsers() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("Filter by name prefix", func(t *testing.T) {
+		repo := newRepo(t)
+
+		john := insertNamedUser(t, repo, "John", "Smith")
+		jones := insertNamedUser(t, repo, "Ada", "Jones")
+		insertNamedUser(t, repo, "Jane_", "Doe")
+		insertNamedUser(t, repo, "Mary", "Major")
+
+		prefix := "jo"
+		got := listUserIDs(t, repo, store.UserListOptions{
+			Filter: store.UserFilter{NamePrefix: &prefix},
+			Sort:   store.Sort{Field: store.SortByFirstName},
+		})
+		if diff := cmp.Diff([]uuid.UUID{jones, john}, got); diff != "" {
+			t.Errorf("ListUsers() mismatch (-want +got):\n%s", diff)
+		}
+
+		// Wildcards in the prefix must be matched literally.
+		prefix = "j_n"
+		got = listUserIDs(t, repo, store.UserListOptions{Filter: store.UserFilter{NamePrefix: &prefix}, Sort: byCreatedAt})
+		if len(got) != 0 {
+			t.Errorf("ListUsers() with wildcard prefix returned %d users, want 0", len(got))
+		}
+	})
+
+	t.Run("Filter by created at", func(t *testing.T) {
+		repo := newRepo(t)
+
+		id := insertUser(t, repo, "john.doe@example.com")
+
+		hourAgo := time.Now().UTC().Add(-time.Hour)
+		inAnHour := time.Now().UTC().Add(time.Hour)
+
+		got := listUserIDs(t, repo, store.UserListOptions{
+			Filter: store.UserFilter{CreatedAfter: &hourAgo, CreatedBefore: &inAnHour},
+			Sort:   byCreatedAt,
+		})
+		if diff := cmp.Diff([]uuid.UUID{id}, got); diff != "" {
+			t.Errorf("ListUsers() mismatch (-want +got):\n%s", diff)
+		}
+
+		got = listUserIDs(t, repo, store.UserListOptions{Filter: store.UserFilter{CreatedAfter: &inAnHour}, Sort: byCreatedAt})
+		if len(got) != 0 {
+			t.Errorf("ListUsers() created after an hour from now returned %d users, want 0", len(got))
+		}
+
+		got = listUserIDs(t, repo, store.UserListOptions{Filter: store.UserFilter{CreatedBefore: &hourAgo}, Sort: byCreatedAt})
+		if len(got) != 0 {
+			t.Errorf("ListUsers() created before an hour ago returned %d users, want 0", len(got))
+		}
+	})
+
+	t.Run("Sort", func(t *testing.T) {
+		repo := newRepo(t)
+
+		charlie := insertNamedUser(t, repo, "Charlie", "Alpha")
+		alice := insertNamedUser(t, repo, "Alice", "Charlie")
+		bob := insertNamedUser(t, repo, "Bob", "Bravo")
+
+		tests := []struct {
+			sort string
+			want []uuid.UUID
+		}{
+			{sort: "firstName", want: []uuid.UUID{alice, bob, charlie}},
+			{sort: "-firstName", want: []uuid.UUID{charlie, bob, alice}},
+			{sort: "lastName", want: []uuid.UUID{charlie, bob, alice}},
+			{sort: "-lastName", want: []uuid.UUID{alice, bob, charlie}},
+		}
+		for _, tt := range tests {
+			got := listUserIDs(t, repo, store.UserListOptions{Sort: store.ParseSort(tt.sort)})
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("ListUsers() sorted by %s mismatch (-want +got):\n%s", tt.sort, diff)
+			}
+		}
+	})
+
+	t.Run("Paginate", func(t *testing.T) {
+		repo := newRepo(t)
+
+		// Users created in quick succession often share a creation time, so
+		// paging by it also checks that ties are handled.
+		var want []uuid.UUID
+		for _, name := range []string{"Alice", "Bob", "Charlie", "Dave", "Eve"} {
+			want = append(want, insertNamedUser(t, repo, name, "Doe"))
+		}
+
+		for _, sort := range []string{"firstName", "-firstName", "createdAt", "-createdAt"} {
+			list := store.UserListOptions{Sort: store.ParseSort(sort)}
+			all := listUserIDs(t, repo, list)
+			if len(all) != len(want) {
+				t.Fatalf("ListUsers() sorted by %s returned %d users, want %d", sort, len(all), len(want))
+			}
+
+			var paged []uuid.UUID
+			list.Pagination.Limit = 2
+			for pages := 0; pages < len(want); pages++ {
+				users, err := repo.ListUsers(list, opts())
+				if err != nil {
+					t.Fatalf("ListUsers() error = %v", err)
+				}
+
+				if len(users) > list.Pagination.Limit {
+					t.Fatalf("ListUsers() returned %d users, want at most %d", len(users), list.Pagination.Limit)
+				}
+
+				for _, user := range users {
+					paged = append(paged, user.ID)
+				}
+
+				if len(users) < list.Pagination.Limit {
+					break
+				}
+
+				cursor := store.UserCursor(list.Sort, users[len(users)-1])
+				list.Pagination.After = &cursor
+			}
+
+			if diff := cmp.Diff(all, paged); diff != "" {
+				t.Errorf("Paging through users sorted by %s mismatch (-want +got):\n%s", sort, diff)
+			}
+		}
+	})
+
+	t.Run("Invalid options", func(t *testing.T) {
+		repo := newRepo(t)
+
+		_, err := repo.ListUsers(store.UserListOptions{Sort: store.Sort{Field: "password"}}, opts())
+		if !errors.Is(err, store.ErrInvalidSort) {
+			t.Errorf("ListUsers() with invalid sort error = %v, want ErrInvalidSort", err)
+		}
+
+		cursor := store.Cursor{Sort: "createdAt", Value: "yesterday", ID: uuid.Must(uuid.NewV4())}
+		_, err = repo.ListUsers(store.UserListOptions{Sort: byCreatedAt, Pagination: store.Pagination{After: &cursor}}, opts())
+		if !errors.Is(err, store.ErrInvalidCursor) {
+			t.Errorf("ListUsers() with invalid cursor error = %v, want ErrInvalidCursor", err)
+		}
+	})
+}
+
+func testListClients(t *testing.T, newRepo Factory) {
+	byName := store.Sort{Field: store.SortByName}
+
+	t.Run("Filter by name", func(t *testing.T) {
+		repo := newRepo(t)
+
+		id := insertClient(t, repo, "Bifrost")
+		insertClient(t, repo, "Gjallarhorn")
+
+		name := "Bifrost"
+		got := listClientIDs(t, repo, store.ClientListOptions{Filter: store.ClientFilter{Name: &name}, Sort: byName})
+		if diff := cmp.Diff([]uuid.UUID{id}, got); diff != "" {
+			t.Errorf("ListClients() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("Filter by enabled", func(t *testing.T) {
+		repo := newRepo(t)
+
+		enabledID := insertClient(t, repo, "Bifrost")
+		disabledID, err := repo.InsertClient(store.NewClient{Name: "Gjallarhorn", Enabled: false}, opts())
+		if err != nil {
+			t.Fatalf("InsertClient() error = %v", err)
+		}
+
+		for _, enabled := range []bool{true, false} {
+			want := []uuid.UUID{enabledID}
+			if !enabled {
+				want = []uuid.UUID{disabledID}
+			}
+
+			got := listClientIDs(t, repo, store.ClientListOptions{Filter: store.ClientFilter{Enabled: &enabled}, Sort: byName})
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("ListClients() with enabled %t mismatch (-want +got):\n%s", enabled, diff)
+			}
+		}
+	})
+
+	t.Run("Paginate", func(t *testing.T) {
+		repo := newRepo(t)
+
+		var want []uuid.UUID
+		for _, name := range []string{"Bifrost", "Gjallarhorn", "Hofund", "Mjolnir", "Yggdrasil"} {
+			want = append(want, insertClient(t, repo, name))
+		}
+
+		list := store.ClientListOptions{Sort: byName, Pagination: store.Pagination{Limit: 2}}
+
+		var got []uuid.UUID
+		for pages := 0; pages < len(want); pages++ {
+			clients, err := repo.ListClients(list, opts())
+			if err != nil {
+				t.Fatalf("ListClients() error = %v", err)
+			}
+
+			for _, client := range clients {
+				got = append(got, client.ID)
+			}
+
+			if len(clients) < list.Pagination.Limit {
+				break
+			}
+
+			cursor := store.ClientCursor(list.Sort, clients[len(clients)-1])
+			list.Pagination.After = &cursor
+		}
+
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Paging through clients mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func insertNamedUser(t *testing.T, repo store.Repository, firstName, lastName string) uuid.UUID {
+	t.Helper()
+
+	id, err := repo.InsertUser(store.NewUser{FirstName: firstName, LastName: lastName}, opts())
+	if err != nil {
+		t.Fatalf("InsertUser() error = %v", err)
+	}
+
+	return id
+}
+
+func listUserIDs(t *testing.T, repo store.Repository, list store.UserListOptions) []uuid.UUID {
+	t.Helper()
+
+	users, err := repo.ListUsers(list, opts())
+	if err != nil {
+		t.Fatalf("ListUsers() error = %v", err)
+	}
+
+	ids := []uuid.UUID{}
+	for _, user := range users {
+		ids = append(ids, user.ID)
+	}
+
+	return ids
+}
+
+func listClientIDs(t *testing.T, repo store.Repository, list store.ClientListOptions) []uuid.UUID {
+	t.Helper()
+
+	clients, err := repo.ListClients(list, opts())
+	if err != nil {
+		t.Fatalf("ListClients() error = %v", err)
+	}
+
+	ids := []uuid.UUID{}
+	for _, client := range clients {
+		ids = append(ids, client.ID)
+	}
+
+	return ids
+}
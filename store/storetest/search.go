@@ -0,0 +1,202 @@
+package storetest
+
+import (
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/google/go-cmp/cmp"
+	"github.com/ninth-realm/heimdall/store"
+)
+
+func testSearchUsers(t *testing.T, newRepo Factory) {
+	t.Run("Match name or email", func(t *testing.T) {
+		repo := newRepo(t)
+
+		john := insertSearchableUser(t, repo, "John", "Smith", "jsmith@example.com")
+		ada := insertSearchableUser(t, repo, "Ada", "Johnson", "ada@example.org")
+		insertSearchableUser(t, repo, "Mary", "Major", "mary@example.net")
+
+		tests := []struct {
+			query string
+			want  []uuid.UUID
+		}{
+			{query: "jo", want: []uuid.UUID{ada, john}},
+			{query: "SMI", want: []uuid.UUID{john}},
+			{query: "jsmith", want: []uuid.UUID{john}},
+			{query: "example.org", want: []uuid.UUID{ada}},
+			{query: "ada johnson", want: []uuid.UUID{ada}},
+			{query: "john major", want: []uuid.UUID{}},
+			{query: "zz", want: []uuid.UUID{}},
+			{query: "@.", want: []uuid.UUID{}},
+		}
+		for _, tt := range tests {
+			got := searchUserIDs(t, repo, tt.query)
+			if diff := cmp.Diff(tt.want, got, sortIDs); diff != "" {
+				t.Errorf("SearchUsers(%q) mismatch (-want +got):\n%s", tt.query, diff)
+			}
+		}
+	})
+
+	t.Run("Include matched email", func(t *testing.T) {
+		repo := newRepo(t)
+
+		id := insertSearchableUser(t, repo, "John", "Smith", "john@example.com")
+		if _, err := repo.InsertEmail(store.NewEmail{UserID: id, Email: "smithy@example.org"}, opts()); err != nil {
+			t.Fatalf("InsertEmail() error = %v", err)
+		}
+
+		results, err := repo.SearchUsers(store.UserSearchOptions{Query: "smithy"}, opts())
+		if err != nil {
+			t.Fatalf("SearchUsers() error = %v", err)
+		}
+
+		if len(results) != 1 || results[0].ID != id || results[0].Email != "smithy@example.org" {
+			t.Errorf("SearchUsers() = %+v, want user %s matched by smithy@example.org", results, id)
+		}
+
+		// A user with several matching email addresses is only found once.
+		results, err = repo.SearchUsers(store.UserSearchOptions{Query: "john"}, opts())
+		if err != nil {
+			t.Fatalf("SearchUsers() error = %v", err)
+		}
+
+		if len(results) != 1 || results[0].FirstName != "John" {
+			t.Errorf("SearchUsers() = %+v, want user %s once", results, id)
+		}
+	})
+
+	t.Run("Rank", func(t *testing.T) {
+		repo := newRepo(t)
+
+		// Words that appear in every document don't say much about relevance,
+		// so some unrelated users are needed for the ranking to be meaningful.
+		insertSearchableUser(t, repo, "Ada", "Lovelace", "ada@example.org")
+		insertSearchableUser(t, repo, "Mary", "Major", "mary@example.net")
+		partial := insertSearchableUser(t, repo, "Jane", "Smith", "jane@example.com")
+		full := insertSearchableUser(t, repo, "Smith", "Smith", "smith@example.com")
+
+		got := searchUserIDs(t, repo, "smith")
+		if diff := cmp.Diff([]uuid.UUID{full, partial}, got); diff != "" {
+			t.Errorf("SearchUsers() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("Index is kept in sync", func(t *testing.T) {
+		repo := newRepo(t)
+
+		id := insertSearchableUser(t, repo, "John", "Smith", "john@example.com")
+		otherID := insertSearchableUser(t, repo, "Jane", "Smith", "jane@example.com")
+
+		user, err := repo.GetUserById(id, opts())
+		if err != nil {
+			t.Fatalf("GetUserById() error = %v", err)
+		}
+
+		user.LastName = "Jones"
+		if err = repo.SaveUser(user, opts()); err != nil {
+			t.Fatalf("SaveUser() error = %v", err)
+		}
+
+		if got := searchUserIDs(t, repo, "jones"); !cmp.Equal([]uuid.UUID{id}, got) {
+			t.Errorf("SearchUsers() after rename = %v, want %v", got, []uuid.UUID{id})
+		}
+
+		if err = repo.DeleteUser(otherID, opts()); err != nil {
+			t.Fatalf("DeleteUser() error = %v", err)
+		}
+
+		if got := searchUserIDs(t, repo, "smith"); len(got) != 0 {
+			t.Errorf("SearchUsers() after rename and delete = %v, want no users", got)
+		}
+	})
+
+	t.Run("Paginate", func(t *testing.T) {
+		repo := newRepo(t)
+
+		// Identical users score the same, so paging also checks that ties
+		// are handled.
+		for i := 0; i < 5; i++ {
+			insertSearchableUser(t, repo, "John", "Doe", uuid.Must(uuid.NewV4()).String()+"@example.com")
+		}
+
+		all := searchUserIDs(t, repo, "doe")
+		if len(all) != 5 {
+			t.Fatalf("SearchUsers() returned %d users, want 5", len(all))
+		}
+
+		search := store.UserSearchOptions{Query: "doe", Pagination: store.Pagination{Limit: 2}}
+
+		var paged []uuid.UUID
+		for pages := 0; pages < len(all); pages++ {
+			results, err := repo.SearchUsers(search, opts())
+			if err != nil {
+				t.Fatalf("SearchUsers() error = %v", err)
+			}
+
+			for _, result := range results {
+				paged = append(paged, result.ID)
+			}
+
+			if len(results) < search.Pagination.Limit {
+				break
+			}
+
+			cursor := store.UserSearchCursor(results[len(results)-1])
+			search.Pagination.After = &cursor
+		}
+
+		if diff := cmp.Diff(all, paged); diff != "" {
+			t.Errorf("Paging through search results mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("Invalid cursor", func(t *testing.T) {
+		repo := newRepo(t)
+
+		cursor := store.Cursor{Sort: store.UserSearchSort.String(), Value: "best", ID: uuid.Must(uuid.NewV4())}
+		_, err := repo.SearchUsers(store.UserSearchOptions{Query: "john", Pagination: store.Pagination{After: &cursor}}, opts())
+		if !errors.Is(err, store.ErrInvalidCursor) {
+			t.Errorf("SearchUsers() with invalid cursor error = %v, want ErrInvalidCursor", err)
+		}
+	})
+}
+
+// sortIDs makes cmp ignore the order of IDs, for searches where every result
+// is expected to score the same.
+var sortIDs = cmp.Transformer("Sort", func(ids []uuid.UUID) []string {
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = id.String()
+	}
+	sort.Strings(s)
+	return s
+})
+
+func insertSearchableUser(t *testing.T, repo store.Repository, firstName, lastName, email string) uuid.UUID {
+	t.Helper()
+
+	id := insertNamedUser(t, repo, firstName, lastName)
+	if _, err := repo.InsertEmail(store.NewEmail{UserID: id, Email: email}, opts()); err != nil {
+		t.Fatalf("InsertEmail() error = %v", err)
+	}
+
+	return id
+}
+
+func searchUserIDs(t *testing.T, repo store.Repository, query string) []uuid.UUID {
+	t.Helper()
+
+	results, err := repo.SearchUsers(store.UserSearchOptions{Query: query}, opts())
+	if err != nil {
+		t.Fatalf("SearchUsers() error = %v", err)
+	}
+
+	ids := []uuid.UUID{}
+	for _, result := range results {
+		ids = append(ids, result.ID)
+	}
+
+	return ids
+}
@@ -0,0 +1,355 @@
+package storetest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/ninth-realm/heimdall/store"
+)
+
+func testSessions(t *testing.T, newRepo Factory) {
+	t.Run("Insert and get", func(t *testing.T) {
+		repo := newRepo(t)
+
+		userID := insertUser(t, repo, "john.doe@example.com")
+		userAgent := "Mozilla/5.0"
+		ipAddress := "127.0.0.1"
+		createdAt := time.Now().UTC().Truncate(time.Second)
+		expiresAt := createdAt.Add(time.Hour)
+
+		id, err := repo.InsertSession(store.NewSession{
+			TokenHash:  "token",
+			UserID:     userID,
+			UserAgent:  &userAgent,
+			IPAddress:  &ipAddress,
+			RememberMe: true,
+			CreatedAt:  createdAt,
+			ExpiresAt:  expiresAt,
+		}, opts())
+		if err != nil {
+			t.Fatalf("InsertSession() error = %v", err)
+		}
+
+		session, err := repo.GetSession("token", opts())
+		if err != nil {
+			t.Fatalf("GetSession() error = %v", err)
+		}
+
+		if session.ID != id || session.UserId != userID || !session.RememberMe {
+			t.Errorf("GetSession() = %+v, want remember me session %s for user %s", session, id, userID)
+		}
+
+		if session.UserAgent == nil || *session.UserAgent != userAgent {
+			t.Errorf("GetSession() user agent = %v, want %q", session.UserAgent, userAgent)
+		}
+
+		if session.IPAddress == nil || *session.IPAddress != ipAddress {
+			t.Errorf("GetSession() IP address = %v, want %q", session.IPAddress, ipAddress)
+		}
+
+		for name, got := range map[string]time.Time{
+			"created at":       session.CreatedAt,
+			"last seen at":     session.LastSeenAt,
+			"authenticated at": session.AuthenticatedAt,
+		} {
+			if !got.Equal(createdAt) {
+				t.Errorf("GetSession() %s = %v, want %v", name, got, createdAt)
+			}
+		}
+
+		if !session.ExpiresAt.Equal(expiresAt) {
+			t.Errorf("GetSession() expires at = %v, want %v", session.ExpiresAt, expiresAt)
+		}
+	})
+
+	t.Run("Missing session", func(t *testing.T) {
+		repo := newRepo(t)
+
+		if _, err := repo.GetSession("token", opts()); !errors.Is(err, store.NotFoundError{}) {
+			t.Errorf("GetSession() of missing session error = %v, want NotFoundError", err)
+		}
+
+		if err := repo.DeleteSession("token", opts()); !errors.Is(err, store.NotFoundError{}) {
+			t.Errorf("DeleteSession() of missing session error = %v, want NotFoundError", err)
+		}
+
+		now := time.Now().UTC()
+		err := repo.ReauthenticateSession(uuid.Must(uuid.NewV4()), now, opts())
+		if !errors.Is(err, store.NotFoundError{}) {
+			t.Errorf("ReauthenticateSession() of missing session error = %v, want NotFoundError", err)
+		}
+
+		err = repo.TouchSession(uuid.Must(uuid.NewV4()), now, now.Add(time.Hour), opts())
+		if !errors.Is(err, store.NotFoundError{}) {
+			t.Errorf("TouchSession() of missing session error = %v, want NotFoundError", err)
+		}
+	})
+
+	t.Run("Duplicate token", func(t *testing.T) {
+		repo := newRepo(t)
+
+		userID := insertUser(t, repo, "john.doe@example.com")
+		insertSession(t, repo, userID, "token")
+
+		now := time.Now().UTC()
+		_, err := repo.InsertSession(store.NewSession{
+			TokenHash: "token",
+			UserID:    userID,
+			CreatedAt: now,
+			ExpiresAt: now.Add(time.Hour),
+		}, opts())
+		if !errors.Is(err, store.ConflictError{}) {
+			t.Errorf("InsertSession() of duplicate token error = %v, want ConflictError", err)
+		}
+	})
+
+	t.Run("Session for missing user", func(t *testing.T) {
+		repo := newRepo(t)
+
+		now := time.Now().UTC()
+		_, err := repo.InsertSession(store.NewSession{
+			TokenHash: "token",
+			UserID:    uuid.Must(uuid.NewV4()),
+			CreatedAt: now,
+			ExpiresAt: now.Add(time.Hour),
+		}, opts())
+		if !errors.Is(err, store.ConstraintViolationError{}) {
+			t.Errorf("InsertSession() for missing user error = %v, want ConstraintViolationError", err)
+		}
+	})
+
+	t.Run("Expired session", func(t *testing.T) {
+		repo := newRepo(t)
+
+		userID := insertUser(t, repo, "john.doe@example.com")
+		insertExpiredSession(t, repo, userID, "token")
+
+		if _, err := repo.GetSession("token", opts()); !errors.Is(err, store.NotFoundError{}) {
+			t.Errorf("GetSession() of expired session error = %v, want NotFoundError", err)
+		}
+
+		sessions, err := repo.ListUserSessions(userID, opts())
+		if err != nil {
+			t.Fatalf("ListUserSessions() error = %v", err)
+		}
+
+		if len(sessions) != 0 {
+			t.Errorf("ListUserSessions() returned %d sessions, want expired session to be excluded", len(sessions))
+		}
+	})
+
+	t.Run("Touch", func(t *testing.T) {
+		repo := newRepo(t)
+
+		userID := insertUser(t, repo, "john.doe@example.com")
+		id := insertSession(t, repo, userID, "token")
+
+		lastSeenAt := time.Now().UTC().Add(time.Minute).Truncate(time.Second)
+		expiresAt := lastSeenAt.Add(2 * time.Hour)
+		if err := repo.TouchSession(id, lastSeenAt, expiresAt, opts()); err != nil {
+			t.Fatalf("TouchSession() error = %v", err)
+		}
+
+		session, err := repo.GetSession("token", opts())
+		if err != nil {
+			t.Fatalf("GetSession() error = %v", err)
+		}
+
+		if !session.LastSeenAt.Equal(lastSeenAt) || !session.ExpiresAt.Equal(expiresAt) {
+			t.Errorf(
+				"GetSession() after touch = last seen %v, expires %v; want %v, %v",
+				session.LastSeenAt, session.ExpiresAt, lastSeenAt, expiresAt,
+			)
+		}
+	})
+
+	t.Run("Reauthenticate", func(t *testing.T) {
+		repo := newRepo(t)
+
+		userID := insertUser(t, repo, "john.doe@example.com")
+		id := insertSession(t, repo, userID, "token")
+
+		authenticatedAt := time.Now().UTC().Add(time.Minute).Truncate(time.Second)
+		if err := repo.ReauthenticateSession(id, authenticatedAt, opts()); err != nil {
+			t.Fatalf("ReauthenticateSession() error = %v", err)
+		}
+
+		session, err := repo.GetSession("token", opts())
+		if err != nil {
+			t.Fatalf("GetSession() error = %v", err)
+		}
+
+		if !session.AuthenticatedAt.Equal(authenticatedAt) {
+			t.Errorf("GetSession() authenticated at = %v, want %v", session.AuthenticatedAt, authenticatedAt)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		repo := newRepo(t)
+
+		userID := insertUser(t, repo, "john.doe@example.com")
+		insertSession(t, repo, userID, "token")
+
+		if err := repo.DeleteSession("token", opts()); err != nil {
+			t.Fatalf("DeleteSession() error = %v", err)
+		}
+
+		if _, err := repo.GetSession("token", opts()); !errors.Is(err, store.NotFoundError{}) {
+			t.Errorf("GetSession() of deleted session error = %v, want NotFoundError", err)
+		}
+	})
+
+	t.Run("List user sessions", func(t *testing.T) {
+		repo := newRepo(t)
+
+		userID := insertUser(t, repo, "john.doe@example.com")
+		otherID := insertUser(t, repo, "jane.doe@example.com")
+		first := insertSession(t, repo, userID, "first")
+		second := insertSession(t, repo, userID, "second")
+		insertSession(t, repo, otherID, "other")
+
+		sessions, err := repo.ListUserSessions(userID, opts())
+		if err != nil {
+			t.Fatalf("ListUserSessions() error = %v", err)
+		}
+
+		ids := map[uuid.UUID]bool{}
+		for _, session := range sessions {
+			ids[session.ID] = true
+		}
+
+		if len(sessions) != 2 || !ids[first] || !ids[second] {
+			t.Errorf("ListUserSessions() = %+v, want sessions %s and %s", sessions, first, second)
+		}
+	})
+
+	t.Run("Delete user session", func(t *testing.T) {
+		repo := newRepo(t)
+
+		userID := insertUser(t, repo, "john.doe@example.com")
+		otherID := insertUser(t, repo, "jane.doe@example.com")
+		id := insertSession(t, repo, userID, "token")
+
+		if err := repo.DeleteUserSession(otherID, id, opts()); !errors.Is(err, store.NotFoundError{}) {
+			t.Errorf("DeleteUserSession() of another user's session error = %v, want NotFoundError", err)
+		}
+
+		if err := repo.DeleteUserSession(userID, id, opts()); err != nil {
+			t.Fatalf("DeleteUserSession() error = %v", err)
+		}
+
+		if _, err := repo.GetSession("token", opts()); !errors.Is(err, store.NotFoundError{}) {
+			t.Errorf("GetSession() of deleted session error = %v, want NotFoundError", err)
+		}
+	})
+
+	t.Run("Delete user sessions", func(t *testing.T) {
+		repo := newRepo(t)
+
+		userID := insertUser(t, repo, "john.doe@example.com")
+		otherID := insertUser(t, repo, "jane.doe@example.com")
+		keepID := insertSession(t, repo, userID, "keep")
+		insertSession(t, repo, userID, "drop")
+		insertSession(t, repo, otherID, "other")
+
+		if err := repo.DeleteUserSessions(userID, keepID, opts()); err != nil {
+			t.Fatalf("DeleteUserSessions() error = %v", err)
+		}
+
+		if _, err := repo.GetSession("keep", opts()); err != nil {
+			t.Errorf("GetSession() of kept session error = %v", err)
+		}
+
+		if _, err := repo.GetSession("drop", opts()); !errors.Is(err, store.NotFoundError{}) {
+			t.Errorf("GetSession() of deleted session error = %v, want NotFoundError", err)
+		}
+
+		if err := repo.DeleteUserSessions(userID, uuid.Nil, opts()); err != nil {
+			t.Fatalf("DeleteUserSessions() error = %v", err)
+		}
+
+		if _, err := repo.GetSession("keep", opts()); !errors.Is(err, store.NotFoundError{}) {
+			t.Errorf("GetSession() after deleting all sessions error = %v, want NotFoundError", err)
+		}
+
+		if _, err := repo.GetSession("other", opts()); err != nil {
+			t.Errorf("GetSession() of another user's session error = %v", err)
+		}
+	})
+
+	t.Run("Delete expired sessions", func(t *testing.T) {
+		repo := newRepo(t)
+
+		userID := insertUser(t, repo, "john.doe@example.com")
+		insertSession(t, repo, userID, "live")
+		insertExpiredSession(t, repo, userID, "first")
+		insertExpiredSession(t, repo, userID, "second")
+
+		count, err := repo.DeleteExpiredSessions(time.Now().UTC(), opts())
+		if err != nil {
+			t.Fatalf("DeleteExpiredSessions() error = %v", err)
+		}
+
+		if count != 2 {
+			t.Errorf("DeleteExpiredSessions() = %d, want 2", count)
+		}
+
+		if _, err = repo.GetSession("live", opts()); err != nil {
+			t.Errorf("GetSession() of live session error = %v", err)
+		}
+	})
+
+	t.Run("Count active sessions", func(t *testing.T) {
+		repo := newRepo(t)
+
+		userID := insertUser(t, repo, "john.doe@example.com")
+		insertSession(t, repo, userID, "first")
+		insertSession(t, repo, userID, "second")
+		insertExpiredSession(t, repo, userID, "expired")
+
+		count, err := repo.CountActiveSessions(time.Now().UTC(), opts())
+		if err != nil {
+			t.Fatalf("CountActiveSessions() error = %v", err)
+		}
+
+		if count != 2 {
+			t.Errorf("CountActiveSessions() = %d, want 2", count)
+		}
+	})
+}
+
+func insertSession(t *testing.T, repo store.Repository, userID uuid.UUID, tokenHash string) uuid.UUID {
+	t.Helper()
+
+	now := time.Now().UTC()
+	id, err := repo.InsertSession(store.NewSession{
+		TokenHash: tokenHash,
+		UserID:    userID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Hour),
+	}, opts())
+	if err != nil {
+		t.Fatalf("InsertSession() error = %v", err)
+	}
+
+	return id
+}
+
+func insertExpiredSession(t *testing.T, repo store.Repository, userID uuid.UUID, tokenHash string) uuid.UUID {
+	t.Helper()
+
+	createdAt := time.Now().UTC().Add(-2 * time.Hour)
+	id, err := repo.InsertSession(store.NewSession{
+		TokenHash: tokenHash,
+		UserID:    userID,
+		CreatedAt: createdAt,
+		ExpiresAt: createdAt.Add(time.Hour),
+	}, opts())
+	if err != nil {
+		t.Fatalf("InsertSession() error = %v", err)
+	}
+
+	return id
+}
@@ -0,0 +1,79 @@
+// Package storetest provides a conformance test suite for implementations of
+// store.Repository. Every backend should run the suite from its own tests so
+// that they all behave the same way:
+//
+//	func TestRepository(t *testing.T) {
+//		storetest.Run(t, func(t *testing.T) store.Repository {
+//			return newMigratedTestDB(t)
+//		})
+//	}
+package storetest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/ninth-realm/heimdall/store"
+)
+
+// Factory creates a new, empty, and fully migrated repository. It is called
+// once per test, and any cleanup should be registered with t.Cleanup.
+type Factory func(t *testing.T) store.Repository
+
+// Run executes the full conformance suite against repositories created by
+// newRepo.
+func Run(t *testing.T, newRepo Factory) {
+	t.Run("Users", func(t *testing.T) { testUsers(t, newRepo) })
+	t.Run("ListUsers", func(t *testing.T) { testListUsers(t, newRepo) })
+	t.Run("SearchUsers", func(t *testing.T) { testSearchUsers(t, newRepo) })
+	t.Run("Passwords", func(t *testing.T) { testPasswords(t, newRepo) })
+	t.Run("Clients", func(t *testing.T) { testClients(t, newRepo) })
+	t.Run("ListClients", func(t *testing.T) { testListClients(t, newRepo) })
+	t.Run("APIKeys", func(t *testing.T) { testAPIKeys(t, newRepo) })
+	t.Run("Sessions", func(t *testing.T) { testSessions(t, newRepo) })
+	t.Run("AuditEvents", func(t *testing.T) { testAuditEvents(t, newRepo) })
+	t.Run("Webhooks", func(t *testing.T) { testWebhooks(t, newRepo) })
+	t.Run("OutboxEvents", func(t *testing.T) { testOutboxEvents(t, newRepo) })
+	t.Run("Health", func(t *testing.T) { testHealth(t, newRepo) })
+	t.Run("UnitOfWork", func(t *testing.T) { testUnitOfWork(t, newRepo) })
+}
+
+// UUIDGenerator generates random v4 UUIDs. It can be used by backends that
+// need a generator when building their test repositories.
+type UUIDGenerator struct{}
+
+func (UUIDGenerator) GenerateUUID() uuid.UUID {
+	return uuid.Must(uuid.NewV4())
+}
+
+func opts() store.QueryOptions {
+	return store.QueryOptions{Ctx: context.Background()}
+}
+
+func insertUser(t *testing.T, repo store.Repository, email string) uuid.UUID {
+	t.Helper()
+
+	id, err := repo.InsertUser(store.NewUser{FirstName: "John", LastName: "Doe"}, opts())
+	if err != nil {
+		t.Fatalf("InsertUser() error = %v", err)
+	}
+
+	_, err = repo.InsertEmail(store.NewEmail{UserID: id, Email: email}, opts())
+	if err != nil {
+		t.Fatalf("InsertEmail() error = %v", err)
+	}
+
+	return id
+}
+
+func insertClient(t *testing.T, repo store.Repository, name string) uuid.UUID {
+	t.Helper()
+
+	id, err := repo.InsertClient(store.NewClient{Name: name, Enabled: true}, opts())
+	if err != nil {
+		t.Fatalf("InsertClient() error = %v", err)
+	}
+
+	return id
+}
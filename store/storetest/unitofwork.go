@@ -0,0 +1,57 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/jmoiron/sqlx"
+	"github.com/ninth-realm/heimdall/store"
+)
+
+func testUnitOfWork(t *testing.T, newRepo Factory) {
+	t.Run("Commit", func(t *testing.T) {
+		repo := newRepo(t)
+
+		id, err := store.RunUnitOfWork(context.Background(), repo, func(txn *sqlx.Tx) (uuid.UUID, error) {
+			return repo.InsertUser(
+				store.NewUser{FirstName: "John", LastName: "Doe"},
+				store.QueryOptions{Ctx: context.Background(), Txn: txn},
+			)
+		})
+		if err != nil {
+			t.Fatalf("RunUnitOfWork() error = %v", err)
+		}
+
+		if _, err = repo.GetUserById(id, opts()); err != nil {
+			t.Errorf("GetUserById() of committed user error = %v", err)
+		}
+	})
+
+	t.Run("Rollback", func(t *testing.T) {
+		repo := newRepo(t)
+
+		errAbort := errors.New("abort")
+		var id uuid.UUID
+		_, err := store.RunUnitOfWork(context.Background(), repo, func(txn *sqlx.Tx) (any, error) {
+			var err error
+			id, err = repo.InsertUser(
+				store.NewUser{FirstName: "John", LastName: "Doe"},
+				store.QueryOptions{Ctx: context.Background(), Txn: txn},
+			)
+			if err != nil {
+				return nil, err
+			}
+
+			return nil, errAbort
+		})
+		if !errors.Is(err, errAbort) {
+			t.Fatalf("RunUnitOfWork() error = %v, want %v", err, errAbort)
+		}
+
+		if _, err = repo.GetUserById(id, opts()); !errors.Is(err, store.NotFoundError{}) {
+			t.Errorf("GetUserById() of rolled back user error = %v, want NotFoundError", err)
+		}
+	})
+}
@@ -0,0 +1,391 @@
+package storetest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/ninth-realm/heimdall/store"
+)
+
+func testUsers(t *testing.T, newRepo Factory) {
+	t.Run("Insert and get", func(t *testing.T) {
+		repo := newRepo(t)
+
+		id := insertUser(t, repo, "john.doe@example.com")
+
+		user, err := repo.GetUserById(id, opts())
+		if err != nil {
+			t.Fatalf("GetUserById() error = %v", err)
+		}
+
+		if user.ID != id || user.FirstName != "John" || user.LastName != "Doe" {
+			t.Errorf("GetUserById() = %+v, want user %s named John Doe", user, id)
+		}
+
+		if user.CreatedAt.IsZero() || user.UpdatedAt.IsZero() {
+			t.Errorf("GetUserById() = %+v, want timestamps to be set", user)
+		}
+	})
+
+	t.Run("Get by email", func(t *testing.T) {
+		repo := newRepo(t)
+
+		id := insertUser(t, repo, "john.doe@example.com")
+		insertUser(t, repo, "jane.doe@example.com")
+
+		user, err := repo.GetUserByEmail("john.doe@example.com", opts())
+		if err != nil {
+			t.Fatalf("GetUserByEmail() error = %v", err)
+		}
+
+		if user.ID != id {
+			t.Errorf("GetUserByEmail() returned user %s, want %s", user.ID, id)
+		}
+	})
+
+	t.Run("List", func(t *testing.T) {
+		repo := newRepo(t)
+
+		insertUser(t, repo, "john.doe@example.com")
+		insertUser(t, repo, "jane.doe@example.com")
+
+		users, err := repo.ListUsers(store.UserListOptions{Sort: store.Sort{Field: store.SortByCreatedAt}}, opts())
+		if err != nil {
+			t.Fatalf("ListUsers() error = %v", err)
+		}
+
+		if len(users) != 2 {
+			t.Errorf("ListUsers() returned %d users, want 2", len(users))
+		}
+	})
+
+	t.Run("Save", func(t *testing.T) {
+		repo := newRepo(t)
+
+		id := insertUser(t, repo, "john.doe@example.com")
+
+		user, err := repo.GetUserById(id, opts())
+		if err != nil {
+			t.Fatalf("GetUserById() error = %v", err)
+		}
+
+		user.FirstName = "Jane"
+		user.LastName = "Smith"
+		if err = repo.SaveUser(user, opts()); err != nil {
+			t.Fatalf("SaveUser() error = %v", err)
+		}
+
+		saved, err := repo.GetUserById(id, opts())
+		if err != nil {
+			t.Fatalf("GetUserById() error = %v", err)
+		}
+
+		if saved.FirstName != "Jane" || saved.LastName != "Smith" {
+			t.Errorf("GetUserById() after save = %+v, want Jane Smith", saved)
+		}
+	})
+
+	t.Run("Save outdated version", func(t *testing.T) {
+		repo := newRepo(t)
+
+		id := insertUser(t, repo, "john.doe@example.com")
+
+		user, err := repo.GetUserById(id, opts())
+		if err != nil {
+			t.Fatalf("GetUserById() error = %v", err)
+		}
+
+		stale := user
+		user.FirstName = "Jane"
+		if err = repo.SaveUser(user, opts()); err != nil {
+			t.Fatalf("SaveUser() error = %v", err)
+		}
+
+		saved, err := repo.GetUserById(id, opts())
+		if err != nil {
+			t.Fatalf("GetUserById() error = %v", err)
+		}
+
+		if saved.Version != user.Version+1 {
+			t.Errorf("GetUserById() after save version = %d, want %d", saved.Version, user.Version+1)
+		}
+
+		stale.LastName = "Smith"
+		if err = repo.SaveUser(stale, opts()); !errors.Is(err, store.VersionMismatchError{}) {
+			t.Errorf("SaveUser() of outdated version error = %v, want VersionMismatchError", err)
+		}
+	})
+
+	t.Run("Missing user", func(t *testing.T) {
+		repo := newRepo(t)
+
+		if _, err := repo.GetUserById(uuid.Must(uuid.NewV4()), opts()); !errors.Is(err, store.NotFoundError{}) {
+			t.Errorf("GetUserById() of missing user error = %v, want NotFoundError", err)
+		}
+
+		if _, err := repo.GetUserByEmail("missing@example.com", opts()); !errors.Is(err, store.NotFoundError{}) {
+			t.Errorf("GetUserByEmail() of missing user error = %v, want NotFoundError", err)
+		}
+
+		if err := repo.SaveUser(store.User{ID: uuid.Must(uuid.NewV4())}, opts()); !errors.Is(err, store.NotFoundError{}) {
+			t.Errorf("SaveUser() of missing user error = %v, want NotFoundError", err)
+		}
+
+		if err := repo.DeleteUser(uuid.Must(uuid.NewV4()), opts()); !errors.Is(err, store.NotFoundError{}) {
+			t.Errorf("DeleteUser() of missing user error = %v, want NotFoundError", err)
+		}
+	})
+
+	t.Run("Email for missing user", func(t *testing.T) {
+		repo := newRepo(t)
+
+		_, err := repo.InsertEmail(store.NewEmail{UserID: uuid.Must(uuid.NewV4()), Email: "john.doe@example.com"}, opts())
+		if !errors.Is(err, store.ConstraintViolationError{}) {
+			t.Errorf("InsertEmail() for missing user error = %v, want ConstraintViolationError", err)
+		}
+	})
+
+	t.Run("Duplicate email", func(t *testing.T) {
+		repo := newRepo(t)
+
+		insertUser(t, repo, "john.doe@example.com")
+
+		id, err := repo.InsertUser(store.NewUser{FirstName: "Jane", LastName: "Doe"}, opts())
+		if err != nil {
+			t.Fatalf("InsertUser() error = %v", err)
+		}
+
+		_, err = repo.InsertEmail(store.NewEmail{UserID: id, Email: "john.doe@example.com"}, opts())
+		if !errors.Is(err, store.ConflictError{}) {
+			t.Errorf("InsertEmail() of duplicate email error = %v, want ConflictError", err)
+		}
+	})
+
+	t.Run("Soft delete", func(t *testing.T) {
+		repo := newRepo(t)
+
+		id := insertUser(t, repo, "john.doe@example.com")
+
+		if err := repo.DeleteUser(id, opts()); err != nil {
+			t.Fatalf("DeleteUser() error = %v", err)
+		}
+
+		if _, err := repo.GetUserById(id, opts()); !errors.Is(err, store.NotFoundError{}) {
+			t.Errorf("GetUserById() of deleted user error = %v, want NotFoundError", err)
+		}
+
+		if _, err := repo.GetUserByEmail("john.doe@example.com", opts()); !errors.Is(err, store.NotFoundError{}) {
+			t.Errorf("GetUserByEmail() of deleted user error = %v, want NotFoundError", err)
+		}
+
+		if err := repo.SaveUser(store.User{ID: id, FirstName: "Jane"}, opts()); !errors.Is(err, store.NotFoundError{}) {
+			t.Errorf("SaveUser() of deleted user error = %v, want NotFoundError", err)
+		}
+
+		if err := repo.DeleteUser(id, opts()); !errors.Is(err, store.NotFoundError{}) {
+			t.Errorf("DeleteUser() of deleted user error = %v, want NotFoundError", err)
+		}
+
+		users, err := repo.ListUsers(store.UserListOptions{Sort: store.Sort{Field: store.SortByCreatedAt}}, opts())
+		if err != nil {
+			t.Fatalf("ListUsers() error = %v", err)
+		}
+
+		if len(users) != 0 {
+			t.Errorf("ListUsers() returned %d users, want deleted user to be excluded", len(users))
+		}
+
+		if got := searchUserIDs(t, repo, "john"); len(got) != 0 {
+			t.Errorf("SearchUsers() = %v, want deleted user to be excluded", got)
+		}
+
+		// The email still belongs to the deleted user, so that it can be
+		// restored.
+		_, err = repo.InsertEmail(store.NewEmail{UserID: insertNamedUser(t, repo, "Jane", "Doe"), Email: "john.doe@example.com"}, opts())
+		if !errors.Is(err, store.ConflictError{}) {
+			t.Errorf("InsertEmail() of deleted user's email error = %v, want ConflictError", err)
+		}
+	})
+
+	t.Run("Restore", func(t *testing.T) {
+		repo := newRepo(t)
+
+		id := insertUser(t, repo, "john.doe@example.com")
+
+		if err := repo.RestoreUser(id, opts()); !errors.Is(err, store.NotFoundError{}) {
+			t.Errorf("RestoreUser() of user that isn't deleted error = %v, want NotFoundError", err)
+		}
+
+		if err := repo.DeleteUser(id, opts()); err != nil {
+			t.Fatalf("DeleteUser() error = %v", err)
+		}
+
+		if err := repo.RestoreUser(id, opts()); err != nil {
+			t.Fatalf("RestoreUser() error = %v", err)
+		}
+
+		user, err := repo.GetUserByEmail("john.doe@example.com", opts())
+		if err != nil {
+			t.Fatalf("GetUserByEmail() of restored user error = %v", err)
+		}
+
+		if user.ID != id {
+			t.Errorf("GetUserByEmail() = %+v, want restored user %s", user, id)
+		}
+
+		if err := repo.RestoreUser(uuid.Must(uuid.NewV4()), opts()); !errors.Is(err, store.NotFoundError{}) {
+			t.Errorf("RestoreUser() of missing user error = %v, want NotFoundError", err)
+		}
+	})
+
+	t.Run("Purge cascades", func(t *testing.T) {
+		repo := newRepo(t)
+
+		id := insertUser(t, repo, "john.doe@example.com")
+		_, err := repo.InsertPassword(store.NewPassword{UserID: id, Hash: "hash"}, opts())
+		if err != nil {
+			t.Fatalf("InsertPassword() error = %v", err)
+		}
+		insertSession(t, repo, id, "token")
+		keepID := insertUser(t, repo, "jane.doe@example.com")
+		liveID := insertUser(t, repo, "ada@example.com")
+
+		for _, deleteID := range []uuid.UUID{id, keepID} {
+			if err = repo.DeleteUser(deleteID, opts()); err != nil {
+				t.Fatalf("DeleteUser() error = %v", err)
+			}
+		}
+
+		// Nothing was deleted before an hour ago.
+		n, err := repo.PurgeDeletedUsers(time.Now().UTC().Add(-time.Hour), opts())
+		if err != nil {
+			t.Fatalf("PurgeDeletedUsers() error = %v", err)
+		}
+
+		if n != 0 {
+			t.Errorf("PurgeDeletedUsers() = %d, want 0", n)
+		}
+
+		if err = repo.RestoreUser(keepID, opts()); err != nil {
+			t.Fatalf("RestoreUser() error = %v", err)
+		}
+
+		n, err = repo.PurgeDeletedUsers(time.Now().UTC().Add(time.Hour), opts())
+		if err != nil {
+			t.Fatalf("PurgeDeletedUsers() error = %v", err)
+		}
+
+		if n != 1 {
+			t.Errorf("PurgeDeletedUsers() = %d, want 1", n)
+		}
+
+		if err = repo.RestoreUser(id, opts()); !errors.Is(err, store.NotFoundError{}) {
+			t.Errorf("RestoreUser() of purged user error = %v, want NotFoundError", err)
+		}
+
+		if _, err = repo.GetUserPasswordHash(id, opts()); !errors.Is(err, store.NotFoundError{}) {
+			t.Errorf("GetUserPasswordHash() of purged user error = %v, want NotFoundError", err)
+		}
+
+		if _, err = repo.GetSession("token", opts()); !errors.Is(err, store.NotFoundError{}) {
+			t.Errorf("GetSession() of purged user error = %v, want NotFoundError", err)
+		}
+
+		for _, liveID := range []uuid.UUID{keepID, liveID} {
+			if _, err = repo.GetUserById(liveID, opts()); err != nil {
+				t.Errorf("GetUserById() of user that wasn't purged error = %v", err)
+			}
+		}
+
+		// The email must have been removed as well for it to be reused.
+		insertUser(t, repo, "john.doe@example.com")
+	})
+}
+
+func testPasswords(t *testing.T, newRepo Factory) {
+	t.Run("Insert and get", func(t *testing.T) {
+		repo := newRepo(t)
+
+		id := insertUser(t, repo, "john.doe@example.com")
+		_, err := repo.InsertPassword(store.NewPassword{UserID: id, Hash: "hash"}, opts())
+		if err != nil {
+			t.Fatalf("InsertPassword() error = %v", err)
+		}
+
+		hash, err := repo.GetUserPasswordHash(id, opts())
+		if err != nil {
+			t.Fatalf("GetUserPasswordHash() error = %v", err)
+		}
+
+		if hash != "hash" {
+			t.Errorf("GetUserPasswordHash() = %q, want %q", hash, "hash")
+		}
+	})
+
+	t.Run("Save replaces existing hash", func(t *testing.T) {
+		repo := newRepo(t)
+
+		id := insertUser(t, repo, "john.doe@example.com")
+		_, err := repo.InsertPassword(store.NewPassword{UserID: id, Hash: "old"}, opts())
+		if err != nil {
+			t.Fatalf("InsertPassword() error = %v", err)
+		}
+
+		if err = repo.SavePassword(store.NewPassword{UserID: id, Hash: "new"}, opts()); err != nil {
+			t.Fatalf("SavePassword() error = %v", err)
+		}
+
+		hash, err := repo.GetUserPasswordHash(id, opts())
+		if err != nil {
+			t.Fatalf("GetUserPasswordHash() error = %v", err)
+		}
+
+		if hash != "new" {
+			t.Errorf("GetUserPasswordHash() = %q, want %q", hash, "new")
+		}
+	})
+
+	t.Run("Save inserts missing hash", func(t *testing.T) {
+		repo := newRepo(t)
+
+		id := insertUser(t, repo, "john.doe@example.com")
+		if err := repo.SavePassword(store.NewPassword{UserID: id, Hash: "new"}, opts()); err != nil {
+			t.Fatalf("SavePassword() error = %v", err)
+		}
+
+		hash, err := repo.GetUserPasswordHash(id, opts())
+		if err != nil {
+			t.Fatalf("GetUserPasswordHash() error = %v", err)
+		}
+
+		if hash != "new" {
+			t.Errorf("GetUserPasswordHash() = %q, want %q", hash, "new")
+		}
+	})
+
+	t.Run("Only one password per user", func(t *testing.T) {
+		repo := newRepo(t)
+
+		id := insertUser(t, repo, "john.doe@example.com")
+		_, err := repo.InsertPassword(store.NewPassword{UserID: id, Hash: "hash"}, opts())
+		if err != nil {
+			t.Fatalf("InsertPassword() error = %v", err)
+		}
+
+		_, err = repo.InsertPassword(store.NewPassword{UserID: id, Hash: "hash"}, opts())
+		if !errors.Is(err, store.ConflictError{}) {
+			t.Errorf("InsertPassword() of second password error = %v, want ConflictError", err)
+		}
+	})
+
+	t.Run("Missing password", func(t *testing.T) {
+		repo := newRepo(t)
+
+		id := insertUser(t, repo, "john.doe@example.com")
+		if _, err := repo.GetUserPasswordHash(id, opts()); !errors.Is(err, store.NotFoundError{}) {
+			t.Errorf("GetUserPasswordHash() of user without password error = %v, want NotFoundError", err)
+		}
+	})
+}
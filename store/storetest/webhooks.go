@@ -0,0 +1,290 @@
+package storetest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/google/go-cmp/cmp"
+	"github.com/ninth-realm/heimdall/store"
+)
+
+func testWebhooks(t *testing.T, newRepo Factory) {
+	t.Run("Insert and get", func(t *testing.T) {
+		repo := newRepo(t)
+
+		id := insertWebhook(t, repo, "https://example.com/hook", "user.created", "user.deleted")
+
+		webhook, err := repo.GetWebhook(id, opts())
+		if err != nil {
+			t.Fatalf("GetWebhook() error = %v", err)
+		}
+
+		// Timestamps are set by the database.
+		webhook.CreatedAt, webhook.UpdatedAt = time.Time{}, time.Time{}
+
+		want := store.Webhook{
+			ID:         id,
+			URL:        "https://example.com/hook",
+			EventTypes: store.StringList{"user.created", "user.deleted"},
+			Secret:     "secret",
+			Enabled:    true,
+			Version:    1,
+		}
+		if diff := cmp.Diff(want, webhook); diff != "" {
+			t.Errorf("GetWebhook() mismatch (-want +got):\n%s", diff)
+		}
+
+		webhooks, err := repo.ListWebhooks(opts())
+		if err != nil {
+			t.Fatalf("ListWebhooks() error = %v", err)
+		}
+
+		if len(webhooks) != 1 || webhooks[0].ID != id {
+			t.Errorf("ListWebhooks() = %v, want only %s", webhooks, id)
+		}
+	})
+
+	t.Run("Save checks the version", func(t *testing.T) {
+		repo := newRepo(t)
+
+		id := insertWebhook(t, repo, "https://example.com/hook", "user.created")
+		webhook, err := repo.GetWebhook(id, opts())
+		if err != nil {
+			t.Fatalf("GetWebhook() error = %v", err)
+		}
+
+		webhook.Enabled = false
+		webhook.EventTypes = store.StringList{"user.updated"}
+		if err = repo.SaveWebhook(webhook, opts()); err != nil {
+			t.Fatalf("SaveWebhook() error = %v", err)
+		}
+
+		saved, err := repo.GetWebhook(id, opts())
+		if err != nil {
+			t.Fatalf("GetWebhook() error = %v", err)
+		}
+
+		if saved.Enabled || saved.Version != 2 || !cmp.Equal(saved.EventTypes, store.StringList{"user.updated"}) {
+			t.Errorf("GetWebhook() after save = %+v", saved)
+		}
+
+		err = repo.SaveWebhook(webhook, opts())
+		if !errors.Is(err, store.VersionMismatchError{}) {
+			t.Errorf("SaveWebhook() of outdated webhook error = %v, want VersionMismatchError", err)
+		}
+
+		webhook.ID = uuid.Must(uuid.NewV4())
+		err = repo.SaveWebhook(webhook, opts())
+		if !errors.Is(err, store.NotFoundError{}) {
+			t.Errorf("SaveWebhook() of missing webhook error = %v, want NotFoundError", err)
+		}
+	})
+
+	t.Run("Delete removes deliveries", func(t *testing.T) {
+		repo := newRepo(t)
+
+		id := insertWebhook(t, repo, "https://example.com/hook", "user.created")
+		deliveryID := insertWebhookDelivery(t, repo, id)
+
+		if err := repo.DeleteWebhook(id, opts()); err != nil {
+			t.Fatalf("DeleteWebhook() error = %v", err)
+		}
+
+		if _, err := repo.GetWebhook(id, opts()); !errors.Is(err, store.NotFoundError{}) {
+			t.Errorf("GetWebhook() after delete error = %v, want NotFoundError", err)
+		}
+
+		if _, err := repo.GetWebhookDelivery(id, deliveryID, opts()); !errors.Is(err, store.NotFoundError{}) {
+			t.Errorf("GetWebhookDelivery() after delete error = %v, want NotFoundError", err)
+		}
+
+		if err := repo.DeleteWebhook(id, opts()); !errors.Is(err, store.NotFoundError{}) {
+			t.Errorf("DeleteWebhook() of missing webhook error = %v, want NotFoundError", err)
+		}
+	})
+
+	t.Run("Deliveries", func(t *testing.T) {
+		repo := newRepo(t)
+
+		id := insertWebhook(t, repo, "https://example.com/hook", "user.created")
+		deliveryID := insertWebhookDelivery(t, repo, id)
+
+		delivery, err := repo.GetWebhookDelivery(id, deliveryID, opts())
+		if err != nil {
+			t.Fatalf("GetWebhookDelivery() error = %v", err)
+		}
+
+		if delivery.Status != store.DeliveryPending || delivery.Attempts != 0 || delivery.LastAttemptAt != nil {
+			t.Errorf("GetWebhookDelivery() of new delivery = %+v", delivery)
+		}
+
+		if string(delivery.Payload) != `{"id":1}` {
+			t.Errorf("GetWebhookDelivery() payload = %s, want {\"id\":1}", delivery.Payload)
+		}
+
+		otherID := insertWebhook(t, repo, "https://example.com/other", "user.created")
+		if _, err := repo.GetWebhookDelivery(otherID, deliveryID, opts()); !errors.Is(err, store.NotFoundError{}) {
+			t.Errorf("GetWebhookDelivery() for another webhook error = %v, want NotFoundError", err)
+		}
+
+		deliveries, err := repo.ListWebhookDeliveries(id, store.WebhookDeliveryListOptions{
+			Sort: store.Sort{Field: store.SortByCreatedAt},
+		}, opts())
+		if err != nil {
+			t.Fatalf("ListWebhookDeliveries() error = %v", err)
+		}
+
+		if len(deliveries) != 1 || deliveries[0].ID != deliveryID {
+			t.Errorf("ListWebhookDeliveries() = %v, want only %s", deliveries, deliveryID)
+		}
+
+		deliveries, err = repo.ListWebhookDeliveries(otherID, store.WebhookDeliveryListOptions{
+			Sort: store.Sort{Field: store.SortByCreatedAt},
+		}, opts())
+		if err != nil {
+			t.Fatalf("ListWebhookDeliveries() error = %v", err)
+		}
+
+		if len(deliveries) != 0 {
+			t.Errorf("ListWebhookDeliveries() of another webhook = %v, want none", deliveries)
+		}
+	})
+
+	t.Run("Claim and finish attempts", func(t *testing.T) {
+		repo := newRepo(t)
+
+		id := insertWebhook(t, repo, "https://example.com/hook", "user.created")
+		deliveryID := insertWebhookDelivery(t, repo, id)
+		now := time.Now().UTC()
+
+		due := listDueWebhookDeliveries(t, repo, now.Add(time.Minute))
+		if len(due) != 1 || due[0].ID != deliveryID {
+			t.Fatalf("ListDueWebhookDeliveries() = %v, want only %s", due, deliveryID)
+		}
+
+		if err := repo.ClaimWebhookDelivery(deliveryID, 0, now.Add(time.Hour), opts()); err != nil {
+			t.Fatalf("ClaimWebhookDelivery() error = %v", err)
+		}
+
+		err := repo.ClaimWebhookDelivery(deliveryID, 0, now.Add(time.Hour), opts())
+		if !errors.Is(err, store.VersionMismatchError{}) {
+			t.Errorf("ClaimWebhookDelivery() twice error = %v, want VersionMismatchError", err)
+		}
+
+		if due := listDueWebhookDeliveries(t, repo, now.Add(time.Minute)); len(due) != 0 {
+			t.Errorf("ListDueWebhookDeliveries() of claimed delivery = %v, want none", due)
+		}
+
+		statusCode, msg := 500, "receiver responded with 500 Internal Server Error"
+		err = repo.FinishWebhookDeliveryAttempt(store.NewWebhookDeliveryAttempt{
+			DeliveryID:  deliveryID,
+			Number:      1,
+			AttemptedAt: now,
+			StatusCode:  &statusCode,
+			Error:       &msg,
+		}, store.DeliveryPending, now.Add(30*time.Minute), opts())
+		if err != nil {
+			t.Fatalf("FinishWebhookDeliveryAttempt() error = %v", err)
+		}
+
+		if due := listDueWebhookDeliveries(t, repo, now.Add(time.Minute)); len(due) != 0 {
+			t.Errorf("ListDueWebhookDeliveries() before retry = %v, want none", due)
+		}
+
+		due = listDueWebhookDeliveries(t, repo, now.Add(time.Hour))
+		if len(due) != 1 || due[0].Attempts != 1 {
+			t.Fatalf("ListDueWebhookDeliveries() at retry = %v, want one delivery with 1 attempt", due)
+		}
+
+		if err := repo.ClaimWebhookDelivery(deliveryID, 1, now.Add(time.Hour), opts()); err != nil {
+			t.Fatalf("ClaimWebhookDelivery() of retry error = %v", err)
+		}
+
+		statusCode = 204
+		err = repo.FinishWebhookDeliveryAttempt(store.NewWebhookDeliveryAttempt{
+			DeliveryID:  deliveryID,
+			Number:      2,
+			AttemptedAt: now.Add(time.Hour),
+			StatusCode:  &statusCode,
+		}, store.DeliverySucceeded, now.Add(time.Hour), opts())
+		if err != nil {
+			t.Fatalf("FinishWebhookDeliveryAttempt() error = %v", err)
+		}
+
+		delivery, err := repo.GetWebhookDelivery(id, deliveryID, opts())
+		if err != nil {
+			t.Fatalf("GetWebhookDelivery() error = %v", err)
+		}
+
+		if delivery.Status != store.DeliverySucceeded || delivery.Attempts != 2 || delivery.LastAttemptAt == nil {
+			t.Errorf("GetWebhookDelivery() after success = %+v", delivery)
+		}
+
+		if due := listDueWebhookDeliveries(t, repo, now.Add(24*time.Hour)); len(due) != 0 {
+			t.Errorf("ListDueWebhookDeliveries() of succeeded delivery = %v, want none", due)
+		}
+
+		attempts, err := repo.ListWebhookDeliveryAttempts(deliveryID, opts())
+		if err != nil {
+			t.Fatalf("ListWebhookDeliveryAttempts() error = %v", err)
+		}
+
+		if len(attempts) != 2 {
+			t.Fatalf("ListWebhookDeliveryAttempts() returned %d attempts, want 2", len(attempts))
+		}
+
+		first, second := attempts[0], attempts[1]
+		if first.Number != 1 || first.StatusCode == nil || *first.StatusCode != 500 || first.Error == nil || *first.Error != msg {
+			t.Errorf("ListWebhookDeliveryAttempts() first = %+v", first)
+		}
+
+		if second.Number != 2 || second.StatusCode == nil || *second.StatusCode != 204 || second.Error != nil {
+			t.Errorf("ListWebhookDeliveryAttempts() second = %+v", second)
+		}
+	})
+}
+
+func insertWebhook(t *testing.T, repo store.Repository, url string, eventTypes ...string) uuid.UUID {
+	t.Helper()
+
+	id, err := repo.InsertWebhook(store.NewWebhook{
+		URL:        url,
+		EventTypes: eventTypes,
+		Secret:     "secret",
+		Enabled:    true,
+	}, opts())
+	if err != nil {
+		t.Fatalf("InsertWebhook() error = %v", err)
+	}
+
+	return id
+}
+
+func insertWebhookDelivery(t *testing.T, repo store.Repository, webhookID uuid.UUID) uuid.UUID {
+	t.Helper()
+
+	id, err := repo.InsertWebhookDelivery(store.NewWebhookDelivery{
+		WebhookID: webhookID,
+		EventID:   uuid.Must(uuid.NewV4()),
+		EventType: "user.created",
+		Payload:   store.RawJSON(`{"id":1}`),
+	}, opts())
+	if err != nil {
+		t.Fatalf("InsertWebhookDelivery() error = %v", err)
+	}
+
+	return id
+}
+
+func listDueWebhookDeliveries(t *testing.T, repo store.Repository, now time.Time) []store.WebhookDelivery {
+	t.Helper()
+
+	deliveries, err := repo.ListDueWebhookDeliveries(now, 10, opts())
+	if err != nil {
+		t.Fatalf("ListDueWebhookDeliveries() error = %v", err)
+	}
+
+	return deliveries
+}
@@ -1,8 +1,10 @@
 package store
 
 import (
+	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/gofrs/uuid/v5"
 )
@@ -13,6 +15,9 @@ type User struct {
 	LastName  string    `json:"lastName" db:"last_name"`
 	CreatedAt time.Time `json:"createdAt" db:"created_at"`
 	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
+	// Version is incremented each time the user is saved. Saving an outdated
+	// copy of a user fails with a VersionMismatchError.
+	Version int64 `json:"-" db:"version"`
 }
 
 type NewUser struct {
@@ -39,11 +44,107 @@ func (p UserPatch) ApplyTo(user User) User {
 	return user
 }
 
+// UserFilter narrows down the users returned by a list query. Unset fields
+// don't filter.
+type UserFilter struct {
+	Email *string
+	// NamePrefix matches the start of either the first or last name,
+	// ignoring case.
+	NamePrefix    *string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+}
+
+type UserListOptions struct {
+	Filter     UserFilter
+	Sort       Sort
+	Pagination Pagination
+}
+
+// Validate checks that the users can be sorted as requested, and that the
+// cursor belongs to that sort.
+func (o UserListOptions) Validate() error {
+	err := o.Sort.validate(SortByCreatedAt, SortByFirstName, SortByLastName)
+	if err != nil {
+		return err
+	}
+
+	return o.Pagination.validate(o.Sort)
+}
+
+// UserCursor returns the cursor that continues a list of users, sorted by sort,
+// after user.
+func UserCursor(sort Sort, user User) Cursor {
+	c := Cursor{Sort: sort.String(), ID: user.ID}
+
+	switch sort.Field {
+	case SortByCreatedAt:
+		c.Value = user.CreatedAt.UTC().Format(time.RFC3339Nano)
+	case SortByFirstName:
+		c.Value = user.FirstName
+	case SortByLastName:
+		c.Value = user.LastName
+	}
+
+	return c
+}
+
+// UserSearchResult is a user found by a search, along with the email address
+// that best matched it.
+type UserSearchResult struct {
+	User
+	Email string `json:"email" db:"email"`
+	// Score is how well the user matched. Higher is better, but scores are
+	// only comparable between results of the same search.
+	Score float64 `json:"-" db:"score"`
+}
+
+// UserSearchSort is the order of search results: best match first.
+var UserSearchSort = Sort{Field: SortByScore, Descending: true}
+
+type UserSearchOptions struct {
+	Query      string
+	Pagination Pagination
+}
+
+// Validate checks that the cursor belongs to a search.
+func (o UserSearchOptions) Validate() error {
+	return o.Pagination.validate(UserSearchSort)
+}
+
+// UserSearchCursor returns the cursor that continues a search after result.
+func UserSearchCursor(result UserSearchResult) Cursor {
+	return Cursor{
+		Sort:  UserSearchSort.String(),
+		Value: strconv.FormatFloat(result.Score, 'g', -1, 64),
+		ID:    result.ID,
+	}
+}
+
+// SearchTerms splits a search query into the words to look for. Anything other
+// than a letter or number separates words, as it does when the backends index
+// names and email addresses. Each term matches the start of a word.
+func SearchTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
 type UserRepository interface {
-	ListUsers(opts QueryOptions) ([]User, error)
+	ListUsers(list UserListOptions, opts QueryOptions) ([]User, error)
+	// SearchUsers returns the users with a name or email address matching
+	// every term in the query, best match first.
+	SearchUsers(search UserSearchOptions, opts QueryOptions) ([]UserSearchResult, error)
 	GetUserById(id uuid.UUID, opts QueryOptions) (User, error)
 	GetUserByEmail(email string, opts QueryOptions) (User, error)
 	InsertUser(user NewUser, opts QueryOptions) (uuid.UUID, error)
 	SaveUser(user User, opts QueryOptions) error
+	// DeleteUser soft deletes a user. Deleted users are treated as missing,
+	// but keep their emails and password until they're purged.
 	DeleteUser(id uuid.UUID, opts QueryOptions) error
+	// RestoreUser undoes the soft deletion of a user that hasn't been purged.
+	RestoreUser(id uuid.UUID, opts QueryOptions) error
+	// PurgeDeletedUsers permanently removes users that were soft deleted before
+	// the given time, and returns how many were removed.
+	PurgeDeletedUsers(before time.Time, opts QueryOptions) (int64, error)
 }
@@ -0,0 +1,243 @@
+package store
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// StringList is a list of strings stored in a single column. Items are joined
+// with commas, so they must not contain any.
+type StringList []string
+
+func (l StringList) Value() (driver.Value, error) {
+	return strings.Join(l, ","), nil
+}
+
+func (l *StringList) Scan(src any) error {
+	var s string
+	switch v := src.(type) {
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	case nil:
+	default:
+		return errors.New("unsupported type for string list")
+	}
+
+	*l = StringList{}
+	if s != "" {
+		*l = strings.Split(s, ",")
+	}
+
+	return nil
+}
+
+// RawJSON is an encoded JSON value stored as text. It's written and read as is
+// when encoded to or decoded from JSON.
+type RawJSON []byte
+
+func (j RawJSON) Value() (driver.Value, error) {
+	return string(j), nil
+}
+
+func (j *RawJSON) Scan(src any) error {
+	switch v := src.(type) {
+	case string:
+		*j = RawJSON(v)
+	case []byte:
+		*j = append(RawJSON{}, v...)
+	default:
+		return errors.New("unsupported type for raw JSON")
+	}
+
+	return nil
+}
+
+func (j RawJSON) MarshalJSON() ([]byte, error) {
+	if len(j) == 0 {
+		return []byte("null"), nil
+	}
+
+	return j, nil
+}
+
+func (j *RawJSON) UnmarshalJSON(b []byte) error {
+	*j = append(RawJSON{}, b...)
+	return nil
+}
+
+// Webhook is a subscription to events. Each event of a subscribed type is
+// delivered to the webhook's URL, signed with its secret.
+type Webhook struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	URL        string     `json:"url" db:"url"`
+	EventTypes StringList `json:"eventTypes" db:"event_types"`
+	Secret     string     `json:"-" db:"secret"`
+	Enabled    bool       `json:"enabled" db:"enabled"`
+	CreatedAt  time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updatedAt" db:"updated_at"`
+	// Version is incremented each time the webhook is saved. Saving an
+	// outdated copy of a webhook fails with a VersionMismatchError.
+	Version int64 `json:"-" db:"version"`
+}
+
+// Subscribes reports whether events of the given type are delivered to the
+// webhook.
+func (w Webhook) Subscribes(eventType string) bool {
+	if !w.Enabled {
+		return false
+	}
+
+	for _, t := range w.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+
+	return false
+}
+
+type NewWebhook struct {
+	URL        string
+	EventTypes []string
+	Secret     string
+	Enabled    bool
+}
+
+type WebhookPatch struct {
+	URL        *string
+	EventTypes *[]string
+	Secret     *string
+	Enabled    *bool
+}
+
+func (p WebhookPatch) ApplyTo(webhook Webhook) Webhook {
+	if p.URL != nil {
+		webhook.URL = strings.TrimSpace(*p.URL)
+	}
+
+	if p.EventTypes != nil {
+		webhook.EventTypes = *p.EventTypes
+	}
+
+	if p.Secret != nil {
+		webhook.Secret = *p.Secret
+	}
+
+	if p.Enabled != nil {
+		webhook.Enabled = *p.Enabled
+	}
+
+	return webhook
+}
+
+// Webhook delivery statuses.
+const (
+	// DeliveryPending deliveries are waiting for their first or next attempt.
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	// DeliveryFailed deliveries ran out of attempts and won't be retried.
+	DeliveryFailed = "failed"
+)
+
+// WebhookDelivery is a single event waiting to be, or that has been, delivered
+// to a webhook. Deliveries act as an outbox: they're written along with the
+// change that caused the event, and sent afterwards.
+type WebhookDelivery struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	WebhookID uuid.UUID `json:"webhookId" db:"webhook_id"`
+	EventID   uuid.UUID `json:"eventId" db:"event_id"`
+	EventType string    `json:"eventType" db:"event_type"`
+	Payload   RawJSON   `json:"payload" db:"payload"`
+	Status    string    `json:"status" db:"status"`
+	Attempts  int       `json:"attempts" db:"attempts"`
+	// NextAttemptAt is when a pending delivery is next due. It's meaningless
+	// once the delivery has succeeded or failed.
+	NextAttemptAt time.Time  `json:"nextAttemptAt" db:"next_attempt_at"`
+	LastAttemptAt *time.Time `json:"lastAttemptAt" db:"last_attempt_at"`
+	CreatedAt     time.Time  `json:"createdAt" db:"created_at"`
+}
+
+type NewWebhookDelivery struct {
+	WebhookID uuid.UUID
+	EventID   uuid.UUID
+	EventType string
+	Payload   RawJSON
+}
+
+// WebhookDeliveryAttempt records the outcome of one attempt at sending a
+// delivery.
+type WebhookDeliveryAttempt struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	DeliveryID uuid.UUID `json:"-" db:"delivery_id"`
+	// Number counts the delivery's attempts, starting at 1.
+	Number      int       `json:"number" db:"number"`
+	AttemptedAt time.Time `json:"attemptedAt" db:"attempted_at"`
+	// StatusCode is the receiver's response status. It's nil if no response
+	// was received.
+	StatusCode *int    `json:"statusCode" db:"status_code"`
+	Error      *string `json:"error" db:"error"`
+}
+
+type NewWebhookDeliveryAttempt struct {
+	DeliveryID  uuid.UUID
+	Number      int
+	AttemptedAt time.Time
+	StatusCode  *int
+	Error       *string
+}
+
+type WebhookDeliveryListOptions struct {
+	Sort       Sort
+	Pagination Pagination
+}
+
+// Validate checks that the deliveries can be sorted as requested, and that the
+// cursor belongs to that sort.
+func (o WebhookDeliveryListOptions) Validate() error {
+	if err := o.Sort.validate(SortByCreatedAt); err != nil {
+		return err
+	}
+
+	return o.Pagination.validate(o.Sort)
+}
+
+// WebhookDeliveryCursor returns the cursor that continues a list of
+// deliveries, sorted by sort, after delivery.
+func WebhookDeliveryCursor(sort Sort, delivery WebhookDelivery) Cursor {
+	return Cursor{
+		Sort:  sort.String(),
+		Value: delivery.CreatedAt.UTC().Format(time.RFC3339Nano),
+		ID:    delivery.ID,
+	}
+}
+
+type WebhookRepository interface {
+	ListWebhooks(opts QueryOptions) ([]Webhook, error)
+	GetWebhook(id uuid.UUID, opts QueryOptions) (Webhook, error)
+	InsertWebhook(webhook NewWebhook, opts QueryOptions) (uuid.UUID, error)
+	SaveWebhook(webhook Webhook, opts QueryOptions) error
+	// DeleteWebhook removes a webhook along with its deliveries.
+	DeleteWebhook(id uuid.UUID, opts QueryOptions) error
+
+	InsertWebhookDelivery(delivery NewWebhookDelivery, opts QueryOptions) (uuid.UUID, error)
+	GetWebhookDelivery(webhookID, deliveryID uuid.UUID, opts QueryOptions) (WebhookDelivery, error)
+	ListWebhookDeliveries(webhookID uuid.UUID, list WebhookDeliveryListOptions, opts QueryOptions) ([]WebhookDelivery, error)
+	// ListDueWebhookDeliveries returns up to limit pending deliveries that
+	// were due at the given time, longest overdue first.
+	ListDueWebhookDeliveries(now time.Time, limit int, opts QueryOptions) ([]WebhookDelivery, error)
+	// ClaimWebhookDelivery counts a new attempt at a pending delivery and
+	// holds it until the given time, so that it isn't attempted elsewhere in
+	// the meantime. It fails with a VersionMismatchError if the delivery has
+	// been attempted since it was read with the given number of attempts.
+	ClaimWebhookDelivery(id uuid.UUID, attempts int, until time.Time, opts QueryOptions) error
+	// FinishWebhookDeliveryAttempt records an attempt and moves the delivery
+	// to its new status. Pending deliveries are next due at nextAttemptAt.
+	FinishWebhookDeliveryAttempt(attempt NewWebhookDeliveryAttempt, status string, nextAttemptAt time.Time, opts QueryOptions) error
+	ListWebhookDeliveryAttempts(deliveryID uuid.UUID, opts QueryOptions) ([]WebhookDeliveryAttempt, error)
+}
@@ -0,0 +1,134 @@
+// Package tracing sets up OpenTelemetry tracing, so that the time spent on a
+// request can be broken down into its handler, services, password hashing and
+// database queries.
+//
+// Spans are started from the context passed down from the HTTP middleware, and
+// a trace started by the caller is continued using the W3C traceparent header.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ninth-realm/heimdall/store"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/ninth-realm/heimdall"
+
+// Exporters.
+const (
+	// ExporterNone disables tracing. Incoming trace context is still passed
+	// along, but no spans are recorded.
+	ExporterNone = ""
+	// ExporterOTLP sends spans to an OpenTelemetry collector over HTTP.
+	ExporterOTLP = "otlp"
+	// ExporterStdout writes spans to stdout, for local testing.
+	ExporterStdout = "stdout"
+)
+
+// Options control where spans are sent.
+type Options struct {
+	// Exporter is one of the Exporter constants.
+	Exporter string
+	// Endpoint is the host and port of the OTLP collector. When empty, the
+	// OTEL_EXPORTER_OTLP_ENDPOINT environment variable or the OTLP default of
+	// localhost:4318 is used.
+	Endpoint string
+	// Insecure sends spans to the OTLP collector over plain HTTP.
+	Insecure bool
+	// SampleRatio is the fraction of new traces that are recorded. Traces
+	// continued from a caller follow the caller's sampling decision.
+	SampleRatio float64
+}
+
+// Setup installs the global tracer provider and propagator. The returned
+// function flushes any buffered spans and should be called before exiting.
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch opts.Exporter {
+	case ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		var clientOpts []otlptracehttp.Option
+		if opts.Endpoint != "" {
+			clientOpts = append(clientOpts, otlptracehttp.WithEndpoint(opts.Endpoint))
+		}
+		if opts.Insecure {
+			clientOpts = append(clientOpts, otlptracehttp.WithInsecure())
+		}
+
+		exporter, err = otlptracehttp.New(ctx, clientOpts...)
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", opts.Exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(
+		resource.Default(),
+		resource.NewSchemaless(semconv.ServiceName("heimdall")),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Start starts a span as a child of any span in ctx. The span must be ended by
+// the caller.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
+
+// End marks the span as failed if err isn't nil, and then ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// ObserveQuery is a store.Observer that records each repository method as a
+// span. Missing rows and version mismatches are expected, e.g. when checking
+// whether something was deleted or retrying an update, so they don't mark the
+// span as failed.
+func ObserveQuery(ctx context.Context, method string) (context.Context, func(error)) {
+	ctx, span := Start(ctx, "store."+method, trace.WithSpanKind(trace.SpanKindClient))
+
+	return ctx, func(err error) {
+		if errors.Is(err, store.NotFoundError{}) || errors.Is(err, store.VersionMismatchError{}) {
+			err = nil
+		}
+
+		End(span, err)
+	}
+}
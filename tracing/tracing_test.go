@@ -0,0 +1,51 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ninth-realm/heimdall/store"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestObserveQuery(t *testing.T) {
+	t.Cleanup(func() { otel.SetTracerProvider(trace.NewNoopTracerProvider()) })
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus codes.Code
+	}{
+		{name: "Success", wantStatus: codes.Unset},
+		{name: "Not found", err: store.NotFoundError{ResourceType: "user"}, wantStatus: codes.Unset},
+		{name: "Version mismatch", err: store.VersionMismatchError{ResourceType: "user"}, wantStatus: codes.Unset},
+		{name: "Unexpected error", err: errors.New("connection refused"), wantStatus: codes.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+			_, done := ObserveQuery(context.Background(), "GetUserById")
+			done(tt.err)
+
+			spans := recorder.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("recorded %d spans, want 1", len(spans))
+			}
+
+			if got := spans[0].Status().Code; got != tt.wantStatus {
+				t.Errorf("span status = %v, want %v", got, tt.wantStatus)
+			}
+
+			if wantEvents := tt.wantStatus == codes.Error; (len(spans[0].Events()) > 0) != wantEvents {
+				t.Errorf("span events = %v, want an error recorded: %v", spans[0].Events(), wantEvents)
+			}
+		})
+	}
+}
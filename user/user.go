@@ -2,27 +2,84 @@ package user
 
 import (
 	"context"
+	"errors"
 	"strings"
 
 	"github.com/gofrs/uuid/v5"
 	"github.com/jmoiron/sqlx"
 	"github.com/ninth-realm/heimdall/crypto"
+	"github.com/ninth-realm/heimdall/event"
 	"github.com/ninth-realm/heimdall/store"
+	"github.com/ninth-realm/heimdall/tracing"
 )
 
+// ErrIncorrectPassword is returned when a user fails to confirm their current
+// password.
+var ErrIncorrectPassword = errors.New("incorrect password")
+
 type Service struct {
 	Repo store.Repository
 }
 
-func (s Service) ListUsers(ctx context.Context) ([]store.User, error) {
-	return s.Repo.ListUsers(store.QueryOptions{Ctx: ctx})
+// ListUsers returns a single page of users. An invalid sort or cursor results
+// in store.ErrInvalidSort or store.ErrInvalidCursor.
+func (s Service) ListUsers(ctx context.Context, list store.UserListOptions) (store.Page[store.User], error) {
+	ctx, span := tracing.Start(ctx, "user.Service.ListUsers")
+	defer span.End()
+
+	if err := list.Validate(); err != nil {
+		return store.Page[store.User]{}, err
+	}
+
+	// Fetch an extra user to find out whether there is another page.
+	limit := list.Pagination.Limit
+	if limit > 0 {
+		list.Pagination.Limit++
+	}
+
+	users, err := s.Repo.ListUsers(list, store.QueryOptions{Ctx: ctx})
+	if err != nil {
+		return store.Page[store.User]{}, err
+	}
+
+	return store.NewPage(users, limit, func(user store.User) store.Cursor {
+		return store.UserCursor(list.Sort, user)
+	}), nil
+}
+
+func (s Service) SearchUsers(ctx context.Context, search store.UserSearchOptions) (store.Page[store.UserSearchResult], error) {
+	ctx, span := tracing.Start(ctx, "user.Service.SearchUsers")
+	defer span.End()
+
+	if err := search.Validate(); err != nil {
+		return store.Page[store.UserSearchResult]{}, err
+	}
+
+	// Fetch an extra result to find out whether there is another page.
+	limit := search.Pagination.Limit
+	if limit > 0 {
+		search.Pagination.Limit++
+	}
+
+	results, err := s.Repo.SearchUsers(search, store.QueryOptions{Ctx: ctx})
+	if err != nil {
+		return store.Page[store.UserSearchResult]{}, err
+	}
+
+	return store.NewPage(results, limit, store.UserSearchCursor), nil
 }
 
 func (s Service) GetUser(ctx context.Context, id uuid.UUID) (store.User, error) {
+	ctx, span := tracing.Start(ctx, "user.Service.GetUser")
+	defer span.End()
+
 	return s.Repo.GetUserById(id, store.QueryOptions{Ctx: ctx})
 }
 
 func (s Service) CreateUser(ctx context.Context, user store.NewUser) (store.User, error) {
+	ctx, span := tracing.Start(ctx, "user.Service.CreateUser")
+	defer span.End()
+
 	user = cleanNewUser(user)
 	return store.RunUnitOfWork(ctx, s.Repo, func(txn *sqlx.Tx) (store.User, error) {
 		id, err := s.Repo.InsertUser(user, store.QueryOptions{Ctx: ctx, Txn: txn})
@@ -42,7 +99,9 @@ func (s Service) CreateUser(ctx context.Context, user store.NewUser) (store.User
 		}
 
 		if user.Password != nil {
+			_, hashSpan := tracing.Start(ctx, "crypto.GetPasswordHash")
 			hash, err := crypto.GetPasswordHash(*user.Password, crypto.DefaultParams)
+			hashSpan.End()
 			if err != nil {
 				return store.User{}, err
 			}
@@ -60,7 +119,21 @@ func (s Service) CreateUser(ctx context.Context, user store.NewUser) (store.User
 			}
 		}
 
-		return s.Repo.GetUserById(id, store.QueryOptions{Ctx: ctx, Txn: txn})
+		created, err := s.Repo.GetUserById(id, store.QueryOptions{Ctx: ctx, Txn: txn})
+		if err != nil {
+			return store.User{}, err
+		}
+
+		err = event.Emit(s.Repo, event.Event{
+			Type:   event.UserCreated,
+			Target: event.Target{Type: event.TargetUser, ID: id.String()},
+			Data:   event.UserData{User: created},
+		}, store.QueryOptions{Ctx: ctx, Txn: txn})
+		if err != nil {
+			return store.User{}, err
+		}
+
+		return created, nil
 	})
 }
 
@@ -71,22 +144,149 @@ func cleanNewUser(user store.NewUser) store.NewUser {
 	return user
 }
 
-func (s Service) UpdateUser(ctx context.Context, id uuid.UUID, patch store.UserPatch) (store.User, error) {
-	user, err := s.Repo.GetUserById(id, store.QueryOptions{Ctx: ctx})
-	if err != nil {
-		return store.User{}, err
-	}
+// UpdateUser applies the patch to version of the user. It fails with a
+// store.VersionMismatchError if the user has been changed since that version.
+func (s Service) UpdateUser(ctx context.Context, id uuid.UUID, version int64, patch store.UserPatch) (store.User, error) {
+	ctx, span := tracing.Start(ctx, "user.Service.UpdateUser")
+	defer span.End()
 
-	user = patch.ApplyTo(user)
+	return store.RunUnitOfWork(ctx, s.Repo, func(txn *sqlx.Tx) (store.User, error) {
+		opts := store.QueryOptions{Ctx: ctx, Txn: txn}
 
-	err = s.Repo.SaveUser(user, store.QueryOptions{Ctx: ctx})
-	if err != nil {
-		return store.User{}, err
-	}
+		user, err := s.Repo.GetUserById(id, opts)
+		if err != nil {
+			return store.User{}, err
+		}
 
-	return s.Repo.GetUserById(id, store.QueryOptions{Ctx: ctx})
+		if user.Version != version {
+			return store.User{}, store.VersionMismatchError{ResourceType: "user", ResourceID: id.String()}
+		}
+
+		if err = s.Repo.SaveUser(patch.ApplyTo(user), opts); err != nil {
+			return store.User{}, err
+		}
+
+		updated, err := s.Repo.GetUserById(id, opts)
+		if err != nil {
+			return store.User{}, err
+		}
+
+		err = event.Emit(s.Repo, event.Event{
+			Type:   event.UserUpdated,
+			Target: event.Target{Type: event.TargetUser, ID: id.String()},
+			Data:   event.UserData{User: updated},
+		}, opts)
+		if err != nil {
+			return store.User{}, err
+		}
+
+		return updated, nil
+	})
 }
 
+// DeleteUser soft deletes a user and ends all of their sessions. The user can
+// be restored until they're purged, but will have to log in again.
 func (s Service) DeleteUser(ctx context.Context, id uuid.UUID) error {
-	return s.Repo.DeleteUser(id, store.QueryOptions{Ctx: ctx})
+	ctx, span := tracing.Start(ctx, "user.Service.DeleteUser")
+	defer span.End()
+
+	_, err := store.RunUnitOfWork(ctx, s.Repo, func(txn *sqlx.Tx) (struct{}, error) {
+		opts := store.QueryOptions{Ctx: ctx, Txn: txn}
+
+		// Deleted users can't be fetched, so the user is read first for the
+		// event.
+		user, err := s.Repo.GetUserById(id, opts)
+		if err != nil {
+			return struct{}{}, err
+		}
+
+		if err := s.Repo.DeleteUser(id, opts); err != nil {
+			return struct{}{}, err
+		}
+
+		if err := s.Repo.DeleteUserSessions(id, uuid.Nil, opts); err != nil {
+			return struct{}{}, err
+		}
+
+		return struct{}{}, event.Emit(s.Repo, event.Event{
+			Type:   event.UserDeleted,
+			Target: event.Target{Type: event.TargetUser, ID: id.String()},
+			Data:   event.UserData{User: user},
+		}, opts)
+	})
+
+	return err
+}
+
+func (s Service) RestoreUser(ctx context.Context, id uuid.UUID) (store.User, error) {
+	ctx, span := tracing.Start(ctx, "user.Service.RestoreUser")
+	defer span.End()
+
+	return store.RunUnitOfWork(ctx, s.Repo, func(txn *sqlx.Tx) (store.User, error) {
+		opts := store.QueryOptions{Ctx: ctx, Txn: txn}
+
+		if err := s.Repo.RestoreUser(id, opts); err != nil {
+			return store.User{}, err
+		}
+
+		user, err := s.Repo.GetUserById(id, opts)
+		if err != nil {
+			return store.User{}, err
+		}
+
+		err = event.Emit(s.Repo, event.Event{
+			Type:   event.UserRestored,
+			Target: event.Target{Type: event.TargetUser, ID: id.String()},
+			Data:   event.UserData{User: user},
+		}, opts)
+		if err != nil {
+			return store.User{}, err
+		}
+
+		return user, nil
+	})
+}
+
+// ChangePassword replaces a user's password after confirming that they know
+// their current one.
+func (s Service) ChangePassword(ctx context.Context, id uuid.UUID, current, new string) error {
+	ctx, span := tracing.Start(ctx, "user.Service.ChangePassword")
+	defer span.End()
+
+	_, err := store.RunUnitOfWork(ctx, s.Repo, func(txn *sqlx.Tx) (struct{}, error) {
+		opts := store.QueryOptions{Ctx: ctx, Txn: txn}
+
+		hash, err := s.Repo.GetUserPasswordHash(id, opts)
+		if err != nil {
+			return struct{}{}, err
+		}
+
+		_, hashSpan := tracing.Start(ctx, "crypto.ValidatePassword")
+		ok, err := crypto.ValidatePassword(current, hash)
+		hashSpan.End()
+		if err != nil {
+			return struct{}{}, err
+		} else if !ok {
+			return struct{}{}, ErrIncorrectPassword
+		}
+
+		_, hashSpan = tracing.Start(ctx, "crypto.GetPasswordHash")
+		hash, err = crypto.GetPasswordHash(new, crypto.DefaultParams)
+		hashSpan.End()
+		if err != nil {
+			return struct{}{}, err
+		}
+
+		err = s.Repo.SavePassword(store.NewPassword{UserID: id, Hash: hash}, opts)
+		if err != nil {
+			return struct{}{}, err
+		}
+
+		return struct{}{}, event.Emit(s.Repo, event.Event{
+			Type:   event.UserPasswordChanged,
+			Target: event.Target{Type: event.TargetUser, ID: id.String()},
+		}, opts)
+	})
+
+	return err
 }
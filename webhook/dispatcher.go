@@ -0,0 +1,205 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/ninth-realm/heimdall/store"
+)
+
+// Defaults used when the Dispatcher's settings are zero.
+const (
+	DefaultTimeout     = 10 * time.Second
+	DefaultMaxAttempts = 10
+	DefaultBaseDelay   = 30 * time.Second
+	DefaultMaxDelay    = 6 * time.Hour
+	DefaultBatchSize   = 100
+)
+
+// responseBodyLimit is how much of a receiver's response is read before the
+// connection is closed. The body itself is ignored.
+const responseBodyLimit = 64 * 1024
+
+var errWebhookDisabled = errors.New("webhook is disabled")
+
+// Dispatcher sends due deliveries from the outbox to their webhooks. A failed
+// delivery is retried after BaseDelay, with the delay doubling after every
+// attempt up to MaxDelay, until it has been attempted MaxAttempts times.
+type Dispatcher struct {
+	Repo   store.Repository
+	Logger *slog.Logger
+	// Client sends the deliveries. Its timeout should be shorter than the
+	// lease on each delivery, which is Timeout plus a minute.
+	Client *http.Client
+	// Timeout is the timeout for each delivery when Client isn't set.
+	Timeout     time.Duration
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	// BatchSize is the max number of deliveries sent each time Dispatch is
+	// called.
+	BatchSize int
+}
+
+// Dispatch attempts every delivery that is currently due, up to the batch size.
+// Deliveries are claimed before they're sent, so several dispatchers can share
+// an outbox without sending the same delivery at once.
+func (d Dispatcher) Dispatch(ctx context.Context) error {
+	deliveries, err := d.Repo.ListDueWebhookDeliveries(
+		time.Now().UTC(),
+		withDefault(d.BatchSize, DefaultBatchSize),
+		store.QueryOptions{Ctx: ctx},
+	)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, delivery := range deliveries {
+		if err := d.deliver(ctx, delivery); err != nil {
+			errs = append(errs, fmt.Errorf("delivery %s: %w", delivery.ID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (d Dispatcher) deliver(ctx context.Context, delivery store.WebhookDelivery) error {
+	opts := store.QueryOptions{Ctx: ctx}
+
+	lease := time.Now().UTC().Add(withDefault(d.Timeout, DefaultTimeout) + time.Minute)
+	err := d.Repo.ClaimWebhookDelivery(delivery.ID, delivery.Attempts, lease, opts)
+	if errors.Is(err, store.VersionMismatchError{}) {
+		// Another dispatcher got to it first.
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	attempt := store.NewWebhookDeliveryAttempt{
+		DeliveryID:  delivery.ID,
+		Number:      delivery.Attempts + 1,
+		AttemptedAt: time.Now().UTC(),
+	}
+
+	webhook, err := d.Repo.GetWebhook(delivery.WebhookID, opts)
+	if errors.Is(err, store.NotFoundError{}) {
+		// The webhook was deleted along with its deliveries.
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	statusCode, sendErr := d.send(ctx, webhook, delivery)
+	if statusCode != 0 {
+		attempt.StatusCode = &statusCode
+	}
+
+	status, next := store.DeliverySucceeded, attempt.AttemptedAt
+	if sendErr != nil {
+		msg := sendErr.Error()
+		attempt.Error = &msg
+
+		status = store.DeliveryPending
+		next = attempt.AttemptedAt.Add(d.backoff(attempt.Number))
+		if attempt.Number >= withDefault(d.MaxAttempts, DefaultMaxAttempts) {
+			status = store.DeliveryFailed
+		}
+
+		d.logger().Warn("Webhook delivery failed",
+			"delivery_id", delivery.ID,
+			"webhook_id", webhook.ID,
+			"attempt", attempt.Number,
+			"error", sendErr,
+		)
+	}
+
+	_, err = store.RunUnitOfWork(ctx, d.Repo, func(txn *sqlx.Tx) (struct{}, error) {
+		opts := store.QueryOptions{Ctx: ctx, Txn: txn}
+		return struct{}{}, d.Repo.FinishWebhookDeliveryAttempt(attempt, status, next, opts)
+	})
+
+	return err
+}
+
+// send makes a single attempt at a delivery. Any response other than a 2xx is
+// a failure. The status code is 0 if no response was received.
+func (d Dispatcher) send(ctx context.Context, webhook store.Webhook, delivery store.WebhookDelivery) (int, error) {
+	if !webhook.Enabled {
+		return 0, errWebhookDisabled
+	}
+
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Heimdall-Webhooks")
+	req.Header.Set(EventTypeHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, delivery.ID.String())
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, time.Now(), body))
+
+	res, err := d.client().Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	io.Copy(io.Discard, io.LimitReader(res.Body, responseBodyLimit))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("receiver responded with %s", res.Status)
+	}
+
+	return res.StatusCode, nil
+}
+
+// backoff returns how long to wait after the given attempt before trying again.
+func (d Dispatcher) backoff(attempt int) time.Duration {
+	delay := withDefault(d.BaseDelay, DefaultBaseDelay)
+	maxDelay := withDefault(d.MaxDelay, DefaultMaxDelay)
+
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > maxDelay {
+		return maxDelay
+	}
+
+	return delay
+}
+
+func (d Dispatcher) client() *http.Client {
+	if d.Client != nil {
+		return d.Client
+	}
+
+	return &http.Client{Timeout: withDefault(d.Timeout, DefaultTimeout)}
+}
+
+func (d Dispatcher) logger() *slog.Logger {
+	if d.Logger != nil {
+		return d.Logger
+	}
+
+	return slog.Default()
+}
+
+func withDefault[T comparable](v, def T) T {
+	var zero T
+	if v == zero {
+		return def
+	}
+
+	return v
+}
@@ -0,0 +1,294 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/ninth-realm/heimdall/event"
+	"github.com/ninth-realm/heimdall/store"
+	"github.com/ninth-realm/heimdall/store/sqlite/sqlitetest"
+)
+
+// receiver is a webhook endpoint that checks the signature of each delivery and
+// responds with the next of its statuses.
+type receiver struct {
+	t        *testing.T
+	secret   string
+	statuses []int
+
+	mu       sync.Mutex
+	received []payload
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		rc.t.Errorf("reading delivery: %v", err)
+	}
+
+	err = Verify(rc.secret, r.Header.Get(SignatureHeader), body, time.Minute, time.Now())
+	if err != nil {
+		rc.t.Errorf("Verify() error = %v", err)
+	}
+
+	var p payload
+	if err := json.Unmarshal(body, &p); err != nil {
+		rc.t.Errorf("decoding delivery: %v", err)
+	}
+
+	if r.Header.Get(EventTypeHeader) != p.Type {
+		rc.t.Errorf("%s header = %s, want %s", EventTypeHeader, r.Header.Get(EventTypeHeader), p.Type)
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.received = append(rc.received, p)
+	status := http.StatusNoContent
+	if len(rc.statuses) > 0 {
+		status, rc.statuses = rc.statuses[0], rc.statuses[1:]
+	}
+
+	w.WriteHeader(status)
+}
+
+func (rc *receiver) count() int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	return len(rc.received)
+}
+
+func TestDispatcher_Dispatch(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T, statuses ...int) (store.Repository, *receiver, *httptest.Server) {
+		repo := sqlitetest.NewDB(t)
+		rc := &receiver{t: t, secret: "secret", statuses: statuses}
+		srv := httptest.NewServer(rc)
+		t.Cleanup(srv.Close)
+
+		_, err := Service{Repo: repo}.CreateWebhook(ctx, store.NewWebhook{
+			URL:        srv.URL,
+			EventTypes: []string{event.UserCreated},
+			Secret:     rc.secret,
+			Enabled:    true,
+		})
+		if err != nil {
+			t.Fatalf("CreateWebhook() error = %v", err)
+		}
+
+		return repo, rc, srv
+	}
+
+	// enqueue emits an event and hands it to the webhooks subscriber.
+	enqueue := func(t *testing.T, repo store.Repository, eventType string) {
+		err := event.Emit(repo, event.Event{
+			Type: eventType,
+			Data: event.UserData{User: store.User{ID: uuid.Must(uuid.NewV4()), FirstName: "John"}},
+		}, store.QueryOptions{Ctx: ctx})
+		if err != nil {
+			t.Fatalf("Emit() error = %v", err)
+		}
+
+		events := &event.Dispatcher{Repo: repo}
+		events.Subscribe("webhooks", Subscriber(repo))
+		if err := events.Dispatch(ctx); err != nil {
+			t.Fatalf("Dispatch() of events error = %v", err)
+		}
+	}
+
+	dispatch := func(t *testing.T, d Dispatcher) {
+		if err := d.Dispatch(ctx); err != nil {
+			t.Fatalf("Dispatch() error = %v", err)
+		}
+	}
+
+	t.Run("Delivers subscribed events", func(t *testing.T) {
+		repo, rc, srv := setup(t)
+
+		enqueue(t, repo, event.UserCreated)
+		enqueue(t, repo, event.UserDeleted)
+
+		d := Dispatcher{Repo: repo, Client: srv.Client()}
+		dispatch(t, d)
+
+		if rc.count() != 1 {
+			t.Fatalf("receiver got %d deliveries, want 1", rc.count())
+		}
+
+		if rc.received[0].Type != event.UserCreated {
+			t.Errorf("delivered event type = %s, want %s", rc.received[0].Type, event.UserCreated)
+		}
+
+		delivery := onlyDelivery(t, repo)
+		if delivery.Status != store.DeliverySucceeded || delivery.Attempts != 1 {
+			t.Errorf("delivery = %s after %d attempts, want succeeded after 1", delivery.Status, delivery.Attempts)
+		}
+
+		dispatch(t, d)
+		if rc.count() != 1 {
+			t.Errorf("receiver got %d deliveries after redispatch, want 1", rc.count())
+		}
+	})
+
+	t.Run("Backs off after a failure", func(t *testing.T) {
+		repo, rc, srv := setup(t, http.StatusInternalServerError)
+
+		enqueue(t, repo, event.UserCreated)
+
+		d := Dispatcher{Repo: repo, Client: srv.Client(), BaseDelay: time.Hour}
+		dispatch(t, d)
+		dispatch(t, d)
+
+		if rc.count() != 1 {
+			t.Fatalf("receiver got %d deliveries, want 1 before the retry is due", rc.count())
+		}
+
+		delivery := onlyDelivery(t, repo)
+		if delivery.Status != store.DeliveryPending {
+			t.Errorf("delivery status = %s, want %s", delivery.Status, store.DeliveryPending)
+		}
+
+		if wait := time.Until(delivery.NextAttemptAt); wait < 59*time.Minute || wait > time.Hour {
+			t.Errorf("next attempt in %v, want about an hour", wait)
+		}
+	})
+
+	t.Run("Retries until delivered", func(t *testing.T) {
+		repo, rc, srv := setup(t, http.StatusInternalServerError, http.StatusBadGateway)
+
+		enqueue(t, repo, event.UserCreated)
+
+		// Retries are due immediately.
+		d := Dispatcher{Repo: repo, Client: srv.Client(), BaseDelay: time.Nanosecond}
+		for i := 0; i < 4; i++ {
+			dispatch(t, d)
+		}
+
+		if rc.count() != 3 {
+			t.Fatalf("receiver got %d deliveries, want 3", rc.count())
+		}
+
+		if rc.received[0].ID != rc.received[2].ID {
+			t.Errorf("retried event ID = %s, want %s", rc.received[2].ID, rc.received[0].ID)
+		}
+
+		history := deliveryHistory(t, repo)
+		if history.Status != store.DeliverySucceeded {
+			t.Errorf("delivery status = %s, want %s", history.Status, store.DeliverySucceeded)
+		}
+
+		wantCodes := []int{500, 502, 204}
+		if len(history.History) != len(wantCodes) {
+			t.Fatalf("delivery has %d attempts, want %d", len(history.History), len(wantCodes))
+		}
+
+		for i, attempt := range history.History {
+			if attempt.Number != i+1 || attempt.StatusCode == nil || *attempt.StatusCode != wantCodes[i] {
+				t.Errorf("attempt %d = %+v, want number %d with status %d", i, attempt, i+1, wantCodes[i])
+			}
+		}
+	})
+
+	t.Run("Fails after max attempts", func(t *testing.T) {
+		repo, rc, srv := setup(t, 500, 500, 500, 500)
+
+		enqueue(t, repo, event.UserCreated)
+
+		d := Dispatcher{Repo: repo, Client: srv.Client(), BaseDelay: time.Nanosecond, MaxAttempts: 3}
+		for i := 0; i < 4; i++ {
+			dispatch(t, d)
+		}
+
+		if rc.count() != 3 {
+			t.Fatalf("receiver got %d deliveries, want 3", rc.count())
+		}
+
+		history := deliveryHistory(t, repo)
+		if history.Status != store.DeliveryFailed || len(history.History) != 3 {
+			t.Errorf("delivery = %s after %d attempts, want failed after 3", history.Status, len(history.History))
+		}
+
+		for _, attempt := range history.History {
+			if attempt.Error == nil {
+				t.Errorf("attempt %d has no error", attempt.Number)
+			}
+		}
+	})
+
+	t.Run("Disabled webhooks are not sent to", func(t *testing.T) {
+		repo, rc, srv := setup(t)
+
+		enqueue(t, repo, event.UserCreated)
+
+		webhook := onlyWebhook(t, repo)
+		disabled := false
+		_, err := Service{Repo: repo}.UpdateWebhook(ctx, webhook.ID, webhook.Version, store.WebhookPatch{Enabled: &disabled})
+		if err != nil {
+			t.Fatalf("UpdateWebhook() error = %v", err)
+		}
+
+		dispatch(t, Dispatcher{Repo: repo, Client: srv.Client(), MaxAttempts: 1})
+
+		if rc.count() != 0 {
+			t.Errorf("receiver got %d deliveries, want 0", rc.count())
+		}
+
+		history := deliveryHistory(t, repo)
+		if history.Status != store.DeliveryFailed || len(history.History) != 1 || history.History[0].StatusCode != nil {
+			t.Errorf("delivery to disabled webhook = %+v", history)
+		}
+	})
+}
+
+func onlyWebhook(t *testing.T, repo store.Repository) store.Webhook {
+	t.Helper()
+
+	webhooks, err := repo.ListWebhooks(store.QueryOptions{Ctx: context.Background()})
+	if err != nil {
+		t.Fatalf("ListWebhooks() error = %v", err)
+	}
+
+	if len(webhooks) != 1 {
+		t.Fatalf("ListWebhooks() returned %d webhooks, want 1", len(webhooks))
+	}
+
+	return webhooks[0]
+}
+
+func onlyDelivery(t *testing.T, repo store.Repository) store.WebhookDelivery {
+	t.Helper()
+
+	page, err := Service{Repo: repo}.ListDeliveries(context.Background(), onlyWebhook(t, repo).ID, store.WebhookDeliveryListOptions{
+		Sort: store.Sort{Field: store.SortByCreatedAt},
+	})
+	if err != nil {
+		t.Fatalf("ListDeliveries() error = %v", err)
+	}
+
+	if len(page.Items) != 1 {
+		t.Fatalf("ListDeliveries() returned %d deliveries, want 1", len(page.Items))
+	}
+
+	return page.Items[0]
+}
+
+func deliveryHistory(t *testing.T, repo store.Repository) DeliveryHistory {
+	t.Helper()
+
+	delivery := onlyDelivery(t, repo)
+	history, err := Service{Repo: repo}.GetDelivery(context.Background(), delivery.WebhookID, delivery.ID)
+	if err != nil {
+		t.Fatalf("GetDelivery() error = %v", err)
+	}
+
+	return history
+}
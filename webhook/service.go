@@ -0,0 +1,130 @@
+package webhook
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/jmoiron/sqlx"
+	"github.com/ninth-realm/heimdall/crypto"
+	"github.com/ninth-realm/heimdall/store"
+)
+
+// secretLength is the number of random bytes in generated secrets.
+const secretLength = 32
+
+type Service struct {
+	Repo store.Repository
+}
+
+func (s Service) ListWebhooks(ctx context.Context) ([]store.Webhook, error) {
+	return s.Repo.ListWebhooks(store.QueryOptions{Ctx: ctx})
+}
+
+func (s Service) GetWebhook(ctx context.Context, id uuid.UUID) (store.Webhook, error) {
+	return s.Repo.GetWebhook(id, store.QueryOptions{Ctx: ctx})
+}
+
+// CreateWebhook subscribes a URL to events. A secret is generated if one isn't
+// provided. The returned webhook includes the secret, which is needed to
+// verify deliveries.
+func (s Service) CreateWebhook(ctx context.Context, webhook store.NewWebhook) (store.Webhook, error) {
+	if webhook.Secret == "" {
+		secret, err := crypto.GenerateRandBase64String(secretLength)
+		if err != nil {
+			return store.Webhook{}, err
+		}
+		webhook.Secret = secret
+	}
+
+	return store.RunUnitOfWork(ctx, s.Repo, func(txn *sqlx.Tx) (store.Webhook, error) {
+		opts := store.QueryOptions{Ctx: ctx, Txn: txn}
+
+		id, err := s.Repo.InsertWebhook(webhook, opts)
+		if err != nil {
+			return store.Webhook{}, err
+		}
+
+		return s.Repo.GetWebhook(id, opts)
+	})
+}
+
+// UpdateWebhook applies the patch to version of the webhook. It fails with a
+// store.VersionMismatchError if the webhook has been changed since that
+// version.
+func (s Service) UpdateWebhook(ctx context.Context, id uuid.UUID, version int64, patch store.WebhookPatch) (store.Webhook, error) {
+	return store.RunUnitOfWork(ctx, s.Repo, func(txn *sqlx.Tx) (store.Webhook, error) {
+		opts := store.QueryOptions{Ctx: ctx, Txn: txn}
+
+		webhook, err := s.Repo.GetWebhook(id, opts)
+		if err != nil {
+			return store.Webhook{}, err
+		}
+
+		if webhook.Version != version {
+			return store.Webhook{}, store.VersionMismatchError{ResourceType: "webhook", ResourceID: id.String()}
+		}
+
+		if err = s.Repo.SaveWebhook(patch.ApplyTo(webhook), opts); err != nil {
+			return store.Webhook{}, err
+		}
+
+		return s.Repo.GetWebhook(id, opts)
+	})
+}
+
+// DeleteWebhook removes a webhook. Its pending deliveries won't be sent.
+func (s Service) DeleteWebhook(ctx context.Context, id uuid.UUID) error {
+	return s.Repo.DeleteWebhook(id, store.QueryOptions{Ctx: ctx})
+}
+
+// ListDeliveries returns a single page of a webhook's deliveries. An invalid
+// sort or cursor results in store.ErrInvalidSort or store.ErrInvalidCursor.
+func (s Service) ListDeliveries(ctx context.Context, webhookID uuid.UUID, list store.WebhookDeliveryListOptions) (store.Page[store.WebhookDelivery], error) {
+	if err := list.Validate(); err != nil {
+		return store.Page[store.WebhookDelivery]{}, err
+	}
+
+	opts := store.QueryOptions{Ctx: ctx}
+
+	// An empty list should only be returned for a webhook that exists.
+	if _, err := s.Repo.GetWebhook(webhookID, opts); err != nil {
+		return store.Page[store.WebhookDelivery]{}, err
+	}
+
+	// Fetch an extra delivery to find out whether there is another page.
+	limit := list.Pagination.Limit
+	if limit > 0 {
+		list.Pagination.Limit++
+	}
+
+	deliveries, err := s.Repo.ListWebhookDeliveries(webhookID, list, opts)
+	if err != nil {
+		return store.Page[store.WebhookDelivery]{}, err
+	}
+
+	return store.NewPage(deliveries, limit, func(delivery store.WebhookDelivery) store.Cursor {
+		return store.WebhookDeliveryCursor(list.Sort, delivery)
+	}), nil
+}
+
+// DeliveryHistory is a delivery along with each attempt at sending it.
+type DeliveryHistory struct {
+	store.WebhookDelivery
+	History []store.WebhookDeliveryAttempt `json:"history"`
+}
+
+func (s Service) GetDelivery(ctx context.Context, webhookID, deliveryID uuid.UUID) (DeliveryHistory, error) {
+	opts := store.QueryOptions{Ctx: ctx}
+
+	delivery, err := s.Repo.GetWebhookDelivery(webhookID, deliveryID, opts)
+	if err != nil {
+		return DeliveryHistory{}, err
+	}
+
+	attempts, err := s.Repo.ListWebhookDeliveryAttempts(deliveryID, opts)
+	if err != nil {
+		return DeliveryHistory{}, err
+	}
+
+	return DeliveryHistory{WebhookDelivery: delivery, History: attempts}, nil
+}
@@ -0,0 +1,162 @@
+// Package webhook notifies other services of changes to users by sending signed
+// HTTP requests to the URLs they've subscribed.
+//
+// Events are added to an outbox of deliveries by the Subscriber, and sent
+// afterwards by a Dispatcher. A delivery that fails is retried with exponential
+// backoff, so receivers may get an event more than once and should use its ID
+// to ignore duplicates.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/ninth-realm/heimdall/event"
+	"github.com/ninth-realm/heimdall/store"
+)
+
+// EventTypes lists every event type that webhooks can subscribe to.
+var EventTypes = []string{
+	event.UserCreated,
+	event.UserUpdated,
+	event.UserDeleted,
+	event.UserRestored,
+}
+
+// IsEventType reports whether webhooks can subscribe to events of type t.
+func IsEventType(t string) bool {
+	for _, eventType := range EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Headers sent with every delivery.
+const (
+	// SignatureHeader holds the delivery's signature. See Sign.
+	SignatureHeader = "Heimdall-Signature"
+	EventTypeHeader = "Heimdall-Event"
+	// DeliveryHeader holds the ID of the delivery, which stays the same
+	// across retries.
+	DeliveryHeader = "Heimdall-Delivery"
+)
+
+// payload is the body of a delivery.
+type payload struct {
+	ID         uuid.UUID     `json:"id"`
+	Type       string        `json:"type"`
+	OccurredAt time.Time     `json:"occurredAt"`
+	Data       store.RawJSON `json:"data"`
+}
+
+// Subscriber adds a delivery of each event to the outbox of every enabled
+// webhook that subscribes to it. Deliveries are added in the transaction that
+// marks the event as handled, so each event is only delivered once per webhook.
+func Subscriber(repo store.Repository) event.Handler {
+	return func(e store.OutboxEvent, opts store.QueryOptions) error {
+		if !IsEventType(e.Type) {
+			return nil
+		}
+
+		webhooks, err := repo.ListWebhooks(opts)
+		if err != nil {
+			return err
+		}
+
+		var body []byte
+		for _, w := range webhooks {
+			if !w.Subscribes(e.Type) {
+				continue
+			}
+
+			if body == nil {
+				body, err = json.Marshal(payload{
+					ID:         e.ID,
+					Type:       e.Type,
+					OccurredAt: e.OccurredAt,
+					Data:       e.Data,
+				})
+				if err != nil {
+					return err
+				}
+			}
+
+			_, err = repo.InsertWebhookDelivery(store.NewWebhookDelivery{
+				WebhookID: w.ID,
+				EventID:   e.ID,
+				EventType: e.Type,
+				Payload:   body,
+			}, opts)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+var (
+	ErrMissingSignature = errors.New("missing or malformed webhook signature")
+	ErrInvalidSignature = errors.New("webhook signature does not match")
+	ErrExpiredSignature = errors.New("webhook signature is too old")
+)
+
+// Sign returns the signature header for a delivery body sent at the given time.
+// It has the form "t=<unix timestamp>,v1=<signature>", where the signature is
+// the hex encoded HMAC-SHA256, keyed with the webhook's secret, of the
+// timestamp and body joined with a ".".
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + t + ",v1=" + signature(secret, t, body)
+}
+
+func signature(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature header of a delivery received at now. Including
+// the timestamp in the signature stops old deliveries from being replayed, so
+// signatures older than tolerance are rejected.
+func Verify(secret, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var t, sig string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			t = value
+		case "v1":
+			sig = value
+		}
+	}
+
+	unix, err := strconv.ParseInt(t, 10, 64)
+	if err != nil || sig == "" {
+		return ErrMissingSignature
+	}
+
+	if !hmac.Equal([]byte(sig), []byte(signature(secret, t, body))) {
+		return ErrInvalidSignature
+	}
+
+	if now.Sub(time.Unix(unix, 0)) > tolerance {
+		return ErrExpiredSignature
+	}
+
+	return nil
+}
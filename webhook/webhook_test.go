@@ -0,0 +1,109 @@
+package webhook
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	secret, body := "secret", []byte(`{"id":"1"}`)
+	sentAt := time.Unix(1700000000, 0)
+	header := Sign(secret, sentAt, body)
+
+	tests := []struct {
+		name    string
+		secret  string
+		header  string
+		body    []byte
+		now     time.Time
+		wantErr error
+	}{
+		{
+			name:   "Valid signature",
+			secret: secret,
+			header: header,
+			body:   body,
+			now:    sentAt.Add(time.Minute),
+		},
+		{
+			name:    "Wrong secret",
+			secret:  "other",
+			header:  header,
+			body:    body,
+			now:     sentAt,
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "Modified body",
+			secret:  secret,
+			header:  header,
+			body:    []byte(`{"id":"2"}`),
+			now:     sentAt,
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "Modified timestamp",
+			secret:  secret,
+			header:  "t=1700000001" + header[len("t=1700000000"):],
+			body:    body,
+			now:     sentAt,
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "Too old",
+			secret:  secret,
+			header:  header,
+			body:    body,
+			now:     sentAt.Add(10 * time.Minute),
+			wantErr: ErrExpiredSignature,
+		},
+		{
+			name:    "Missing signature",
+			secret:  secret,
+			header:  "",
+			body:    body,
+			now:     sentAt,
+			wantErr: ErrMissingSignature,
+		},
+		{
+			name:    "Missing timestamp",
+			secret:  secret,
+			header:  "v1=abc",
+			body:    body,
+			now:     sentAt,
+			wantErr: ErrMissingSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.secret, tt.header, tt.body, 5*time.Minute, tt.now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDispatcher_backoff(t *testing.T) {
+	d := Dispatcher{BaseDelay: time.Minute, MaxDelay: 10 * time.Minute}
+
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 1, want: time.Minute},
+		{attempt: 2, want: 2 * time.Minute},
+		{attempt: 3, want: 4 * time.Minute},
+		{attempt: 4, want: 8 * time.Minute},
+		{attempt: 5, want: 10 * time.Minute},
+		{attempt: 50, want: 10 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := d.backoff(tt.attempt); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}